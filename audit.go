@@ -0,0 +1,82 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/user"
+	"time"
+)
+
+// A record of an accepted filter in the audit log
+type auditRecord struct {
+	Time         string   `json:"time"`
+	User         string   `json:"user,omitempty"`
+	Filter       string   `json:"filter"`
+	Files        []string `json:"files,omitempty"`
+	InputSHA256  string   `json:"input_sha256"`
+	OutputSHA256 string   `json:"output_sha256"`
+}
+
+// Create an audit record for the document. outputSum is the SHA-256 hash of
+// the output as it was written.
+func newAuditRecord(d *Document, outputSum []byte, now time.Time) auditRecord {
+	inputSum := sha256.Sum256([]byte(d.input))
+	record := auditRecord{
+		Time:         now.UTC().Format(time.RFC3339),
+		Filter:       d.filter,
+		Files:        d.files,
+		InputSHA256:  hex.EncodeToString(inputSum[:]),
+		OutputSHA256: hex.EncodeToString(outputSum),
+	}
+
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+
+	return record
+}
+
+// Append a record to the audit log as a single line of JSON. The file is only
+// ever appended to and is synced to disk before returning.
+func appendAudit(filename string, record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendAudit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	doc := Document{input: "{}", filter: ".a", files: []string{"in.json"}}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	sum := sha256.Sum256([]byte("null\n"))
+	assert.NoError(t, appendAudit(filename, newAuditRecord(&doc, sum[:], now)))
+
+	doc.filter = ".b"
+	assert.NoError(t, appendAudit(filename, newAuditRecord(&doc, sum[:], now)))
+
+	contents, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	var record auditRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "2021-06-01T12:00:00Z", record.Time)
+	assert.Equal(t, ".a", record.Filter)
+	assert.Equal(t, []string{"in.json"}, record.Files)
+	assert.Equal(t, "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", record.InputSHA256)
+	assert.Equal(t, "38e0b9de817f645c4bec37c0d4a3e58baecccb040f5718dc069a72c7385a0bed", record.OutputSHA256)
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, ".b", record.Filter)
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// A container (object or array) that is open at some point while walking
+// pretty-printed JSON
+type pathFrame struct {
+	array bool
+	index int
+	key   string
+}
+
+// Format a path of frames as a jq path expression
+func formatPath(frames []pathFrame) string {
+	if len(frames) == 0 {
+		return "."
+	}
+
+	var sb strings.Builder
+	for _, f := range frames {
+		if f.array {
+			sb.WriteString("[" + strconv.Itoa(f.index) + "]")
+		} else {
+			sb.WriteString("." + quoteKey(f.key))
+		}
+	}
+
+	return sb.String()
+}
+
+// Quote an object key if it cannot be used as an identifier in a jq path
+func quoteKey(key string) string {
+	if key == "" || strings.ContainsAny(key, SpecialChars+` "\`) || !strings.Contains(Alphabet, strings.ToLower(key[:1])) {
+		b, _ := json.Marshal(key)
+		return string(b)
+	}
+
+	return key
+}
+
+// Split a line of pretty-printed JSON of the form `"key": value` into its key
+// and value. If the line does not begin with a key, ok is false.
+func splitKey(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, `"`) {
+		return "", line, false
+	}
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			rest := line[i+1:]
+			if !strings.HasPrefix(rest, ": ") {
+				return "", line, false
+			}
+
+			if err := json.Unmarshal([]byte(line[:i+1]), &key); err != nil {
+				return "", line, false
+			}
+
+			return key, rest[2:], true
+		}
+	}
+
+	return "", line, false
+}
+
+// Compute the jq path of the value on each line of pretty-printed JSON
+// output. Lines that close an object or array are given the path of the
+// container. Lines beginning with '#' (such as the index comments added by
+// the number values option) are given the path of the next value.
+func linePaths(text string) []string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	paths := make([]string, len(lines))
+
+	var stack []pathFrame
+	pending := []int{}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			pending = append(pending, i)
+			continue
+		}
+
+		line = strings.TrimSuffix(line, ",")
+		if line == "}" || line == "]" {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+			paths[i] = formatPath(stack)
+			continue
+		}
+
+		if n := len(stack); n > 0 {
+			top := &stack[n-1]
+			if top.array {
+				top.index++
+			}
+		}
+
+		key, value, ok := splitKey(line)
+		if n := len(stack); n > 0 && !stack[n-1].array && ok {
+			stack[n-1].key = key
+		} else {
+			value = line
+		}
+
+		path := formatPath(stack)
+		paths[i] = path
+		for _, j := range pending {
+			paths[j] = path
+		}
+		pending = pending[:0]
+
+		if value == "{" {
+			stack = append(stack, pathFrame{})
+		} else if value == "[" {
+			stack = append(stack, pathFrame{array: true, index: -1})
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinePaths(t *testing.T) {
+	text := `{
+  "a": 1,
+  "b-c": [
+    {
+      "d": "x\"y"
+    },
+    []
+  ],
+  "e": {}
+}
+# 1
+2
+`
+	assert.Equal(t, []string{
+		".",
+		".a",
+		`."b-c"`,
+		`."b-c"[0]`,
+		`."b-c"[0].d`,
+		`."b-c"[0]`,
+		`."b-c"[1]`,
+		`."b-c"`,
+		".e",
+		".",
+		".",
+		".",
+	}, linePaths(text))
+}
+
+func TestSplitKey(t *testing.T) {
+	key, value, ok := splitKey(`"a\"b": {`)
+	assert.True(t, ok)
+	assert.Equal(t, `a"b`, key)
+	assert.Equal(t, "{", value)
+
+	_, value, ok = splitKey(`"just a string"`)
+	assert.False(t, ok)
+	assert.Equal(t, `"just a string"`, value)
+}
+
+func TestQuoteKey(t *testing.T) {
+	assert.Equal(t, "foo", quoteKey("foo"))
+	assert.Equal(t, `"foo-bar"`, quoteKey("foo-bar"))
+	assert.Equal(t, `"1a"`, quoteKey("1a"))
+	assert.Equal(t, `""`, quoteKey(""))
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A jq version as major, minor, and patch numbers
+type jqVersion [3]int
+
+func (v jqVersion) less(w jqVersion) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+
+	return false
+}
+
+// Format the version like jq does, e.g. "1.6" or "1.7.1"
+func (v jqVersion) String() string {
+	if v[2] == 0 {
+		return fmt.Sprintf("%d.%d", v[0], v[1])
+	}
+
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// Parse the output of jq --version, e.g. "jq-1.6" or "jq-1.7.1"
+func parseJQVersion(s string) (jqVersion, bool) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return jqVersion{}, false
+	}
+
+	var v jqVersion
+	for i, part := range m[1:] {
+		if part != "" {
+			v[i], _ = strconv.Atoi(part)
+		}
+	}
+
+	return v, true
+}
+
+// Determine the version of the given jq command
+func detectJQVersion(command string) (jqVersion, bool) {
+	out, err := exec.Command(command, "--version").Output()
+	if err != nil {
+		return jqVersion{}, false
+	}
+
+	return parseJQVersion(string(out))
+}
+
+// The builtins introduced in each version of jq, starting with those
+// available in jq 1.5
+var jqBuiltins = []struct {
+	version  jqVersion
+	builtins []string
+}{
+	{jqVersion{1, 5, 0}, []string{
+		"acos", "acosh", "add", "all", "any", "arrays", "ascii_downcase",
+		"ascii_upcase", "asin", "asinh", "atan", "atan2", "atanh", "booleans",
+		"bsearch", "capture", "cbrt", "ceil", "combinations", "contains",
+		"copysign", "cos", "cosh", "debug", "del", "delpaths", "drem", "empty",
+		"endswith", "env", "erf", "erfc", "error", "exp", "exp10", "exp2",
+		"explode", "expm1", "fabs", "fdim", "finites", "first", "flatten",
+		"floor", "fma", "fmax", "fmin", "fmod", "format", "frexp",
+		"from_entries", "fromdate", "fromdateiso8601", "fromjson",
+		"fromstream", "gamma", "getpath", "gmtime", "group_by", "gsub", "has",
+		"hypot", "implode", "in", "index", "indices", "infinite", "input",
+		"input_filename", "input_line_number", "inputs", "inside", "isempty",
+		"isfinite", "isinfinite", "isnan", "isnormal", "iterables", "j0", "j1",
+		"jn", "join", "keys", "keys_unsorted", "last", "ldexp", "leaf_paths",
+		"length", "lgamma", "lgamma_r", "limit", "log", "log10", "log1p",
+		"log2", "logb", "ltrimstr", "map", "map_values", "match", "max",
+		"max_by", "min", "min_by", "mktime", "modf", "modulemeta", "nan",
+		"nearbyint", "nextafter", "nexttoward", "normals", "not", "now", "nth",
+		"nulls", "numbers", "objects", "path", "paths", "pow", "pow10",
+		"range", "recurse", "remainder", "repeat", "reverse", "rindex", "rint",
+		"round", "rtrimstr", "scalars", "scalb", "scalbln", "scan", "select",
+		"setpath", "significand", "sin", "sinh", "sort", "sort_by", "split",
+		"splits", "sqrt", "startswith", "strftime", "strings",
+		"strptime", "sub", "tan", "tanh", "test", "tgamma", "to_entries",
+		"todate", "todateiso8601", "tojson", "tonumber", "tostream",
+		"tostring", "transpose", "trunc", "truncate_stream", "type", "unique",
+		"unique_by", "until", "values", "while", "with_entries", "y0", "y1",
+		"yn", "@base32", "@base64", "@base64d", "@csv", "@html", "@json",
+		"@sh", "@text", "@tsv", "@uri",
+	}},
+	{jqVersion{1, 6, 0}, []string{
+		"IN", "INDEX", "JOIN", "builtins", "halt", "halt_error", "localtime",
+		"stderr", "strflocaltime", "utf8bytelength", "walk",
+	}},
+	{jqVersion{1, 7, 0}, []string{
+		"have_decnum", "have_literal_numbers", "pick", "@base32d",
+	}},
+	{jqVersion{1, 7, 1}, []string{
+		"abs", "ltrim", "rtrim", "toarray", "trim",
+	}},
+}
+
+// The builtins available in the given version of jq, sorted by name. If the
+// version is unknown all builtins are returned.
+func availableBuiltins(version jqVersion, known bool) []string {
+	var names []string
+	for _, b := range jqBuiltins {
+		if known && version.less(b.version) {
+			break
+		}
+
+		names = append(names, b.builtins...)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+var trailingIdentPattern = regexp.MustCompile(`@?[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Complete the builtin name at the end of the filter text. Identifiers that
+// are object keys (.foo) or variables ($foo) are not completed.
+func completeBuiltin(text string, builtins []string) []string {
+	loc := trailingIdentPattern.FindStringIndex(text)
+	if loc == nil {
+		return nil
+	}
+
+	if start := loc[0]; start > 0 && strings.ContainsRune(".$", rune(text[start-1])) {
+		return nil
+	}
+
+	word := text[loc[0]:]
+	var entries []string
+	for _, name := range builtins {
+		if name != word && strings.HasPrefix(name, word) {
+			entries = append(entries, text[:loc[0]]+name)
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJQVersion(t *testing.T) {
+	v, ok := parseJQVersion("jq-1.6\n")
+	assert.True(t, ok)
+	assert.Equal(t, jqVersion{1, 6, 0}, v)
+
+	v, ok = parseJQVersion("jq-1.7.1-dirty")
+	assert.True(t, ok)
+	assert.Equal(t, jqVersion{1, 7, 1}, v)
+
+	_, ok = parseJQVersion("jq-master")
+	assert.False(t, ok)
+}
+
+func TestJQVersionString(t *testing.T) {
+	assert.Equal(t, "1.6", jqVersion{1, 6, 0}.String())
+	assert.Equal(t, "1.7.1", jqVersion{1, 7, 1}.String())
+}
+
+func TestAvailableBuiltins(t *testing.T) {
+	builtins := availableBuiltins(jqVersion{1, 6, 0}, true)
+	assert.Contains(t, builtins, "INDEX")
+	assert.Contains(t, builtins, "map")
+	assert.NotContains(t, builtins, "pick")
+	assert.True(t, sort.StringsAreSorted(builtins))
+
+	builtins = availableBuiltins(jqVersion{1, 7, 0}, true)
+	assert.Contains(t, builtins, "pick")
+	assert.NotContains(t, builtins, "trim")
+
+	builtins = availableBuiltins(jqVersion{1, 5, 0}, true)
+	assert.NotContains(t, builtins, "walk")
+
+	builtins = availableBuiltins(jqVersion{}, false)
+	assert.Contains(t, builtins, "trim")
+}
+
+func TestCompleteBuiltin(t *testing.T) {
+	builtins := []string{"map", "map_values", "max", "@base64"}
+	assert.Equal(t, []string{".a | map", ".a | map_values"}, completeBuiltin(".a | ma", builtins)[:2])
+	assert.Equal(t, []string{"map_values"}, completeBuiltin("map", builtins))
+	assert.Equal(t, []string{".x | @base64"}, completeBuiltin(".x | @b", builtins))
+	assert.Empty(t, completeBuiltin(".ma", builtins))
+	assert.Empty(t, completeBuiltin("$ma", builtins))
+	assert.Empty(t, completeBuiltin(".a | ", builtins))
+}
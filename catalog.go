@@ -0,0 +1,118 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A named filter in a catalog
+type CatalogEntry struct {
+	Name        string   `json:"name"`
+	Filter      string   `json:"filter"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// A catalog of vetted filters with descriptions, stored as JSON, so that a
+// team can share the filters it uses for common data
+type Catalog struct {
+	Filters []CatalogEntry `json:"filters"`
+}
+
+// Load the catalog from the given path. Every filter must have a name.
+func loadCatalog(path string) (*Catalog, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading catalog: %w", err)
+	}
+
+	var c Catalog
+	if err := json.Unmarshal(contents, &c); err != nil {
+		return nil, fmt.Errorf("error reading catalog %s: %w", path, err)
+	}
+
+	for i, e := range c.Filters {
+		if e.Name == "" {
+			return nil, fmt.Errorf("error reading catalog %s: filter %d has no name", path, i+1)
+		}
+
+		if strings.TrimSpace(e.Filter) == "" {
+			return nil, fmt.Errorf("error reading catalog %s: filter %q is empty", path, e.Name)
+		}
+	}
+
+	return &c, nil
+}
+
+// Return the entries matching all of the words in the query, ignoring case.
+// A word starting with # matches the entries with a tag starting with the
+// rest of the word, and other words match the name, the description, the
+// tags, or the filter of an entry.
+func (c *Catalog) Search(query string) []CatalogEntry {
+	words := strings.Fields(strings.ToLower(query))
+
+	var matches []CatalogEntry
+outer:
+	for _, e := range c.Filters {
+		text := strings.ToLower(strings.Join(append([]string{e.Name, e.Description, e.Filter}, e.Tags...), "\n"))
+		for _, w := range words {
+			if strings.HasPrefix(w, "#") && len(w) > 1 {
+				if !e.hasTagPrefix(w[1:]) {
+					continue outer
+				}
+			} else if !strings.Contains(text, w) {
+				continue outer
+			}
+		}
+
+		matches = append(matches, e)
+	}
+
+	return matches
+}
+
+// Report whether a tag of the entry starts with the lowercase prefix,
+// ignoring case
+func (e CatalogEntry) hasTagPrefix(prefix string) bool {
+	for _, t := range e.Tags {
+		if strings.HasPrefix(strings.ToLower(t), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The secondary text of the entry in the catalog picker: its description
+// followed by its tags
+func (e CatalogEntry) summary() string {
+	parts := []string{}
+	if e.Description != "" {
+		parts = append(parts, e.Description)
+	}
+
+	for _, t := range e.Tags {
+		parts = append(parts, "#"+t)
+	}
+
+	return strings.Join(parts, " ")
+}
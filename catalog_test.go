@@ -0,0 +1,82 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	dir := t.TempDir()
+	write := func(contents string) string {
+		path := filepath.Join(dir, "catalog.json")
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+		return path
+	}
+
+	path := write(`{"filters": [{"name": "Failed jobs", "filter": ".jobs[] | select(.failed)", "tags": ["ci"]}]}`)
+	c, err := loadCatalog(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []CatalogEntry{{Name: "Failed jobs", Filter: ".jobs[] | select(.failed)", Tags: []string{"ci"}}}, c.Filters)
+
+	path = write(`{"filters": [{"filter": "."}]}`)
+	_, err = loadCatalog(path)
+	assert.EqualError(t, err, "error reading catalog "+path+": filter 1 has no name")
+
+	path = write(`{"filters": [{"name": "Nothing", "filter": " "}]}`)
+	_, err = loadCatalog(path)
+	assert.EqualError(t, err, "error reading catalog "+path+`: filter "Nothing" is empty`)
+
+	_, err = loadCatalog(filepath.Join(dir, "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCatalogSearch(t *testing.T) {
+	c := &Catalog{Filters: []CatalogEntry{
+		{Name: "Failed jobs", Filter: ".jobs[] | select(.failed)", Description: "Jobs that did not pass", Tags: []string{"CI"}},
+		{Name: "Slow jobs", Filter: ".jobs[] | select(.duration > 600)", Tags: []string{"ci", "perf"}},
+		{Name: "Users", Filter: ".users"},
+	}}
+
+	names := func(query string) []string {
+		var names []string
+		for _, e := range c.Search(query) {
+			names = append(names, e.Name)
+		}
+
+		return names
+	}
+
+	assert.Equal(t, []string{"Failed jobs", "Slow jobs", "Users"}, names(""))
+	assert.Equal(t, []string{"Failed jobs", "Slow jobs"}, names("JOBS"))
+	assert.Equal(t, []string{"Failed jobs"}, names("jobs pass"))
+	assert.Equal(t, []string{"Slow jobs"}, names("duration"))
+	assert.Equal(t, []string{"Failed jobs", "Slow jobs"}, names("#ci"))
+	assert.Equal(t, []string{"Slow jobs"}, names("#pe jobs"))
+	assert.Empty(t, names("#users"))
+}
+
+func TestCatalogEntrySummary(t *testing.T) {
+	assert.Equal(t, "Jobs that did not pass #ci #perf", CatalogEntry{Description: "Jobs that did not pass", Tags: []string{"ci", "perf"}}.summary())
+	assert.Equal(t, "#ci", CatalogEntry{Tags: []string{"ci"}}.summary())
+	assert.Equal(t, "", CatalogEntry{}.summary())
+}
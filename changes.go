@@ -0,0 +1,158 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// A value that is in both the input and the output but differs
+const diffChange diffOp = '~'
+
+// A path that was added, removed, or changed between the input and the output
+type pathChange struct {
+	op   diffOp
+	path string
+}
+
+// Compare the parsed input and output values and return the paths that were
+// added, removed, or changed. Objects are compared by key and arrays by
+// index, so that inserting an element into an array changes the elements
+// after it. When the input or the output is a stream of several values,
+// each path is preceded by the index of its value in the stream, as in the
+// output pane with -number-values.
+func changedPaths(input, output []*jsonValue) []pathChange {
+	var changes []pathChange
+	stream := len(input) > 1 || len(output) > 1
+	for i := 0; i < len(input) || i < len(output); i++ {
+		c := changeWalker{}
+		if stream {
+			c.stream = fmt.Sprintf("#%d ", i)
+		}
+
+		switch {
+		case i >= len(output):
+			c.add(diffDelete, nil)
+		case i >= len(input):
+			c.add(diffInsert, nil)
+		default:
+			c.compare(input[i], output[i], nil)
+		}
+
+		changes = append(changes, c.changes...)
+	}
+
+	return changes
+}
+
+// Collects the changed paths of one value of the input and output
+type changeWalker struct {
+	stream  string
+	changes []pathChange
+}
+
+func (c *changeWalker) add(op diffOp, frames []pathFrame) {
+	c.changes = append(c.changes, pathChange{op, c.stream + formatPath(frames)})
+}
+
+func (c *changeWalker) compare(a, b *jsonValue, frames []pathFrame) {
+	at := func(f pathFrame) []pathFrame {
+		return append(frames[:len(frames):len(frames)], f)
+	}
+
+	switch {
+	case a.kind == jsonObject && b.kind == jsonObject:
+		bmembers := make(map[string]*jsonValue, len(b.members))
+		for _, m := range b.members {
+			bmembers[m.key] = m.value
+		}
+
+		amembers := make(map[string]bool, len(a.members))
+		for _, m := range a.members {
+			amembers[m.key] = true
+			if v, ok := bmembers[m.key]; ok {
+				c.compare(m.value, v, at(pathFrame{key: m.key}))
+			} else {
+				c.add(diffDelete, at(pathFrame{key: m.key}))
+			}
+		}
+
+		for _, m := range b.members {
+			if !amembers[m.key] {
+				c.add(diffInsert, at(pathFrame{key: m.key}))
+			}
+		}
+	case a.kind == jsonArray && b.kind == jsonArray:
+		for i := 0; i < len(a.items) || i < len(b.items); i++ {
+			path := at(pathFrame{array: true, index: i})
+			switch {
+			case i >= len(b.items):
+				c.add(diffDelete, path)
+			case i >= len(a.items):
+				c.add(diffInsert, path)
+			default:
+				c.compare(a.items[i], b.items[i], path)
+			}
+		}
+	case a.kind != b.kind || a.scalar != b.scalar || a.str != b.str:
+		c.add(diffChange, frames)
+	}
+}
+
+// Return the paths that the filter of the document adds, removes, or changes
+// in the input. Both are compared in full, since sampling and paging would
+// only leave out part of the output.
+func (d *Document) Changes() ([]pathChange, error) {
+	opts := d.options.preview()
+	input, err := d.results((&Document{filter: ".", options: d.options}).EffectiveFilter(false), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := d.results(d.EffectiveFilter(false), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return changedPaths(input, output), nil
+}
+
+// Format the changed paths for the changes pane, one path per line. If color
+// is true, added, removed, and changed paths are highlighted.
+func formatChanges(changes []pathChange, color bool) string {
+	if len(changes) == 0 {
+		return "[::d](no changes)[::-]"
+	}
+
+	tags := map[diffOp]string{diffInsert: "[green]", diffDelete: "[red]", diffChange: "[yellow]"}
+
+	var sb strings.Builder
+	for _, c := range changes {
+		tag := ""
+		if color {
+			tag = tags[c.op]
+		}
+
+		fmt.Fprintf(&sb, "%s%c %s[-]\n", tag, c.op, tview.Escape(c.path))
+	}
+
+	return sb.String()
+}
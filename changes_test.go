@@ -0,0 +1,91 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedPaths(t *testing.T) {
+	parse := func(s string) []*jsonValue {
+		values, err := parseJSONStream([]byte(s))
+		assert.Nil(t, err)
+		return values
+	}
+
+	input := parse(`{"a": 1, "b": {"c": [1, 2, 3]}, "d": "x", "my key": null}`)
+	output := parse(`{"a": 2, "b": {"c": [1, 2]}, "e": {}, "my key": null}`)
+	assert.Equal(t, []pathChange{
+		{diffChange, ".a"},
+		{diffDelete, ".b.c[2]"},
+		{diffDelete, ".d"},
+		{diffInsert, ".e"},
+	}, changedPaths(input, output))
+
+	assert.Empty(t, changedPaths(input, input))
+
+	// A value of another type is changed as a whole
+	assert.Equal(t, []pathChange{{diffChange, ".b"}}, changedPaths(parse(`{"b": [1]}`), parse(`{"b": {"0": 1}}`)))
+	assert.Equal(t, []pathChange{{diffChange, "."}}, changedPaths(parse(`"1"`), parse(`1`)))
+
+	// Values of a stream are told apart by their index
+	assert.Equal(t, []pathChange{
+		{diffChange, "#0 .a"},
+		{diffDelete, "#1 ."},
+	}, changedPaths(parse(`{"a": 1} {"a": 2}`), parse(`{"a": 3}`)))
+}
+
+func TestFormatChanges(t *testing.T) {
+	changes := []pathChange{{diffInsert, `."[x]"`}, {diffChange, ".a"}}
+	assert.Equal(t, "+ .\"[x[]\"[-]\n~ .a[-]\n", formatChanges(changes, false))
+	assert.Equal(t, "[green]+ .\"[x[]\"[-]\n[yellow]~ .a[-]\n", formatChanges(changes, true))
+	assert.Equal(t, "[::d](no changes)[::-]", formatChanges(nil, true))
+}
+
+func TestDocumentChanges(t *testing.T) {
+	// cat outputs the input, so nothing changes
+	doc := &Document{input: `{"a": 1}`, filter: ".a", options: Options{command: "./testdata/cat"}}
+	changes, err := doc.Changes()
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	doc.options.command = "./testdata/caterror"
+	_, err = doc.Changes()
+	assert.NotNil(t, err)
+}
+
+func TestDocumentChangesPreview(t *testing.T) {
+	// Sampling leaves out elements of the output, which are not removed
+	doc := &Document{input: `[1, 2, 3]`, filter: ".", options: Options{command: "jq", sample: 2}}
+	changes, err := doc.Changes()
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	// Neither are the results on other pages
+	doc = &Document{input: "1 2", filter: ".", options: Options{command: "jq", pageSize: 1}}
+	changes, err = doc.Changes()
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	doc.filter = ". + 1"
+	changes, err = doc.Changes()
+	assert.Nil(t, err)
+	assert.Len(t, changes, 2)
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The version of jq that introduced each builtin after jq 1.5
+var builtinVersions = func() map[string]jqVersion {
+	versions := map[string]jqVersion{}
+	for _, b := range jqBuiltins[1:] {
+		for _, name := range b.builtins {
+			versions[name] = b.version
+		}
+	}
+
+	return versions
+}()
+
+// Return the builtins used in a filter that the given version of jq does not
+// have, sorted by name. Functions defined in the filter are not builtins.
+// This only looks at the names in the filter, so the filter need not
+// compile.
+func unsupportedBuiltins(filter string, version jqVersion) []string {
+	found := map[string]bool{}
+	defined := map[string]bool{}
+	collectBuiltins(filter, found, defined)
+
+	var names []string
+	for name := range found {
+		if introduced, ok := builtinVersions[name]; ok && !defined[name] && version.less(introduced) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func collectBuiltins(filter string, found, defined map[string]bool) {
+	tokens := tokenizeFilter(filter)
+	for i, tok := range tokens {
+		switch {
+		case tok.kind == tokenString:
+			for _, code := range interpolations(tok.text) {
+				collectBuiltins(code, found, defined)
+			}
+		case tok.kind != tokenIdent:
+		case i > 0 && tokens[i-1].text == "def":
+			defined[tok.text] = true
+		case i+1 < len(tokens) && tokens[i+1].text == ":":
+			// Identifiers followed by a colon are object keys
+		default:
+			found[tok.text] = true
+		}
+	}
+}
+
+// Return a warning naming the builtins used in a filter that the given
+// version of jq does not have, or an empty string if it has all of them
+func compatWarning(filter string, version jqVersion) string {
+	names := unsupportedBuiltins(filter, version)
+	if len(names) == 0 {
+		return ""
+	}
+
+	described := make([]string, len(names))
+	for i, name := range names {
+		described[i] = fmt.Sprintf("%s (jq %s)", name, builtinVersions[name])
+	}
+
+	return fmt.Sprintf("jq %s does not have %s", version, strings.Join(described, ", "))
+}
+
+// Return a warning naming the builtins used in the filter of the document
+// that the installed version of jq does not have, if -compat-warnings is
+// given
+func (d *Document) CompatWarning() string {
+	if !d.options.compatWarnings || d.jqVersion == nil {
+		return ""
+	}
+
+	return compatWarning(d.filter, *d.jqVersion)
+}
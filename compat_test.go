@@ -0,0 +1,59 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedBuiltins(t *testing.T) {
+	v16 := jqVersion{1, 6, 0}
+	assert.Equal(t, []string{"abs", "pick"}, unsupportedBuiltins(".[] | pick(.a) | .b | abs", v16))
+	assert.Empty(t, unsupportedBuiltins(".[] | pick(.a) | abs", jqVersion{1, 7, 1}))
+	assert.Equal(t, []string{"abs"}, unsupportedBuiltins(".[] | pick(.a) | abs", jqVersion{1, 7, 0}))
+
+	// Builtins of jq 1.5 and 1.6 are supported
+	assert.Empty(t, unsupportedBuiltins("map(select(.a)) | walk(.)", v16))
+
+	// Fields, variables, object keys, comments, and strings are not
+	// builtins, but interpolations are filters
+	assert.Empty(t, unsupportedBuiltins(`.abs | $pick | {trim: 1} | "ltrim" # toarray`, v16))
+	assert.Equal(t, []string{"trim"}, unsupportedBuiltins(`"\(.a | trim)"`, v16))
+
+	// Functions defined in the filter replace the builtins
+	assert.Empty(t, unsupportedBuiltins("def abs: if . < 0 then -. else . end; .[] | abs", v16))
+	assert.Equal(t, []string{"@base32d"}, unsupportedBuiltins(".a | @base32d", v16))
+}
+
+func TestCompatWarning(t *testing.T) {
+	assert.Equal(t, "jq 1.6 does not have abs (jq 1.7.1), pick (jq 1.7)", compatWarning("pick(.a) | abs", jqVersion{1, 6, 0}))
+	assert.Empty(t, compatWarning(".a", jqVersion{1, 6, 0}))
+
+	version := jqVersion{1, 6, 0}
+	doc := &Document{filter: "abs", jqVersion: &version}
+	assert.Empty(t, doc.CompatWarning())
+
+	doc.options.compatWarnings = true
+	assert.Equal(t, "jq 1.6 does not have abs (jq 1.7.1)", doc.CompatWarning())
+
+	// The warning needs the version of jq
+	doc.jqVersion = nil
+	assert.Empty(t, doc.CompatWarning())
+}
@@ -0,0 +1,254 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// User configuration, stored as JSON
+type Config struct {
+	path string
+
+	// Filters and paths that are always suggested first by autocomplete
+	Favorites []string `json:"favorites,omitempty"`
+
+	// Text inserted in the filter by pressing Alt and a key, by key
+	Inserts map[string]string `json:"inserts,omitempty"`
+
+	// The layout of the last session
+	Layout Layout `json:"layout"`
+
+	// Rules choosing the initial filter for the input, in order
+	FilterRules []FilterRule `json:"filter_rules,omitempty"`
+
+	// What Enter does in the filter field when -enter is not given
+	Enter string `json:"enter,omitempty"`
+
+	// The catalog of shared filters loaded when -catalog is not given
+	Catalog string `json:"catalog,omitempty"`
+
+	// How control characters are shown in the output pane, by character
+	ControlChars map[string]ControlChar `json:"control_chars,omitempty"`
+}
+
+// The actions Enter can have in the filter field
+var enterActions = map[string]bool{
+	EnterAccept: true, EnterNewline: true, EnterRun: true,
+}
+
+// A rule choosing the initial filter when no filter is given. The rule
+// matches if all of its conditions match, and a rule without conditions
+// matches any input.
+type FilterRule struct {
+	// A glob pattern matched against the base name of each input file
+	Files string `json:"files,omitempty"`
+
+	// The type of the first input value, as named by jq's type builtin
+	Type string `json:"type,omitempty"`
+
+	Filter string `json:"filter"`
+}
+
+// The types a filter rule can match
+var filterRuleTypes = map[string]bool{
+	"null": true, "boolean": true, "number": true,
+	"string": true, "array": true, "object": true,
+}
+
+func (r FilterRule) check() error {
+	if _, err := filepath.Match(r.Files, ""); err != nil {
+		return fmt.Errorf("invalid files pattern %q in filter rule", r.Files)
+	}
+
+	if r.Type != "" && !filterRuleTypes[r.Type] {
+		return fmt.Errorf("invalid type %q in filter rule: must be one of null, boolean, number, string, array, or object", r.Type)
+	}
+
+	return nil
+}
+
+func (r FilterRule) matches(files []string, inputType string) bool {
+	if r.Type != "" && r.Type != inputType {
+		return false
+	}
+
+	if r.Files == "" {
+		return true
+	}
+
+	for _, file := range files {
+		if ok, _ := filepath.Match(r.Files, filepath.Base(file)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the filter of the first rule matching the input files and the
+// type of the first input value
+func (c *Config) InitialFilter(files []string, inputType string) (string, bool) {
+	for _, r := range c.FilterRules {
+		if r.matches(files, inputType) {
+			return r.Filter, true
+		}
+	}
+
+	return "", false
+}
+
+// Layout preferences that are restored at startup
+type Layout struct {
+	ExpandFilter bool `json:"expand_filter,omitempty"`
+	ExpandError  bool `json:"expand_error,omitempty"`
+	RawInput     bool `json:"raw_input,omitempty"`
+}
+
+// Load the configuration from the given path. A missing file results in an
+// empty configuration.
+func (c *Config) Load(path string) error {
+	c.path = path
+	if path == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("error reading config: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, c); err != nil {
+		return fmt.Errorf("error reading config %s: %w", path, err)
+	}
+
+	for _, r := range c.FilterRules {
+		if err := r.check(); err != nil {
+			return fmt.Errorf("error reading config %s: %w", path, err)
+		}
+	}
+
+	if c.Enter != "" && !enterActions[c.Enter] {
+		return fmt.Errorf("error reading config %s: invalid enter action %q: must be one of accept, newline, or run", path, c.Enter)
+	}
+
+	for key := range c.Inserts {
+		if len([]rune(key)) == 1 && strings.Contains(boundAltKeys, key) {
+			return fmt.Errorf("error reading config %s: cannot insert text with Alt-%s, which is bound to a command", path, key)
+		}
+	}
+
+	for key, style := range c.ControlChars {
+		if _, err := parseControlChar(key); err != nil {
+			return fmt.Errorf("error reading config %s: %w", path, err)
+		}
+
+		if err := style.check(); err != nil {
+			return fmt.Errorf("error reading config %s: control character %q: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+// Write the configuration back to the file it was loaded from
+func (c *Config) Save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, append(contents, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+
+	return nil
+}
+
+// The keys that are bound to commands when pressed with Alt, which cannot
+// insert text
+const boundAltKeys = "ADEacdeghijklmnopqrstuwxyz.,123456789"
+
+// Text inserted in the filter by pressing Alt and a key when the
+// configuration does not set it
+var defaultInserts = map[string]string{
+	"|": " | ",
+	"[": "[]",
+}
+
+// Return the text inserted in the filter by pressing Alt and the key. The
+// configured insertions take precedence over the defaults, and a default can
+// be disabled by configuring an empty insertion.
+func (c *Config) Insertion(key rune) (string, bool) {
+	text, ok := c.Inserts[string(key)]
+	if !ok {
+		text = defaultInserts[string(key)]
+	}
+
+	return text, text != ""
+}
+
+// Add the entry to the favorites if it is not one already, otherwise remove
+// it. Returns true if the entry is now a favorite.
+func (c *Config) ToggleFavorite(entry string) bool {
+	for i, fav := range c.Favorites {
+		if fav == entry {
+			c.Favorites = append(c.Favorites[:i], c.Favorites[i+1:]...)
+			return false
+		}
+	}
+
+	c.Favorites = append(c.Favorites, entry)
+	return true
+}
+
+// Put the favorites that begin with text ahead of the other entries,
+// removing duplicates
+func (c *Config) PinFavorites(text string, entries []string) []string {
+	var pinned []string
+	for _, fav := range c.Favorites {
+		if len(fav) >= len(text) && fav[:len(text)] == text {
+			pinned = append(pinned, fav)
+		}
+	}
+
+	for _, e := range entries {
+		if !contains(pinned, e) {
+			pinned = append(pinned, e)
+		}
+	}
+
+	return pinned
+}
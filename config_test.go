@@ -0,0 +1,148 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigLoadMissing(t *testing.T) {
+	var c Config
+	assert.NoError(t, c.Load(filepath.Join(t.TempDir(), "missing.json")))
+	assert.Empty(t, c.Favorites)
+}
+
+func TestConfigLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+
+	var c Config
+	assert.Error(t, c.Load(path))
+}
+
+func TestConfigSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "config.json")
+
+	var c Config
+	assert.NoError(t, c.Load(path))
+	c.Favorites = []string{".foo"}
+	c.Layout.ExpandError = true
+	assert.NoError(t, c.Save())
+
+	var loaded Config
+	assert.NoError(t, loaded.Load(path))
+	assert.Equal(t, []string{".foo"}, loaded.Favorites)
+	assert.Equal(t, Layout{ExpandError: true}, loaded.Layout)
+}
+
+func TestConfigToggleFavorite(t *testing.T) {
+	var c Config
+	assert.True(t, c.ToggleFavorite(".a"))
+	assert.True(t, c.ToggleFavorite(".b"))
+	assert.Equal(t, []string{".a", ".b"}, c.Favorites)
+	assert.False(t, c.ToggleFavorite(".a"))
+	assert.Equal(t, []string{".b"}, c.Favorites)
+}
+
+func TestConfigInsertion(t *testing.T) {
+	c := Config{Inserts: map[string]string{"f": "select()", "[": ""}}
+
+	text, ok := c.Insertion('|')
+	assert.True(t, ok)
+	assert.Equal(t, " | ", text)
+
+	text, ok = c.Insertion('f')
+	assert.True(t, ok)
+	assert.Equal(t, "select()", text)
+
+	_, ok = c.Insertion('[')
+	assert.False(t, ok)
+
+	_, ok = c.Insertion('x')
+	assert.False(t, ok)
+}
+
+func TestConfigPinFavorites(t *testing.T) {
+	c := Config{Favorites: []string{".items", ".meta", "keys"}}
+	assert.Equal(t, []string{".items", ".meta", ".id"}, c.PinFavorites(".", []string{".id", ".items"}))
+	assert.Equal(t, []string{".items", ".meta", "keys", ".a"}, c.PinFavorites("", []string{".a"}))
+	assert.Empty(t, c.PinFavorites(".x", nil))
+}
+
+func TestConfigInitialFilter(t *testing.T) {
+	c := Config{FilterRules: []FilterRule{
+		{Files: "*.har", Filter: ".log.entries[]"},
+		{Files: "*.json", Type: "array", Filter: ".[]"},
+		{Type: "object", Filter: "keys"},
+	}}
+
+	filter, ok := c.InitialFilter([]string{"other.json", "dir/site.har"}, "object")
+	assert.True(t, ok)
+	assert.Equal(t, ".log.entries[]", filter)
+
+	filter, ok = c.InitialFilter([]string{"list.json"}, "array")
+	assert.True(t, ok)
+	assert.Equal(t, ".[]", filter)
+
+	filter, ok = c.InitialFilter(nil, "object")
+	assert.True(t, ok)
+	assert.Equal(t, "keys", filter)
+
+	_, ok = c.InitialFilter([]string{"list.json"}, "string")
+	assert.False(t, ok)
+}
+
+func TestConfigLoadInvalidFilterRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var c Config
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"filter_rules": [{"files": "[", "filter": "."}]}`), 0644))
+	assert.Error(t, c.Load(path))
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"filter_rules": [{"type": "list", "filter": "."}]}`), 0644))
+	assert.Error(t, c.Load(path))
+}
+
+func TestConfigLoadInserts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var c Config
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"inserts": {"f": "select(", "/": " // "}}`), 0644))
+	assert.NoError(t, c.Load(path))
+	assert.Equal(t, " // ", c.Inserts["/"])
+
+	// Alt-S selects the value at the top of the input
+	assert.NoError(t, os.WriteFile(path, []byte(`{"inserts": {"s": "select("}}`), 0644))
+	assert.Error(t, c.Load(path))
+}
+
+func TestConfigLoadEnter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var c Config
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"enter": "newline"}`), 0644))
+	assert.NoError(t, c.Load(path))
+	assert.Equal(t, EnterNewline, c.Enter)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"enter": "exit"}`), 0644))
+	assert.Error(t, c.Load(path))
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// How a control character is shown in the output pane
+type ControlChar struct {
+	// The text shown instead of the character, or empty to show its
+	// escape code
+	Glyph string `json:"glyph,omitempty"`
+
+	// The color of the text, as a name such as "yellow" or as #rrggbb, or
+	// empty to use the color of the surrounding text
+	Color string `json:"color,omitempty"`
+}
+
+// The control characters shown in the output pane when the configuration
+// does not set them. The record separator written by jq with --seq frames
+// each value.
+var defaultControlChars = map[rune]ControlChar{
+	'\x1e': {Glyph: "␞"},
+}
+
+func isControlChar(r rune) bool {
+	return (r < 0x20 || r == 0x7f) && r != '\n' && r != '\t'
+}
+
+func (c ControlChar) check() error {
+	for _, r := range c.Glyph {
+		if isControlChar(r) {
+			return fmt.Errorf("invalid glyph %q: must be printable", c.Glyph)
+		}
+	}
+
+	if c.Color != "" && tcell.GetColor(c.Color) == tcell.ColorDefault {
+		return fmt.Errorf("invalid color %q: must be a color name or #rrggbb", c.Color)
+	}
+
+	return nil
+}
+
+// Parse the key of a control character in the configuration, which is the
+// character itself, e.g. "\u001e"
+func parseControlChar(key string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(key)
+	if size != len(key) || !isControlChar(r) || r == '\x1b' {
+		return 0, fmt.Errorf("invalid control character %q: must be a single control character other than newline, tab, or escape", key)
+	}
+
+	return r, nil
+}
+
+// Return how control characters are shown in the output pane. The
+// configured characters take precedence over the defaults.
+func (c *Config) ControlCharStyles() map[rune]ControlChar {
+	styles := make(map[rune]ControlChar, len(defaultControlChars)+len(c.ControlChars))
+	for r, style := range defaultControlChars {
+		styles[r] = style
+	}
+
+	for key, style := range c.ControlChars {
+		// The keys are checked when the configuration is loaded
+		if r, err := parseControlChar(key); err == nil {
+			styles[r] = style
+		}
+	}
+
+	return styles
+}
+
+// Replace the control characters that have a style with their glyph or
+// escape code, colored with an ANSI color sequence that the output pane
+// converts to its own colors. The foreground color is reset after the
+// glyph, which is where jq resets it too.
+func visualizeControlChars(out []byte, styles map[rune]ControlChar) []byte {
+	if len(styles) == 0 {
+		return out
+	}
+
+	var buf bytes.Buffer
+	for _, b := range out {
+		style, ok := styles[rune(b)]
+		if !ok {
+			buf.WriteByte(b)
+			continue
+		}
+
+		glyph := style.Glyph
+		if glyph == "" {
+			glyph = fmt.Sprintf(`\x%02x`, b)
+		}
+
+		if style.Color == "" {
+			buf.WriteString(glyph)
+			continue
+		}
+
+		r, g, bl := tcell.GetColor(style.Color).RGB()
+		fmt.Fprintf(&buf, "\x1b[38;2;%d;%d;%dm%s\x1b[39m", r, g, bl, glyph)
+	}
+
+	return buf.Bytes()
+}
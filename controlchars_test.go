@@ -0,0 +1,81 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisualizeControlChars(t *testing.T) {
+	styles := map[rune]ControlChar{
+		'\x1e': {Glyph: "␞"},
+		'\x01': {Color: "#ff0000"},
+		'\x02': {Glyph: "^B", Color: "#00ff00"},
+	}
+
+	assert.Equal(t, "␞1\n␞2\n", string(visualizeControlChars([]byte("\x1e1\n\x1e2\n"), styles)))
+	assert.Equal(t, "a\x1b[38;2;255;0;0m\\x01\x1b[39mb", string(visualizeControlChars([]byte("a\x01b"), styles)))
+	assert.Equal(t, "\x1b[38;2;0;255;0m^B\x1b[39m", string(visualizeControlChars([]byte("\x02"), styles)))
+
+	// Characters without a style and multibyte characters are kept
+	assert.Equal(t, "\x03é\x1b[0m", string(visualizeControlChars([]byte("\x03é\x1b[0m"), styles)))
+	assert.Equal(t, "\x1e", string(visualizeControlChars([]byte("\x1e"), nil)))
+
+	// The glyph is not escaped again
+	assert.Equal(t, "␞1", string(escapeNonPrintable(visualizeControlChars([]byte("\x1e1"), styles))))
+}
+
+func TestConfigControlCharStyles(t *testing.T) {
+	var c Config
+	assert.Equal(t, defaultControlChars, c.ControlCharStyles())
+
+	c.ControlChars = map[string]ControlChar{"\x1e": {Glyph: "|", Color: "yellow"}, "\x00": {Glyph: "∅"}}
+	assert.Equal(t, map[rune]ControlChar{
+		'\x1e': {Glyph: "|", Color: "yellow"},
+		'\x00': {Glyph: "∅"},
+	}, c.ControlCharStyles())
+
+	// The defaults are not changed
+	assert.Equal(t, "␞", defaultControlChars['\x1e'].Glyph)
+}
+
+func TestConfigLoadControlChars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var c Config
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"control_chars": {"\u001e": {"glyph": "␞", "color": "yellow"}}}`), 0644))
+	assert.NoError(t, c.Load(path))
+	assert.Equal(t, ControlChar{Glyph: "␞", Color: "yellow"}, c.ControlChars["\x1e"])
+
+	for _, contents := range []string{
+		`{"control_chars": {"a": {"glyph": "A"}}}`,
+		`{"control_chars": {"\n": {"glyph": "N"}}}`,
+		`{"control_chars": {"\u001b": {"glyph": "E"}}}`,
+		`{"control_chars": {"\u0001\u0002": {"glyph": "x"}}}`,
+		`{"control_chars": {"\u0001": {"glyph": "\u0001"}}}`,
+		`{"control_chars": {"\u0001": {"color": "bluish"}}}`,
+	} {
+		var c Config
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+		assert.Error(t, c.Load(path), contents)
+	}
+}
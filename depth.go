@@ -0,0 +1,60 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "bytes"
+
+// Collapsed objects and arrays are replaced with strings beginning with this
+// character from the Unicode private use area, so that jq can format the
+// results, and the strings are then replaced with the markers
+const depthMarker string = "\ue001"
+
+// Replace the objects and arrays nested deeper than max levels in the value,
+// the value itself being on the first level, with marker strings. Empty
+// objects and arrays are kept, since they show as much as a marker.
+func (v *jsonValue) collapseBelow(max int) {
+	v.collapse(max, 1)
+}
+
+func (v *jsonValue) collapse(max, level int) {
+	for _, item := range v.items {
+		item.collapse(max, level+1)
+	}
+
+	for _, m := range v.members {
+		m.value.collapse(max, level+1)
+	}
+
+	if level <= max {
+		return
+	}
+
+	switch {
+	case v.kind == jsonObject && len(v.members) > 0:
+		*v = jsonValue{kind: jsonString, str: depthMarker + "{...}"}
+	case v.kind == jsonArray && len(v.items) > 0:
+		*v = jsonValue{kind: jsonString, str: depthMarker + "[...]"}
+	}
+}
+
+// Replace the marker strings in the output formatted by jq with {...} and
+// [...], which keep the color of strings
+func expandDepthMarkers(out []byte) []byte {
+	out = bytes.ReplaceAll(out, []byte(`"`+depthMarker+`{...}"`), []byte("{...}"))
+	return bytes.ReplaceAll(out, []byte(`"`+depthMarker+`[...]"`), []byte("[...]"))
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseBelow(t *testing.T) {
+	collapse := func(input string, max int) string {
+		values, err := parseJSONStream([]byte(input))
+		assert.Nil(t, err)
+		for _, v := range values {
+			v.collapseBelow(max)
+		}
+
+		return string(expandDepthMarkers(formatJSONStream(values, "")))
+	}
+
+	input := `{"a": {"b": {"c": [1, {"d": 2}]}, "e": [], "f": {}}, "g": [[1], 2]}`
+	assert.Equal(t, `{"a":{...},"g":[...]}`+"\n", collapse(input, 1))
+	assert.Equal(t, `{"a":{"b":{...},"e":[],"f":{}},"g":[[...],2]}`+"\n", collapse(input, 2))
+	assert.Equal(t, `{"a":{"b":{"c":[1,{"d":2}]},"e":[],"f":{}},"g":[[1],2]}`+"\n", collapse(input, 5))
+
+	// Scalars are never collapsed
+	assert.Equal(t, "1\n\"x\"\n", collapse(`1 "x"`, 1))
+}
+
+func TestWriteToMaxDepth(t *testing.T) {
+	doc := Document{
+		input:   `{"a": {"b": [1]}}`,
+		filter:  ".",
+		options: Options{command: "./testdata/cat", maxDepth: 1},
+	}
+
+	// The depth is only limited in the output pane
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a": {"b": [1]}}`, buf.String())
+
+	// cat outputs the collapsed results it is given to format
+	tv := tview.NewTextView()
+	_, err = doc.WriteTo(tv)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":{...}}`+"\n", tv.GetText(true))
+
+	assert.Equal(t, 0, doc.options.inputViewOptions().maxDepth)
+}
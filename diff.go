@@ -0,0 +1,130 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rivo/tview"
+)
+
+type diffOp byte
+
+const (
+	diffEqual  diffOp = ' '
+	diffDelete diffOp = '-'
+	diffInsert diffOp = '+'
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// Compute a line diff between a and b. Replaced lines are deleted before
+// the lines replacing them are inserted. Lines such as "}," are frequent in
+// formatted JSON, so they are not treated as junk.
+func diffLines(a, b []string) []diffLine {
+	var lines []diffLine
+	for _, op := range difflib.NewMatcherWithJunk(a, b, false, nil).GetOpCodes() {
+		if op.Tag == 'e' {
+			for _, text := range a[op.I1:op.I2] {
+				lines = append(lines, diffLine{diffEqual, text})
+			}
+
+			continue
+		}
+
+		for _, text := range a[op.I1:op.I2] {
+			lines = append(lines, diffLine{diffDelete, text})
+		}
+
+		for _, text := range b[op.J1:op.J2] {
+			lines = append(lines, diffLine{diffInsert, text})
+		}
+	}
+
+	return lines
+}
+
+// Split text into lines, ignoring a trailing newline
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
+
+// Write a diff of a and b to the TextView. If color is true, added and
+// removed lines are highlighted.
+func writeDiff(tv *tview.TextView, a, b string, color bool) error {
+	tv.Clear()
+	for _, line := range diffLines(splitLines(a), splitLines(b)) {
+		text := tview.Escape(line.text)
+
+		var tag string
+		if color && line.op == diffInsert {
+			tag = "[green]"
+		} else if color && line.op == diffDelete {
+			tag = "[red]"
+		}
+
+		if _, err := fmt.Fprintf(tv, "%s%c %s[-]\n", tag, line.op, text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Render the document input and filtered output without colors so that they
+// can be compared line by line
+func (d *Document) render(filter string) (string, error) {
+	c := Document{input: d.input, filter: filter, options: d.options.preview(), inputFile: d.inputFile}
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.seq = false
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Write a diff between the formatted input and the filtered output of the
+// document
+func (d *Document) WriteDiffTo(tv *tview.TextView) error {
+	input, err := d.render(".")
+	if err != nil {
+		return err
+	}
+
+	output, err := d.render(d.previewFilter())
+	if err != nil {
+		return err
+	}
+
+	return writeDiff(tv, input, output, !d.options.monoUI)
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"{", `  "a": 1,`, `  "b": 2`, "}"}
+	b := []string{"{", `  "a": 1,`, `  "b": 3,`, `  "c": 4`, "}"}
+
+	assert.Equal(t, []diffLine{
+		{diffEqual, "{"},
+		{diffEqual, `  "a": 1,`},
+		{diffDelete, `  "b": 2`},
+		{diffInsert, `  "b": 3,`},
+		{diffInsert, `  "c": 4`},
+		{diffEqual, "}"},
+	}, diffLines(a, b))
+}
+
+func TestDiffLinesEmpty(t *testing.T) {
+	assert.Empty(t, diffLines(nil, nil))
+	assert.Equal(t, []diffLine{{diffInsert, "a"}}, diffLines(nil, []string{"a"}))
+	assert.Equal(t, []diffLine{{diffDelete, "a"}}, diffLines([]string{"a"}, nil))
+}
+
+func TestSplitLines(t *testing.T) {
+	assert.Nil(t, splitLines(""))
+	assert.Equal(t, []string{"a", "b"}, splitLines("a\nb\n"))
+}
+
+func TestDiffLinesFrequentLines(t *testing.T) {
+	// Lines repeated throughout long inputs still match
+	var a []string
+	for i := 0; i < 300; i++ {
+		a = append(a, "  {", fmt.Sprintf(`    "id": %d`, i), "  },")
+	}
+
+	b := append([]string{"["}, a...)
+	lines := diffLines(a, b)
+	assert.Len(t, lines, len(b))
+	assert.Equal(t, diffLine{diffInsert, "["}, lines[0])
+	for _, line := range lines[1:] {
+		assert.Equal(t, diffEqual, line.op)
+	}
+}
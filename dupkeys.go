@@ -0,0 +1,124 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Inputs larger than this many bytes are not checked for duplicate keys
+const dupKeysMaxSize int = 16 << 20
+
+// At most this many paths of duplicate keys are listed
+const dupKeysMaxPaths int = 10
+
+// Find the object keys that occur more than once in the same object of a
+// stream of JSON values. The input is read token by token, without building
+// the values. Returns the path of each duplicate key, once per object; paths
+// in values after the first of the stream are labeled with the value number.
+func duplicateKeys(input string) ([]string, error) {
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var paths []string
+	for i := 0; ; i++ {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return paths, nil
+		}
+
+		if err != nil {
+			return paths, err
+		}
+
+		label := ""
+		if i > 0 {
+			label = fmt.Sprintf(" (value %d)", i+1)
+		}
+
+		if err := walkDuplicateKeys(dec, tok, nil, label, &paths); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return paths, err
+		}
+	}
+}
+
+func walkDuplicateKeys(dec *json.Decoder, tok json.Token, frames []pathFrame, label string, paths *[]string) error {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]int{}
+	for i := 0; dec.More(); i++ {
+		frame := pathFrame{array: true, index: i}
+		if delim == '{' {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			key := keyTok.(string)
+			frame = pathFrame{key: key}
+			seen[key]++
+			if seen[key] == 2 {
+				*paths = append(*paths, formatPath(append(frames, frame))+label)
+			}
+		}
+
+		valueTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if err := walkDuplicateKeys(dec, valueTok, append(frames, frame), label, paths); err != nil {
+			return err
+		}
+	}
+
+	// The closing delimiter
+	_, err := dec.Token()
+	return err
+}
+
+// Describe the duplicate keys in the input, or return the empty string if
+// there are none or the input cannot be checked
+func duplicateKeysWarning(input string) string {
+	if len(input) > dupKeysMaxSize {
+		return ""
+	}
+
+	paths, err := duplicateKeys(input)
+	if err != nil || len(paths) == 0 {
+		return ""
+	}
+
+	more := ""
+	if len(paths) > dupKeysMaxPaths {
+		more = fmt.Sprintf(" and %d more", len(paths)-dupKeysMaxPaths)
+		paths = paths[:dupKeysMaxPaths]
+	}
+
+	return fmt.Sprintf("Duplicate keys in the input, jq keeps the last value: %s%s", strings.Join(paths, ", "), more)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	paths, err := duplicateKeys(`{"a": 1, "b": [{"c": 1, "c": 2, "c": 3}], "a": 2} {"x": {}, "x": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".b[0].c", ".a", ".x (value 2)"}, paths)
+
+	paths, err = duplicateKeys(`{"a": {"b": 1}, "c": {"b": 2}} [1, "a"]`)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, err = duplicateKeys(`{"a": 1, "a"`)
+	assert.Error(t, err)
+}
+
+func TestDuplicateKeysWarning(t *testing.T) {
+	assert.Empty(t, duplicateKeysWarning(`{"a": 1}`))
+	assert.Empty(t, duplicateKeysWarning("not json"))
+	assert.Equal(t, "Duplicate keys in the input, jq keeps the last value: .a", duplicateKeysWarning(`{"a": 1, "a": 2}`))
+
+	var values []string
+	for i := 0; i < dupKeysMaxPaths+2; i++ {
+		values = append(values, fmt.Sprintf(`"k%d": 1, "k%d": 2`, i, i))
+	}
+
+	warning := duplicateKeysWarning("{" + strings.Join(values, ", ") + "}")
+	assert.True(t, strings.HasSuffix(warning, ".k9 and 2 more"))
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// The editor used when neither $VISUAL nor $EDITOR is set
+const defaultEditor string = "vi"
+
+// Return the editor command from $VISUAL or $EDITOR, like other terminal
+// programs. The command is run with the shell and may include arguments.
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+
+	return defaultEditor
+}
+
+// Edit text in a temporary file with the editor command, which inherits
+// the terminal, and return the edited text
+func editText(editor, text string) (string, error) {
+	f, err := os.CreateTemp("", "ijq-input-*.json")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	return string(edited), err
+}
+
+// Replace the input with an edited version of it, which must be valid JSON
+// unless the input is raw text. The input as it was before the first edit
+// is kept, so that the edits can be undone.
+func (d *Document) EditInput(text string) error {
+	if !d.options.rawInput && !d.options.nullInput {
+		if _, err := parseJSONStream([]byte(text)); err != nil {
+			return fmt.Errorf("the edited input is not valid JSON: %v", err)
+		}
+	}
+
+	if !d.edited {
+		d.originalInput, d.edited = d.input, true
+	}
+
+	d.input = text
+	return nil
+}
+
+// Restore the input as it was before it was edited. Report whether the
+// input was edited.
+func (d *Document) RevertInput() bool {
+	if !d.edited {
+		return false
+	}
+
+	d.input, d.originalInput, d.edited = d.originalInput, "", false
+	return true
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	assert.Equal(t, defaultEditor, editorCommand())
+
+	t.Setenv("EDITOR", "nano -w")
+	assert.Equal(t, "nano -w", editorCommand())
+
+	t.Setenv("VISUAL", "code --wait")
+	assert.Equal(t, "code --wait", editorCommand())
+}
+
+func TestEditText(t *testing.T) {
+	edited, err := editText("sed -i s/1/2/", `{"a": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a": 2}`, edited)
+
+	_, err = editText("false", `{"a": 1}`)
+	assert.EqualError(t, err, `editor "false" failed: exit status 1`)
+}
+
+func TestDocumentEditInput(t *testing.T) {
+	doc := &Document{input: `{"a": 1}`}
+	assert.False(t, doc.RevertInput())
+
+	err := doc.EditInput(`{"a": 2`)
+	assert.EqualError(t, err, "the edited input is not valid JSON: unexpected end of JSON input")
+	assert.Equal(t, `{"a": 1}`, doc.input)
+	assert.False(t, doc.edited)
+
+	// The original input is kept across several edits
+	assert.NoError(t, doc.EditInput(`{"a": 2}`))
+	assert.NoError(t, doc.EditInput(`{"a": 3} {"b": 4}`))
+	assert.Equal(t, `{"a": 3} {"b": 4}`, doc.input)
+	assert.True(t, doc.edited)
+
+	assert.True(t, doc.RevertInput())
+	assert.Equal(t, `{"a": 1}`, doc.input)
+	assert.False(t, doc.edited)
+	assert.False(t, doc.RevertInput())
+
+	// Raw input is not JSON
+	doc.options.rawInput = true
+	assert.NoError(t, doc.EditInput("not {json"))
+	assert.Equal(t, "not {json", doc.input)
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Look up the encoding of the final output by its IANA name, e.g. latin1 or
+// utf-16. UTF-8 (or an empty name) needs no conversion and returns nil.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	if enc == nil {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	if enc == unicode.UTF8 {
+		return nil, nil
+	}
+
+	return enc, nil
+}
+
+// Convert UTF-8 output to the named encoding. The error names the first
+// character that the encoding cannot represent.
+func encodeOutput(data []byte, name string) ([]byte, error) {
+	enc, err := lookupEncoding(name)
+	if enc == nil || err != nil {
+		return data, err
+	}
+
+	out, err := enc.NewEncoder().Bytes(data)
+	if err == nil {
+		return out, nil
+	}
+
+	for _, r := range string(data) {
+		if _, err := enc.NewEncoder().String(string(r)); err != nil {
+			return nil, fmt.Errorf("cannot encode %q in %s", r, name)
+		}
+	}
+
+	return nil, fmt.Errorf("cannot encode the output in %s: %w", name, err)
+}
+
+// A writer converting the UTF-8 text written to it to an encoding. Each
+// write is converted on its own, which works for Document.WriteTo since it
+// writes its output at once.
+type encodingWriter struct {
+	w    io.Writer
+	name string
+}
+
+// Return a writer writing to w in the named encoding
+func newEncodingWriter(w io.Writer, name string) io.Writer {
+	if name == "" {
+		return w
+	}
+
+	return encodingWriter{w, name}
+}
+
+func (e encodingWriter) Write(p []byte) (int, error) {
+	data, err := encodeOutput(p, e.name)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := e.w.Write(data); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
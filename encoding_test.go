@@ -0,0 +1,68 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupEncoding(t *testing.T) {
+	for _, name := range []string{"", "utf-8", "UTF-8"} {
+		enc, err := lookupEncoding(name)
+		assert.NoError(t, err)
+		assert.Nil(t, enc)
+	}
+
+	enc, err := lookupEncoding("latin1")
+	assert.NoError(t, err)
+	assert.NotNil(t, enc)
+
+	_, err = lookupEncoding("klingon")
+	assert.EqualError(t, err, `unknown encoding "klingon"`)
+}
+
+func TestEncodeOutput(t *testing.T) {
+	out, err := encodeOutput([]byte("\"é\"\n"), "latin1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\"\xe9\"\n"), out)
+
+	out, err = encodeOutput([]byte("é"), "utf-16le")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xe9, 0x00}, out)
+
+	out, err = encodeOutput([]byte("é"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("é"), out)
+
+	_, err = encodeOutput([]byte("a€"), "latin1")
+	assert.EqualError(t, err, `cannot encode '€' in latin1`)
+}
+
+func TestEncodingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newEncodingWriter(&buf, "latin1")
+	n, err := w.Write([]byte("é"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "\xe9", buf.String())
+
+	assert.Equal(t, &buf, newEncodingWriter(&buf, ""))
+}
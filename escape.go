@@ -0,0 +1,61 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Replace characters that are not printable with escape codes, so that
+// binary data cannot disturb the terminal. Control characters and invalid
+// UTF-8 are written as \xNN and other unprintable characters, such as
+// zero-width and bidirectional formatting characters, as \uNNNN. Newlines,
+// tabs, and the color sequences written by jq are kept.
+func escapeNonPrintable(out []byte) []byte {
+	var buf bytes.Buffer
+	for len(out) > 0 {
+		if out[0] == '\x1b' {
+			if loc := ansiEscapePattern.FindIndex(out); loc != nil && loc[0] == 0 {
+				buf.Write(out[:loc[1]])
+				out = out[loc[1]:]
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRune(out)
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&buf, `\x%02x`, out[0])
+		case r == '\n' || r == '\t':
+			buf.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, r)
+		case !unicode.IsGraphic(r):
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		default:
+			buf.Write(out[:size])
+		}
+
+		out = out[size:]
+	}
+
+	return buf.Bytes()
+}
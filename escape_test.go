@@ -0,0 +1,33 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeNonPrintable(t *testing.T) {
+	assert.Equal(t, "a\tb\nc", string(escapeNonPrintable([]byte("a\tb\nc"))))
+	assert.Equal(t, `a\x00b\x1b\x7f`, string(escapeNonPrintable([]byte("a\x00b\x1b\x7f"))))
+	assert.Equal(t, `\xff\xfe`, string(escapeNonPrintable([]byte("\xff\xfe"))))
+	assert.Equal(t, `x\u200by\u0085`, string(escapeNonPrintable([]byte("x\u200by\u0085"))))
+	assert.Equal(t, "\x1b[1;39m{}\x1b[0m é", string(escapeNonPrintable([]byte("\x1b[1;39m{}\x1b[0m é"))))
+	assert.Empty(t, escapeNonPrintable(nil))
+}
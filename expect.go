@@ -0,0 +1,54 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/rivo/tview"
+)
+
+// Format the expected output given with -expect in the same way as the
+// output is rendered for a diff, so that differences in formatting (such as
+// compact output) do not count. Expected output that is not JSON, such as raw
+// strings, is used as is.
+func (d *Document) formatExpected(expected string) string {
+	c := Document{input: expected, options: d.options}
+	c.options.prefix = ""
+	c.options.selection = ""
+	c.options.nullInput = false
+	c.options.slurp = false
+	c.options.rawInput = false
+	c.options.softErrors = false
+
+	formatted, err := c.render(".")
+	if err != nil {
+		return expected
+	}
+
+	return formatted
+}
+
+// Write a diff between the expected output and the complete filtered output
+// of the document. Report whether they are equal.
+func (d *Document) WriteExpectedDiffTo(tv *tview.TextView) (bool, error) {
+	output, err := d.render(d.filter)
+	if err != nil {
+		return false, err
+	}
+
+	return output == d.expected, writeDiff(tv, d.expected, output, !d.options.monoUI)
+}
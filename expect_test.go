@@ -0,0 +1,55 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentWriteExpectedDiffTo(t *testing.T) {
+	doc := &Document{
+		input:    "a\nb\n",
+		filter:   ".",
+		options:  Options{command: "./testdata/cat", monoUI: true},
+		expected: "a\nc\n",
+	}
+
+	tv := tview.NewTextView().SetDynamicColors(true)
+	match, err := doc.WriteExpectedDiffTo(tv)
+	assert.NoError(t, err)
+	assert.False(t, match)
+	assert.Equal(t, "  a\n- c\n+ b\n", tv.GetText(true))
+
+	doc.expected = doc.formatExpected("a\nb\n")
+	match, err = doc.WriteExpectedDiffTo(tv)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, "  a\n  b\n", tv.GetText(true))
+}
+
+func TestDocumentFormatExpected(t *testing.T) {
+	doc := &Document{options: Options{command: "cat", prefix: ".a"}}
+	assert.Equal(t, "x\n", doc.formatExpected("x\n"))
+
+	// Expected output is kept as is when it cannot be formatted
+	doc.options.command = "./testdata/caterror"
+	assert.Equal(t, "{\"a\":1}", doc.formatExpected("{\"a\":1}"))
+}
@@ -0,0 +1,347 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenField
+	tokenVariable
+	tokenOperator
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// Operators sorted so that longer operators are matched first
+var operators = []string{
+	"//=", "|=", "+=", "-=", "*=", "/=", "%=",
+	"==", "!=", "<=", ">=", "//", "..", "?//",
+	"|", ",", "+", "-", "*", "/", "%", "<", ">", "=", "?",
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// Split a jq filter into tokens. This is not a full jq lexer, but it is good
+// enough to find the builtins, keywords, and operators used in a filter.
+// Comments are skipped and string literals (including any interpolations) are
+// returned as a single token.
+func tokenizeFilter(filter string) []token {
+	var tokens []token
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '#':
+			for i < len(filter) && filter[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			i++
+			depth := 0
+			for i < len(filter) {
+				if filter[i] == '\\' && i+1 < len(filter) && filter[i+1] == '(' {
+					depth++
+					i += 2
+					continue
+				}
+
+				if filter[i] == '\\' {
+					i += 2
+					continue
+				}
+
+				if depth > 0 && filter[i] == ')' {
+					depth--
+				} else if depth == 0 && filter[i] == '"' {
+					i++
+					break
+				}
+
+				i++
+			}
+
+			if i > len(filter) {
+				i = len(filter)
+			}
+
+			tokens = append(tokens, token{tokenString, filter[start:i], start, i})
+			continue
+		case c == '$' || (c == '@' && i+1 < len(filter) && isIdentStart(filter[i+1])):
+			i++
+			for i < len(filter) && (isIdentChar(filter[i]) || filter[i] == ':') {
+				i++
+			}
+
+			kind := tokenVariable
+			if c == '@' {
+				kind = tokenIdent
+			}
+
+			tokens = append(tokens, token{kind, filter[start:i], start, i})
+			continue
+		case c == '.' && i+1 < len(filter) && isIdentStart(filter[i+1]):
+			i++
+			for i < len(filter) && isIdentChar(filter[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenField, filter[start:i], start, i})
+			continue
+		case isIdentStart(c):
+			for i < len(filter) && (isIdentChar(filter[i]) || (filter[i] == ':' && i+1 < len(filter) && filter[i+1] == ':')) {
+				if filter[i] == ':' {
+					i++
+				}
+				i++
+			}
+
+			tokens = append(tokens, token{tokenIdent, filter[start:i], start, i})
+			continue
+		case c >= '0' && c <= '9':
+			for i < len(filter) && (isIdentChar(filter[i]) || filter[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenNumber, filter[start:i], start, i})
+			continue
+		}
+
+		matched := false
+		for _, op := range operators {
+			if strings.HasPrefix(filter[i:], op) {
+				i += len(op)
+				tokens = append(tokens, token{tokenOperator, op, start, i})
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			i++
+			tokens = append(tokens, token{tokenPunct, filter[start:i], start, i})
+		}
+	}
+
+	return tokens
+}
+
+// Short descriptions of jq keywords and operators
+var operatorDocs = map[string]string{
+	"|":       "Pipe: feed the output of the left filter into the right filter",
+	",":       "Comma: produce the outputs of the left filter followed by those of the right",
+	"+":       "Add numbers, concatenate strings or arrays, or merge objects",
+	"-":       "Subtract numbers, or remove elements from an array",
+	"*":       "Multiply numbers, repeat a string, or recursively merge objects",
+	"/":       "Divide numbers, or split a string by a separator",
+	"%":       "Remainder of integer division",
+	"==":      "True if both sides are equal",
+	"!=":      "True if both sides are not equal",
+	"<":       "True if the left side is less than the right side",
+	"<=":      "True if the left side is less than or equal to the right side",
+	">":       "True if the left side is greater than the right side",
+	">=":      "True if the left side is greater than or equal to the right side",
+	"//":      "Alternative: the left side's outputs unless they are all false or null, otherwise the right side",
+	"?":       "Suppress errors from the preceding expression",
+	"..":      "Recurse: every value in the input, including the input itself",
+	"=":       "Assign the value of the right side to the paths on the left",
+	"|=":      "Update the paths on the left by running the right side on their values",
+	"+=":      "Add the right side to the values at the paths on the left",
+	"-=":      "Subtract the right side from the values at the paths on the left",
+	"*=":      "Multiply the values at the paths on the left by the right side",
+	"/=":      "Divide the values at the paths on the left by the right side",
+	"%=":      "Replace the values at the paths on the left with the remainder of dividing them by the right side",
+	"//=":     "Assign the right side to the paths on the left whose values are false or null",
+	"?//":     "Destructuring alternative: try each pattern in turn",
+	"and":     "Logical and",
+	"or":      "Logical or",
+	"as":      "Bind the outputs of the expression to a variable",
+	"def":     "Define a function",
+	"if":      "Conditional: if cond then a elif cond then b else c end",
+	"then":    "Part of an if expression",
+	"elif":    "Part of an if expression",
+	"else":    "Part of an if expression",
+	"end":     "Ends an if expression",
+	"try":     "Run an expression, catching any errors it raises",
+	"catch":   "Handle an error raised in a try expression",
+	"reduce":  "Combine all outputs of an expression into a single value: reduce EXP as $x (INIT; UPDATE)",
+	"foreach": "Like reduce, but produce each intermediate state: foreach EXP as $x (INIT; UPDATE; EXTRACT)",
+	"label":   "Define a label that can be broken out of with break",
+	"break":   "Break out of the label with the given name",
+	"import":  "Import a module",
+	"include": "Include a module's definitions",
+}
+
+// Short descriptions of common jq builtins
+var builtinDocs = map[string]string{
+	"add":            "Add all of the elements of the input array together",
+	"all":            "True if all elements of the input array (or outputs of a generator) are true",
+	"any":            "True if any element of the input array (or output of a generator) is true",
+	"arrays":         "Select only inputs that are arrays",
+	"ascii_downcase": "Convert a string to lowercase",
+	"ascii_upcase":   "Convert a string to uppercase",
+	"booleans":       "Select only inputs that are booleans",
+	"contains":       "True if the argument is completely contained in the input",
+	"debug":          "Print the input to stderr as a debug message and pass it through",
+	"del":            "Delete the values at the given paths",
+	"empty":          "Produce no output",
+	"endswith":       "True if the input string ends with the argument",
+	"env":            "An object containing the environment variables",
+	"error":          "Raise an error with the input or the given message",
+	"explode":        "Convert a string into an array of codepoints",
+	"first":          "The first element of an array, or the first output of a generator",
+	"flatten":        "Flatten nested arrays, optionally to a given depth",
+	"floor":          "Round a number down",
+	"from_entries":   "Convert an array of {key, value} objects into an object",
+	"fromjson":       "Parse a JSON string",
+	"getpath":        "The value at the given path",
+	"group_by":       "Group the elements of an array by the value of an expression",
+	"gsub":           "Replace all matches of a regular expression",
+	"has":            "True if the input object has the given key or the input array has the given index",
+	"implode":        "Convert an array of codepoints into a string",
+	"in":             "True if the input key is in the given object or array",
+	"index":          "The index of the first occurrence of the argument",
+	"indices":        "The indices of all occurrences of the argument",
+	"input":          "Read the next input",
+	"inputs":         "Read all remaining inputs",
+	"inside":         "True if the input is completely contained in the argument",
+	"isempty":        "True if the expression produces no output",
+	"iterables":      "Select only inputs that are arrays or objects",
+	"join":           "Join an array of strings with a separator",
+	"keys":           "The keys of an object (sorted) or the indices of an array",
+	"keys_unsorted":  "The keys of an object in their original order",
+	"last":           "The last element of an array, or the last output of a generator",
+	"length":         "The length of a string, array, or object, or the absolute value of a number",
+	"limit":          "At most the first n outputs of an expression",
+	"ltrimstr":       "Remove the given prefix from a string, if present",
+	"map":            "Apply an expression to each element of an array",
+	"map_values":     "Apply an expression to each value of an object or array",
+	"match":          "Match a regular expression, producing match objects",
+	"max":            "The largest element of an array",
+	"max_by":         "The element of an array with the largest value of an expression",
+	"min":            "The smallest element of an array",
+	"min_by":         "The element of an array with the smallest value of an expression",
+	"not":            "Logical negation of the input",
+	"nulls":          "Select only inputs that are null",
+	"numbers":        "Select only inputs that are numbers",
+	"objects":        "Select only inputs that are objects",
+	"path":           "The paths produced by an expression, as arrays",
+	"paths":          "All paths in the input, optionally only those whose values match an expression",
+	"range":          "Produce a range of numbers",
+	"recurse":        "Recursively descend into the input",
+	"reverse":        "Reverse an array or string",
+	"rtrimstr":       "Remove the given suffix from a string, if present",
+	"scalars":        "Select only inputs that are not arrays or objects",
+	"scan":           "Produce each match of a regular expression",
+	"select":         "Produce the input unchanged if the condition is true, otherwise nothing",
+	"setpath":        "Set the value at the given path",
+	"sort":           "Sort an array",
+	"sort_by":        "Sort an array by the value of an expression",
+	"split":          "Split a string by a separator",
+	"splits":         "Split a string by a regular expression, producing each part",
+	"startswith":     "True if the input string starts with the argument",
+	"strings":        "Select only inputs that are strings",
+	"sub":            "Replace the first match of a regular expression",
+	"test":           "True if the input string matches a regular expression",
+	"to_entries":     "Convert an object into an array of {key, value} objects",
+	"tojson":         "Serialize the input as a JSON string",
+	"tonumber":       "Parse a string as a number",
+	"tostring":       "Convert the input to a string",
+	"transpose":      "Transpose an array of arrays",
+	"type":           "The type of the input as a string",
+	"unique":         "Sort an array and remove duplicates",
+	"unique_by":      "Remove elements of an array with duplicate values of an expression",
+	"until":          "Repeatedly apply an update until a condition is true",
+	"values":         "Select only inputs that are not null",
+	"walk":           "Recursively apply an expression to every value",
+	"while":          "Repeatedly apply an update while a condition is true",
+	"with_entries":   "Transform the {key, value} entries of an object",
+	"@base64":        "Encode a string as base64",
+	"@base64d":       "Decode a base64 string",
+	"@csv":           "Format an array as a CSV row",
+	"@html":          "Escape a string for HTML",
+	"@json":          "Serialize the input as JSON",
+	"@sh":            "Quote a string for use in a shell command",
+	"@text":          "Convert the input to a string",
+	"@tsv":           "Format an array as a TSV row",
+	"@uri":           "Percent-encode a string for use in a URI",
+}
+
+// Look up the description of a token
+func describeToken(t token) (string, bool) {
+	switch t.kind {
+	case tokenOperator:
+		doc, ok := operatorDocs[t.text]
+		return doc, ok
+	case tokenIdent:
+		if doc, ok := operatorDocs[t.text]; ok {
+			return doc, true
+		}
+
+		doc, ok := builtinDocs[t.text]
+		return doc, ok
+	}
+
+	return "", false
+}
+
+// Produce a description of each distinct builtin, keyword, and operator used
+// in the filter, in the order in which they first appear
+func explainFilter(filter string) string {
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	for _, t := range tokenizeFilter(filter) {
+		if seen[t.text] {
+			continue
+		}
+
+		if doc, ok := describeToken(t); ok {
+			seen[t.text] = true
+			fmt.Fprintf(&sb, "%-16s %s\n", t.text, doc)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "No known builtins or operators found in the filter.\n"
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenTexts(tokens []token) []string {
+	var texts []string
+	for _, t := range tokens {
+		texts = append(texts, t.text)
+	}
+
+	return texts
+}
+
+func TestTokenizeFilter(t *testing.T) {
+	tokens := tokenizeFilter(`.items[] | select(.name == "a \(.b) c") # comment
+| $x // @csv`)
+	assert.Equal(t, []string{
+		".items", "[", "]", "|", "select", "(", ".name", "==", `"a \(.b) c"`, ")",
+		"|", "$x", "//", "@csv",
+	}, tokenTexts(tokens))
+
+	assert.Equal(t, tokenField, tokens[0].kind)
+	assert.Equal(t, tokenIdent, tokens[4].kind)
+	assert.Equal(t, tokenString, tokens[8].kind)
+	assert.Equal(t, tokenVariable, tokens[11].kind)
+	assert.Equal(t, 11, tokens[4].start)
+	assert.Equal(t, 17, tokens[4].end)
+}
+
+func TestTokenizeFilterOperators(t *testing.T) {
+	assert.Equal(t, []string{".", "..", "|=", ".a", "//=", "1"}, tokenTexts(tokenizeFilter(". .. |= .a //= 1")))
+}
+
+func TestExplainFilter(t *testing.T) {
+	explanation := explainFilter(".[] | select(.a) | select(.b)")
+	assert.Contains(t, explanation, "select")
+	assert.Contains(t, explanation, "Pipe")
+	assert.Equal(t, 2, len(splitLines(explanation)))
+
+	assert.Contains(t, explainFilter(".foo"), "No known builtins")
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Shorten the JSON strings in jq output that are longer than max characters
+// to their first max characters followed by an ellipsis, except on the lines
+// whose index is in unfolded. Object keys are never shortened. An escape
+// sequence such as \n counts as a single character. The color sequences
+// written by jq are kept.
+func foldStrings(out []byte, max int, unfolded map[int]bool) []byte {
+	var buf bytes.Buffer
+	for i, line := range bytes.SplitAfter(out, []byte{'\n'}) {
+		if unfolded[i] {
+			buf.Write(line)
+		} else {
+			foldLine(&buf, line, max)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func foldLine(buf *bytes.Buffer, line []byte, max int) {
+	for len(line) > 0 {
+		if line[0] == '\x1b' {
+			if loc := ansiEscapePattern.FindIndex(line); loc != nil && loc[0] == 0 {
+				buf.Write(line[:loc[1]])
+				line = line[loc[1]:]
+				continue
+			}
+		}
+
+		if line[0] != '"' {
+			buf.WriteByte(line[0])
+			line = line[1:]
+			continue
+		}
+
+		end := stringEnd(line)
+		if end < 0 {
+			buf.Write(line)
+			return
+		}
+
+		cut := foldPoint(line[1:end], max)
+		if cut < 0 || isKey(line[end+1:]) {
+			buf.Write(line[:end+1])
+		} else {
+			buf.Write(line[:cut+1])
+			buf.WriteString("…\"")
+		}
+
+		line = line[end+1:]
+	}
+}
+
+// Return the index of the quote closing the string that begins the line, or
+// -1 if the string is not closed on the line
+func stringEnd(line []byte) int {
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Return the length in bytes of the first max characters of the contents of
+// a string, or -1 if the string is not longer than max characters
+func foldPoint(s []byte, max int) int {
+	n := 0
+	for i := 0; i < len(s); n++ {
+		if n == max {
+			return i
+		}
+
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == 'u':
+			i += 6
+		case s[i] == '\\':
+			i += 2
+		default:
+			_, size := utf8.DecodeRune(s[i:])
+			i += size
+		}
+	}
+
+	return -1
+}
+
+// Report whether the text following a string begins with a colon, ignoring
+// color sequences and spaces, which makes the string an object key
+func isKey(rest []byte) bool {
+	for len(rest) > 0 {
+		if loc := ansiEscapePattern.FindIndex(rest); loc != nil && loc[0] == 0 {
+			rest = rest[loc[1]:]
+			continue
+		}
+
+		if rest[0] != ' ' {
+			return rest[0] == ':'
+		}
+
+		rest = rest[1:]
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldStrings(t *testing.T) {
+	fold := func(out string, unfolded map[int]bool) string {
+		return string(foldStrings([]byte(out), 5, unfolded))
+	}
+
+	assert.Equal(t, `"abcde"`, fold(`"abcde"`, nil))
+	assert.Equal(t, `"abcde…"`, fold(`"abcdef"`, nil))
+	assert.Equal(t, `["héllo…", 1]`, fold(`["héllowörld", 1]`, nil))
+	assert.Equal(t, `"a\n\"\u0001b…"`, fold(`"a\n\"\u0001bcdef"`, nil))
+	assert.Equal(t, `"a\"bcd…"`, fold(`"a\"bcdef"`, nil))
+
+	// Keys are kept, with or without colors
+	assert.Equal(t, `  "longkey": "value…",`, fold(`  "longkey": "values1",`, nil))
+	colored := "\x1b[34;1m\"longkey\"\x1b[0m\x1b[1;39m: \x1b[0m\x1b[0;32m\"values1\"\x1b[0m"
+	assert.Equal(t, "\x1b[34;1m\"longkey\"\x1b[0m\x1b[1;39m: \x1b[0m\x1b[0;32m\"value…\"\x1b[0m", fold(colored, nil))
+
+	// Unfolded lines are shown in full
+	assert.Equal(t, "\"abcdef\"\n\"ghijk…\"\n", fold("\"abcdef\"\n\"ghijklm\"\n", map[int]bool{0: true}))
+
+	// Text that is not a closed string is kept
+	assert.Equal(t, `"abcdefgh`, fold(`"abcdefgh`, nil))
+	assert.Empty(t, foldStrings(nil, 5, nil))
+}
@@ -3,21 +3,26 @@ module git.sr.ht/~gpanders/ijq
 go 1.18
 
 require (
-	github.com/gdamore/tcell/v2 v2.7.0
+	github.com/alecthomas/chroma/v2 v2.3.0
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/gdamore/tcell/v2 v2.7.1
 	github.com/kyoh86/xdg v1.2.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/rivo/tview v0.0.0-20231206124440-5f078138442e
-	github.com/stretchr/testify v1.7.0
-	golang.org/x/term v0.15.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.2.0
+	github.com/stretchr/testify v1.8.0
+	github.com/titanous/json5 v1.0.0
+	golang.org/x/term v0.17.0
+	golang.org/x/text v0.14.0
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
-	golang.org/x/sys v0.15.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
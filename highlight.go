@@ -0,0 +1,118 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// The style raw output is highlighted with. It only uses the 16 terminal
+// colors and leaves plain text uncolored, like the colors written by jq.
+var highlightStyle = chroma.MustNewStyle("ijq", chroma.StyleEntries{
+	chroma.Comment:         "#555555",
+	chroma.Keyword:         "#007f7f",
+	chroma.NameAttribute:   "#00007f",
+	chroma.NameTag:         "#00007f",
+	chroma.NameBuiltin:     "#007f7f",
+	chroma.LiteralString:   "#007f00",
+	chroma.LiteralNumber:   "#7f007f",
+	chroma.LiteralDate:     "#7f007f",
+	chroma.Punctuation:     "#555555",
+	chroma.Operator:        "#555555",
+	chroma.GenericError:    "#7f0000",
+	chroma.GenericEmph:     "#7f7fe0",
+	chroma.GenericDeleted:  "#7f0000",
+	chroma.GenericInserted: "#007f00",
+	chroma.Error:           "#7f0000",
+})
+
+// Lexers for the kinds of raw output that chroma has no lexer for
+var (
+	csvLexer = lexers.Register(chroma.MustNewLexer(&chroma.Config{
+		Name:      "CSV",
+		Aliases:   []string{"csv", "tsv"},
+		Filenames: []string{"*.csv", "*.tsv"},
+	}, func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{Pattern: `"(?:[^"]|"")*"`, Type: chroma.LiteralString},
+				{Pattern: `(?<=^|[,\t])-?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?(?=[,\t\n]|$)`, Type: chroma.LiteralNumber},
+				{Pattern: `[,\t]`, Type: chroma.Punctuation},
+				{Pattern: `[^",\t\n]+|\n`, Type: chroma.Text},
+			},
+		}
+	}))
+
+	logLexer = lexers.Register(chroma.MustNewLexer(&chroma.Config{
+		Name:      "Log",
+		Aliases:   []string{"log"},
+		Filenames: []string{"*.log"},
+	}, func() chroma.Rules {
+		return chroma.Rules{
+			"root": {
+				{Pattern: `\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)?`, Type: chroma.LiteralDate},
+				{Pattern: `\d{2}:\d{2}:\d{2}(?:[.,]\d+)?`, Type: chroma.LiteralDate},
+				{Pattern: `\b(?i:fatal|panic|crit(?:ical)?|err(?:or)?)\b`, Type: chroma.GenericError},
+				{Pattern: `\b(?i:warn(?:ing)?)\b`, Type: chroma.GenericEmph},
+				{Pattern: `\b(?i:info|notice)\b`, Type: chroma.NameBuiltin},
+				{Pattern: `\b(?i:debug|trace)\b`, Type: chroma.Comment},
+				{Pattern: `"(?:[^"\\\n]|\\.)*"`, Type: chroma.LiteralString},
+				{Pattern: `\w+(?==)`, Type: chroma.NameAttribute},
+				{Pattern: `\b\d+(?:\.\d+)?\b`, Type: chroma.LiteralNumber},
+				{Pattern: `[^\d"\w]+|\w+`, Type: chroma.Text},
+			},
+		}
+	}))
+)
+
+// Return the lexer with the given name, alias, or file extension, or an
+// error if there is none
+func findLexer(name string) (chroma.Lexer, error) {
+	lexer := lexers.Get(name)
+	if lexer == nil {
+		return nil, fmt.Errorf("unknown lexer %q", name)
+	}
+
+	return chroma.Coalesce(lexer), nil
+}
+
+// Color raw output with the named lexer. The result contains ANSI color
+// sequences, which the output pane converts to its own colors.
+func highlight(out []byte, name string) ([]byte, error) {
+	lexer, err := findLexer(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := lexer.Tokenise(nil, string(out))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := formatters.TTY16.Format(&buf, highlightStyle, tokens); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	out, err := highlight([]byte("1,\"a,b\",x\n-2.5,y\n"), "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[35m1\x1b[0m\x1b[90m,\x1b[0m\x1b[32m\"a,b\"\x1b[0m\x1b[90m,\x1b[0mx\n\x1b[35m-2.5\x1b[0m\x1b[90m,\x1b[0my\n", string(out))
+
+	out, err = highlight([]byte("2024-01-02T03:04:05Z WARN retry n=2\n"), "log")
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[35m2024-01-02T03:04:05Z\x1b[0m \x1b[33mWARN\x1b[0m retry \x1b[34mn\x1b[0m=\x1b[35m2\x1b[0m\n", string(out))
+
+	// Text that is not colored by the lexer is left as is
+	out, err = highlight([]byte("a1 b2\n"), "log")
+	assert.Nil(t, err)
+	assert.Equal(t, "a1 b2\n", string(out))
+
+	_, err = highlight([]byte("x"), "nope")
+	assert.EqualError(t, err, `unknown lexer "nope"`)
+}
+
+func TestHighlightOutput(t *testing.T) {
+	doc := Document{input: "1,x\n", options: Options{command: "./testdata/cat", rawOutput: true, highlight: "csv"}}
+
+	// The final output is only highlighted when requested and colored
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "1,x\n", buf.String())
+
+	doc.options.highlightOutput = true
+	doc.options.monochrome = true
+	buf.Reset()
+	_, err = doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "1,x\n", buf.String())
+
+	doc.options.monochrome = false
+	buf.Reset()
+	_, err = doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[35m1\x1b[0m\x1b[90m,\x1b[0mx\n", buf.String())
+}
@@ -21,17 +21,35 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// Orderings for history items
+const (
+	HistoryOrderOldest = "oldest"
+	HistoryOrderRecent = "recent"
+	HistoryOrderAlpha  = "alpha"
+)
+
 type history struct {
 	path  string
 	Items []string
+
+	// Notes saying what history items are for, by item. The notes are
+	// kept in a JSON file next to the history file, so that the history
+	// file stays a plain list of filters.
+	Notes map[string]string
+
+	// The history file is read but never written, so that the filters of
+	// a session are not kept
+	readOnly bool
 }
 
 func (h *history) Init(path string) error {
@@ -59,9 +77,91 @@ func (h *history) Init(path string) error {
 		)
 	}
 
+	return h.readNotes()
+}
+
+// The file holding the notes of the history items
+func (h *history) notesPath() string {
+	return h.path + ".notes"
+}
+
+func (h *history) readNotes() error {
+	contents, err := ioutil.ReadFile(h.notesPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("error retrieving history notes: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &h.Notes); err != nil {
+		return fmt.Errorf("error retrieving history notes %s: %w", h.notesPath(), err)
+	}
+
+	return nil
+}
+
+// Write the notes to a temporary file that then replaces the notes file, so
+// that the notes are not lost if writing fails
+func (h *history) writeNotes() error {
+	if h.path == "" || h.readOnly {
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(h.Notes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), os.ModePerm); err != nil {
+		return fmt.Errorf("error writing history notes: %w", err)
+	}
+
+	tmp := h.notesPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(contents, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing history notes: %w", err)
+	}
+
+	if err := os.Rename(tmp, h.notesPath()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing history notes: %w", err)
+	}
+
 	return nil
 }
 
+// Return the note of a history item, if it has one
+func (h *history) Note(expression string) string {
+	return h.Notes[strings.TrimSpace(expression)]
+}
+
+// Add the expression to the history with a note saying what it is for. An
+// empty note removes the note of the expression.
+func (h *history) AddNote(expression, note string) error {
+	if err := h.Add(expression); err != nil {
+		return err
+	}
+
+	expression = strings.TrimSpace(expression)
+	note = strings.TrimSpace(note)
+	if expression == "" || h.path == "" || note == h.Notes[expression] {
+		return nil
+	}
+
+	if note == "" {
+		delete(h.Notes, expression)
+	} else {
+		if h.Notes == nil {
+			h.Notes = map[string]string{}
+		}
+
+		h.Notes[expression] = note
+	}
+
+	return h.writeNotes()
+}
+
 func (h *history) Add(expression string) error {
 	expression = strings.TrimSpace(expression)
 	if expression == "" {
@@ -79,6 +179,9 @@ func (h *history) Add(expression string) error {
 	}
 
 	h.Items = append(h.Items, expression)
+	if h.readOnly {
+		return nil
+	}
 
 	file, err := h.openFile()
 	if err != nil {
@@ -94,6 +197,107 @@ func (h *history) Add(expression string) error {
 	return nil
 }
 
+// Return the history items in the given order. Items are stored oldest
+// first.
+func (h *history) Ordered(order string) []string {
+	items := append([]string(nil), h.Items...)
+	switch order {
+	case HistoryOrderRecent:
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	case HistoryOrderAlpha:
+		sort.Strings(items)
+	}
+
+	return items
+}
+
+// Return the most recent limit history items in the given order, or all
+// items if limit is not positive
+func (h *history) Suggestions(order string, limit int) []string {
+	recent := history{Items: h.Items}
+	if limit > 0 && len(h.Items) > limit {
+		recent.Items = h.Items[len(h.Items)-limit:]
+	}
+
+	return recent.Ordered(order)
+}
+
+// Remove the given expressions from the history. The history file is
+// written to a temporary file that then replaces it, so that the history is
+// not lost if writing fails.
+func (h *history) Remove(expressions []string) error {
+	var kept []string
+	for _, item := range h.Items {
+		if !contains(expressions, item) {
+			kept = append(kept, item)
+		}
+	}
+
+	if len(kept) == len(h.Items) {
+		return nil
+	}
+
+	if h.path != "" && !h.readOnly {
+		var buf bytes.Buffer
+		for _, item := range kept {
+			fmt.Fprintln(&buf, item)
+		}
+
+		tmp := h.path + ".tmp"
+		if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("error writing history: %w", err)
+		}
+
+		if err := os.Rename(tmp, h.path); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("error writing history: %w", err)
+		}
+	}
+
+	h.Items = kept
+
+	// The notes of removed items are removed with them
+	noted := false
+	for _, expression := range expressions {
+		if _, ok := h.Notes[expression]; ok {
+			delete(h.Notes, expression)
+			noted = true
+		}
+	}
+
+	if noted {
+		return h.writeNotes()
+	}
+
+	return nil
+}
+
+// Append the expressions to a file of filters, one per line like the
+// history file, skipping those that are in the file already. Return the
+// number of expressions written.
+func exportFilters(path string, expressions []string) (int, error) {
+	var filters history
+	if err := filters.Init(path); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, expression := range expressions {
+		count := len(filters.Items)
+		if err := filters.Add(expression); err != nil {
+			return n, err
+		}
+
+		if len(filters.Items) > count {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
 func (h *history) openFile() (*os.File, error) {
 	err := os.MkdirAll(filepath.Dir(h.path), os.ModePerm)
 	if err != nil {
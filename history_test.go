@@ -181,3 +181,130 @@ func TestHistory(t *testing.T) {
 
 	assert.NoError(t, os.Remove(histFile))
 }
+
+func TestHistoryOrdered(t *testing.T) {
+	h := history{Items: []string{"b", "c", "a"}}
+
+	assert.Equal(t, []string{"b", "c", "a"}, h.Ordered(HistoryOrderOldest))
+	assert.Equal(t, []string{"a", "c", "b"}, h.Ordered(HistoryOrderRecent))
+	assert.Equal(t, []string{"a", "b", "c"}, h.Ordered(HistoryOrderAlpha))
+
+	// Ordering must not modify the stored items
+	assert.Equal(t, []string{"b", "c", "a"}, h.Items)
+}
+
+func TestHistorySuggestions(t *testing.T) {
+	h := history{Items: []string{"b", "c", "a"}}
+
+	assert.Equal(t, []string{"c", "a"}, h.Suggestions(HistoryOrderOldest, 2))
+	assert.Equal(t, []string{"a", "c"}, h.Suggestions(HistoryOrderRecent, 2))
+	assert.Equal(t, []string{"a", "c"}, h.Suggestions(HistoryOrderAlpha, 2))
+	assert.Equal(t, []string{"b", "c", "a"}, h.Suggestions(HistoryOrderOldest, 0))
+	assert.Equal(t, []string{"b", "c", "a"}, h.Suggestions(HistoryOrderOldest, 5))
+	assert.Equal(t, []string{"b", "c", "a"}, h.Items)
+}
+
+func TestHistoryRemove(t *testing.T) {
+	histFile := makeHistoryFilename()
+	assert.NoError(t, ioutil.WriteFile(histFile, []byte("one\ntwo\nthree\n"), 0644))
+
+	var h history
+	assert.NoError(t, h.Init(histFile))
+	assert.NoError(t, h.Remove([]string{"one", "three", "four"}))
+	assert.Equal(t, []string{"two"}, h.Items)
+
+	contents, err := ioutil.ReadFile(histFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "two\n", string(contents))
+	assert.NoFileExists(t, histFile+".tmp")
+
+	assert.NoError(t, os.Remove(histFile))
+
+	// Without a history file only the items are changed
+	h = history{Items: []string{"a", "b"}}
+	assert.NoError(t, h.Remove([]string{"a"}))
+	assert.Equal(t, []string{"b"}, h.Items)
+}
+
+func TestExportFilters(t *testing.T) {
+	file := path.Join(t.TempDir(), "filters.txt")
+
+	n, err := exportFilters(file, []string{".a", ".b"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Filters in the file already are skipped
+	n, err = exportFilters(file, []string{".b", ".c"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	contents, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, ".a\n.b\n.c\n", string(contents))
+}
+
+func TestHistoryReadOnly(t *testing.T) {
+	histFile := makeHistoryFilename()
+	assert.NoError(t, ioutil.WriteFile(histFile, []byte("one\ntwo\n"), 0644))
+	defer os.Remove(histFile)
+
+	h := history{readOnly: true}
+	assert.NoError(t, h.Init(histFile))
+	assert.Equal(t, []string{"one", "two"}, h.Items)
+
+	assert.NoError(t, h.Add("three"))
+	assert.NoError(t, h.Remove([]string{"one"}))
+	assert.Equal(t, []string{"two", "three"}, h.Items)
+
+	contents, err := ioutil.ReadFile(histFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", string(contents))
+}
+
+func TestHistoryNotes(t *testing.T) {
+	histFile := path.Join(t.TempDir(), "history")
+
+	var h history
+	assert.NoError(t, h.Init(histFile))
+	assert.NoError(t, h.AddNote(".items[] | .id ", " the ids of the items "))
+	assert.NoError(t, h.AddNote(".a", ""))
+	assert.Equal(t, []string{".items[] | .id", ".a"}, h.Items)
+	assert.Equal(t, "the ids of the items", h.Note(".items[] | .id"))
+	assert.Empty(t, h.Note(".a"))
+
+	// The history file is still a list of filters
+	contents, err := ioutil.ReadFile(histFile)
+	assert.NoError(t, err)
+	assert.Equal(t, ".items[] | .id\n.a\n", string(contents))
+
+	var loaded history
+	assert.NoError(t, loaded.Init(histFile))
+	assert.Equal(t, map[string]string{".items[] | .id": "the ids of the items"}, loaded.Notes)
+
+	// Adding a filter again changes its note, and an empty note removes
+	// it
+	assert.NoError(t, loaded.AddNote(".a", "just a"))
+	assert.Equal(t, "just a", loaded.Note(".a"))
+	assert.NoError(t, loaded.AddNote(".items[] | .id", ""))
+	assert.Empty(t, loaded.Note(".items[] | .id"))
+
+	// The notes of removed items are removed
+	assert.NoError(t, loaded.Remove([]string{".a"}))
+	var removed history
+	assert.NoError(t, removed.Init(histFile))
+	assert.Empty(t, removed.Notes)
+	assert.NoFileExists(t, histFile+".notes.tmp")
+
+	assert.NoError(t, ioutil.WriteFile(histFile+".notes", []byte("[]"), 0644))
+	assert.Error(t, (&history{}).Init(histFile))
+}
+
+func TestHistoryNotesReadOnly(t *testing.T) {
+	histFile := path.Join(t.TempDir(), "history")
+
+	h := history{readOnly: true}
+	assert.NoError(t, h.Init(histFile))
+	assert.NoError(t, h.AddNote(".a", "note"))
+	assert.Equal(t, "note", h.Note(".a"))
+	assert.NoFileExists(t, histFile+".notes")
+}
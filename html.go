@@ -0,0 +1,197 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSS colors for the standard and bright ANSI colors
+var ansiColors = [...]string{
+	"#000000", "#aa0000", "#00aa00", "#aa5500",
+	"#0000aa", "#aa00aa", "#00aaaa", "#aaaaaa",
+	"#555555", "#ff5555", "#55ff55", "#ffff55",
+	"#5555ff", "#ff55ff", "#55ffff", "#ffffff",
+}
+
+// Convert the parameters of an ANSI SGR escape sequence to a CSS style
+func sgrToStyle(params string) string {
+	var styles []string
+	for _, p := range strings.Split(params, ";") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case n == 1:
+			styles = append(styles, "font-weight:bold")
+		case n == 2:
+			styles = append(styles, "opacity:0.7")
+		case n == 4:
+			styles = append(styles, "text-decoration:underline")
+		case n >= 30 && n <= 37:
+			styles = append(styles, "color:"+ansiColors[n-30])
+		case n >= 90 && n <= 97:
+			styles = append(styles, "color:"+ansiColors[n-90+8])
+		case n >= 40 && n <= 47:
+			styles = append(styles, "background-color:"+ansiColors[n-40])
+		}
+	}
+
+	return strings.Join(styles, ";")
+}
+
+// A writer that converts ANSI colored text to HTML as it is written,
+// turning color escape sequences into styled spans. An escape sequence that
+// is split across writes is held back until the rest of it is written.
+type htmlWriter struct {
+	w       *bufio.Writer
+	open    bool
+	pending []byte
+}
+
+func newHTMLWriter(w io.Writer) *htmlWriter {
+	return &htmlWriter{w: bufio.NewWriter(w)}
+}
+
+func (h *htmlWriter) Write(p []byte) (int, error) {
+	text := append(h.pending, p...)
+	h.pending = nil
+
+	// Hold back a trailing escape sequence that is not complete yet
+	if i := bytes.LastIndexByte(text, '\x1b'); i >= 0 && isEscapePrefix(text[i:]) {
+		h.pending = append([]byte(nil), text[i:]...)
+		text = text[:i]
+	}
+
+	for len(text) > 0 {
+		loc := ansiEscapePattern.FindIndex(text)
+		if loc == nil {
+			h.w.WriteString(html.EscapeString(string(text)))
+			break
+		}
+
+		h.w.WriteString(html.EscapeString(string(text[:loc[0]])))
+
+		if h.open {
+			h.w.WriteString("</span>")
+			h.open = false
+		}
+
+		// Strip the leading "\x1b[" and trailing "m"
+		if style := sgrToStyle(string(text[loc[0]+2 : loc[1]-1])); style != "" {
+			h.w.WriteString(`<span style="` + style + `">`)
+			h.open = true
+		}
+
+		text = text[loc[1]:]
+	}
+
+	return len(p), nil
+}
+
+// Write the text that was held back, close the open span, and flush the
+// HTML to the underlying writer
+func (h *htmlWriter) Close() error {
+	h.w.WriteString(html.EscapeString(string(h.pending)))
+	h.pending = nil
+
+	if h.open {
+		h.w.WriteString("</span>")
+		h.open = false
+	}
+
+	return h.w.Flush()
+}
+
+// Report whether text is the beginning of an SGR escape sequence that is
+// missing its final "m"
+func isEscapePrefix(text []byte) bool {
+	if len(text) < 2 {
+		return true
+	}
+
+	if text[1] != '[' {
+		return false
+	}
+
+	for _, c := range text[2:] {
+		if c != ';' && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write ANSI colored text as HTML, converting color escape sequences to
+// styled spans
+func writeANSIAsHTML(w io.Writer, text []byte) error {
+	h := newHTMLWriter(w)
+	_, _ = h.Write(text)
+	return h.Close()
+}
+
+// Write the filtered output of the document as a standalone HTML page with
+// syntax highlighting. The output of jq is converted as it is produced.
+func (d *Document) WriteHTMLTo(w io.Writer) error {
+	c := Document{input: d.input, filter: d.filter, options: d.options, inputFile: d.inputFile}
+	c.options.forceColor = true
+	c.options.monochrome = false
+	c.options.stream = true
+
+	header := "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<title>" + html.EscapeString(d.filter) + "</title>\n</head>\n<body>\n<pre>"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	h := newHTMLWriter(w)
+	if _, err := c.WriteTo(h); err != nil {
+		return err
+	}
+
+	if err := h.Close(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "</pre>\n</body>\n</html>\n")
+	return err
+}
+
+// Export the filtered output of the document to an HTML file
+func (d *Document) ExportHTML(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := d.WriteHTMLTo(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSgrToStyle(t *testing.T) {
+	assert.Equal(t, "", sgrToStyle("0"))
+	assert.Equal(t, "", sgrToStyle(""))
+	assert.Equal(t, "font-weight:bold;color:#0000aa", sgrToStyle("1;34"))
+	assert.Equal(t, "color:#00aa00", sgrToStyle("0;32"))
+}
+
+func TestWriteANSIAsHTML(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeANSIAsHTML(&buf, []byte("\x1b[0;32m\"<a>\"\x1b[0m\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<span style=\"color:#00aa00\">&#34;&lt;a&gt;&#34;</span>\n", buf.String())
+}
+
+func TestHTMLWriterSplitEscape(t *testing.T) {
+	var buf bytes.Buffer
+	h := newHTMLWriter(&buf)
+	for _, chunk := range []string{"\x1b", "[0;3", "2m\"a", "\"\x1b[0", "m\n"} {
+		_, err := h.Write([]byte(chunk))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, h.Close())
+	assert.Equal(t, "<span style=\"color:#00aa00\">&#34;a&#34;</span>\n", buf.String())
+}
+
+func TestWriteHTMLTo(t *testing.T) {
+	doc := Document{input: `{"a":"<b>"}`, filter: ".a", options: Options{command: "jq"}}
+	var buf bytes.Buffer
+	assert.NoError(t, doc.WriteHTMLTo(&buf))
+	assert.Contains(t, buf.String(), "<title>.a</title>")
+	assert.Contains(t, buf.String(), "&#34;&lt;b&gt;&#34;</span>\n</pre>")
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// The number of spaces jq indents each level of pretty-printed output by
+const jqIndentWidth = 2
+
+// The indentation at the start of a line of jq output, possibly after color
+// sequences
+var indentPattern = regexp.MustCompile("^((?:\x1b\\[[0-9;]*m)*)( +)")
+
+// Check an indent string given with -indent-string, which must be made of
+// spaces and tabs
+func checkIndentString(indent string) error {
+	if indent == "" || strings.Trim(indent, " \t") != "" {
+		return errors.New("indent string must only contain spaces and tabs")
+	}
+
+	return nil
+}
+
+// Replace the indentation of pretty-printed jq output with the given string
+// for each level. Each line of pretty-printed JSON begins with its
+// indentation, since strings cannot contain raw newlines. This does not
+// apply to raw output, in which strings can begin lines with spaces.
+func reindent(out []byte, indent string) []byte {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(out, []byte{'\n'}) {
+		m := indentPattern.FindSubmatchIndex(line)
+		if m == nil {
+			buf.Write(line)
+			continue
+		}
+
+		spaces := m[5] - m[4]
+		buf.Write(line[:m[4]])
+		buf.WriteString(strings.Repeat(indent, spaces/jqIndentWidth))
+		buf.WriteString(strings.Repeat(" ", spaces%jqIndentWidth))
+		buf.Write(line[m[5]:])
+	}
+
+	return buf.Bytes()
+}
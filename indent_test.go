@@ -0,0 +1,43 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIndentString(t *testing.T) {
+	assert.NoError(t, checkIndentString("    "))
+	assert.NoError(t, checkIndentString("\t"))
+	assert.Error(t, checkIndentString(""))
+	assert.Error(t, checkIndentString(" x"))
+	assert.Error(t, checkIndentString("\n"))
+}
+
+func TestReindent(t *testing.T) {
+	out := "{\n  \"a\": [\n    1\n  ]\n}\n"
+	assert.Equal(t, "{\n\t\"a\": [\n\t\t1\n\t]\n}\n", string(reindent([]byte(out), "\t")))
+	assert.Equal(t, "{\n    \"a\": [\n        1\n    ]\n}\n", string(reindent([]byte(out), "    ")))
+
+	// Color sequences before the indentation are kept
+	colored := "\x1b[1;39m{\n  \x1b[0m\x1b[34;1m\"a\"\x1b[0m\n\x1b[1;39m  ]\n"
+	assert.Equal(t, "\x1b[1;39m{\n\t\x1b[0m\x1b[34;1m\"a\"\x1b[0m\n\x1b[1;39m\t]\n", string(reindent([]byte(colored), "\t")))
+	assert.Empty(t, reindent(nil, "\t"))
+}
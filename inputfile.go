@@ -0,0 +1,117 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sync"
+)
+
+// A temporary file holding a large input, which jq reads instead of
+// standard input. Writing a large input to jq's standard input on every run
+// of the filter is slow, so the file is written once and given to jq as its
+// input file until the input changes.
+type inputFile struct {
+	// Inputs smaller than this many bytes are written to standard input,
+	// and 0 always writes them to standard input
+	threshold int
+
+	mu    sync.Mutex
+	path  string
+	input string
+}
+
+func newInputFile(threshold int) *inputFile {
+	return &inputFile{threshold: threshold}
+}
+
+// Return the path of a file holding the input, writing the file if it does
+// not hold the input yet, or false if the input is small enough to be
+// written to standard input
+func (f *inputFile) Path(input string) (string, bool, error) {
+	if f == nil || f.threshold <= 0 || len(input) < f.threshold {
+		return "", false, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path != "" && f.input == input {
+		return f.path, true, nil
+	}
+
+	tmp, err := os.CreateTemp("", "ijq-input-*")
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, err := tmp.WriteString(input); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", false, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", false, err
+	}
+
+	if f.path != "" {
+		os.Remove(f.path)
+	}
+
+	f.path, f.input = tmp.Name(), input
+	return f.path, true, nil
+}
+
+// Remove the file, if it was written
+func (f *inputFile) Remove() {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path != "" {
+		os.Remove(f.path)
+	}
+
+	f.path, f.input = "", ""
+}
+
+// Remove the file and exit with the given code. The file would be left
+// behind otherwise, since os.Exit does not run deferred functions.
+func (f *inputFile) exit(code int) {
+	f.Remove()
+	os.Exit(code)
+}
+
+// Remove the file, then print v and exit like log.Fatalln
+func (f *inputFile) fatal(v ...interface{}) {
+	f.Remove()
+	log.Fatalln(v...)
+}
+
+// jq names the input file in its error messages, where the file stands for
+// standard input
+func hideInputFile(out []byte, path string) []byte {
+	return bytes.ReplaceAll(out, []byte(path), []byte("<stdin>"))
+}
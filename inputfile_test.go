@@ -0,0 +1,88 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputFilePath(t *testing.T) {
+	f := newInputFile(4)
+
+	// Small inputs are written to standard input
+	_, ok, err := f.Path("{}")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	path, ok, err := f.Path("[1, 2]")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "[1, 2]", string(contents))
+
+	// The file is reused until the input changes
+	same, _, _ := f.Path("[1, 2]")
+	assert.Equal(t, path, same)
+
+	changed, ok, err := f.Path("[3, 4]")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotEqual(t, path, changed)
+	assert.NoFileExists(t, path)
+	contents, _ = os.ReadFile(changed)
+	assert.Equal(t, "[3, 4]", string(contents))
+
+	f.Remove()
+	assert.NoFileExists(t, changed)
+
+	// A missing file and a threshold of 0 always use standard input
+	var missing *inputFile
+	_, ok, _ = missing.Path("[1, 2]")
+	assert.False(t, ok)
+	missing.Remove()
+
+	_, ok, _ = newInputFile(0).Path("[1, 2]")
+	assert.False(t, ok)
+}
+
+func TestDocumentWriteToInputFile(t *testing.T) {
+	f := newInputFile(1)
+	defer f.Remove()
+
+	doc := &Document{input: `{"a": 1}`, filter: ".a", options: Options{command: "jq", monochrome: true}, inputFile: f}
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n", buf.String())
+	assert.NotEmpty(t, f.path)
+
+	// The error names standard input rather than the file
+	doc.filter = ".a.b"
+	_, err = doc.WriteTo(&buf)
+	if assert.Error(t, err) {
+		stderr := string(err.(*exec.ExitError).Stderr)
+		assert.Contains(t, stderr, "<stdin>")
+		assert.NotContains(t, stderr, f.path)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/titanous/json5"
+)
+
+// A syntax error in JSON5 input, at a 1-based line and column
+type json5Error struct {
+	line   int
+	column int
+	msg    string
+}
+
+func (e *json5Error) Error() string {
+	return fmt.Sprintf("invalid JSON5 at line %d, column %d: %s", e.line, e.column, e.msg)
+}
+
+// jq writes infinite numbers as the largest double, and NaN as null
+const (
+	json5Infinity string = "1.7976931348623157e+308"
+	json5NaN      string = "null"
+)
+
+// The json5 package decodes objects into maps, which lose the order of their
+// members. The values are decoded in the order of the input, so each value
+// is numbered when it is decoded to put the members back in that order.
+var json5Decoded int64
+
+// A value decoded by the json5 package, in the form of a JSON value
+type json5Value struct {
+	value *jsonValue
+	n     int64
+}
+
+func (v *json5Value) UnmarshalJSON(data []byte) error {
+	v.n = atomic.AddInt64(&json5Decoded, 1)
+	switch data[0] {
+	case '{':
+		var members map[string]json5Value
+		if err := json5.Unmarshal(data, &members); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(members))
+		for key := range members {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			return members[keys[i]].n < members[keys[j]].n
+		})
+
+		v.value = &jsonValue{kind: jsonObject}
+		for _, key := range keys {
+			v.value.members = append(v.value.members, jsonMember{key: key, value: members[key].value})
+		}
+	case '[':
+		var items []json5Value
+		if err := json5.Unmarshal(data, &items); err != nil {
+			return err
+		}
+
+		v.value = &jsonValue{kind: jsonArray}
+		for _, item := range items {
+			v.value.items = append(v.value.items, item.value)
+		}
+	default:
+		dec := json5.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+
+		var scalar interface{}
+		if err := dec.Decode(&scalar); err != nil {
+			return err
+		}
+
+		v.value = json5Scalar(scalar)
+	}
+
+	return nil
+}
+
+// Convert a scalar decoded by the json5 package to a JSON value
+func json5Scalar(scalar interface{}) *jsonValue {
+	switch s := scalar.(type) {
+	case bool:
+		return &jsonValue{kind: jsonBool, scalar: fmt.Sprint(s)}
+	case string:
+		return &jsonValue{kind: jsonString, str: s}
+	case json5.Number:
+		// Infinity and NaN are the numbers that have no JSON form
+		if f, err := s.Float64(); err == nil {
+			switch {
+			case math.IsInf(f, 1):
+				return &jsonValue{kind: jsonNumber, scalar: json5Infinity}
+			case math.IsInf(f, -1):
+				return &jsonValue{kind: jsonNumber, scalar: "-" + json5Infinity}
+			case math.IsNaN(f):
+				return &jsonValue{kind: jsonNull, scalar: json5NaN}
+			}
+		}
+
+		return &jsonValue{kind: jsonNumber, scalar: json5Number(string(s))}
+	}
+
+	return &jsonValue{kind: jsonNull, scalar: "null"}
+}
+
+// Convert a JSON5 number to JSON. Hexadecimal numbers are converted to
+// decimal, and the leading or trailing decimal point and plus sign that JSON
+// does not allow are dropped.
+func json5Number(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, ok := new(big.Int).SetString(s[2:], 16)
+		if !ok {
+			return sign + s
+		}
+
+		if n.Sign() == 0 {
+			sign = ""
+		}
+
+		return sign + n.String()
+	}
+
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i != -1 {
+		mantissa, exponent = s[:i], s[i:]
+	}
+
+	if strings.HasPrefix(mantissa, ".") {
+		mantissa = "0" + mantissa
+	}
+
+	return sign + strings.TrimSuffix(mantissa, ".") + exponent
+}
+
+// Parse a stream of whitespace separated JSON5 values. Object members are
+// kept in their original order, and numbers are converted to their JSON
+// form.
+func parseJSON5(data []byte) ([]*jsonValue, error) {
+	dec := json5.NewDecoder(bytes.NewReader(data))
+
+	var values []*jsonValue
+	for {
+		var v json5Value
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return values, nil
+		}
+
+		if err != nil {
+			return nil, json5SyntaxError(data, err)
+		}
+
+		values = append(values, v.value)
+	}
+}
+
+// Give the error of the json5 package the line and column it occurred at
+func json5SyntaxError(data []byte, err error) error {
+	offset := len(data)
+	if syntaxErr, ok := err.(*json5.SyntaxError); ok {
+		offset = int(syntaxErr.Offset) - 1
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return &json5Error{line: line, column: column, msg: err.Error()}
+}
+
+// Convert JSON5 input to a stream of JSON values, as jq reads it
+func convertJSON5(data []byte) ([]byte, error) {
+	values, err := parseJSON5(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatJSONStream(values, "  "), nil
+}
+
+// Report whether the data is not valid JSON but is valid JSON5, to suggest
+// -json5
+func looksLikeJSON5(data []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return false
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	values, err := parseJSON5(data)
+	return err == nil && len(values) > 0
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertJSON5(t *testing.T) {
+	input := `// A comment
+{
+	unquoted: 'single "quoted"', $id_2: "line \
+break", /* a block comment */ "quoted": [1, 2,],
+	hex: 0xFF, neg: -0x10, half: .5, whole: 5., plus: +1, exp: 1.e3,
+	inf: Infinity, ninf: -Infinity, nan: NaN, none: null,
+	escapes: 'é😀\'',
+	b: {}, a: [],
+}
+2 'three'
+`
+	out, err := convertJSON5([]byte(input))
+	assert.Nil(t, err)
+
+	// The output is indented like jq, compact it to compare the values
+	values, err := parseJSONStream(out)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"unquoted":"single \"quoted\"","$id_2":"line break","quoted":[1,2],`+
+		`"hex":255,"neg":-16,"half":0.5,"whole":5,"plus":1,"exp":1e3,`+
+		`"inf":1.7976931348623157e+308,"ninf":-1.7976931348623157e+308,"nan":null,"none":null,`+
+		`"escapes":"é😀'","b":{},"a":[]}`+"\n2\n\"three\"\n", string(formatJSONStream(values, "")))
+
+	// Errors say where they are
+	_, err = convertJSON5([]byte("{a: 1,\n  b: [1 2]}"))
+	assert.EqualError(t, err, "invalid JSON5 at line 2, column 9: invalid character '2' after array element")
+}
+
+func TestLooksLikeJSON5(t *testing.T) {
+	assert.True(t, looksLikeJSON5([]byte("{a: 1, // one\n}")))
+	assert.False(t, looksLikeJSON5([]byte(`{"a": 1} [2]`)))
+	assert.False(t, looksLikeJSON5([]byte("not json")))
+	assert.False(t, looksLikeJSON5([]byte("")))
+}
+
+func TestDocumentReadJSON5(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json5")
+	assert.NoError(t, os.WriteFile(file, []byte("{b: 1, a: [2,],}"), 0644))
+
+	doc := &Document{options: Options{json5: true}}
+	assert.NoError(t, doc.ReadFiles([]string{file}))
+	assert.Equal(t, "{\n  \"b\": 1,\n  \"a\": [\n    2\n  ]\n}\n", doc.input)
+
+	_, err := doc.ReadFrom(strings.NewReader("#!ijq .a\n{a: 1}"))
+	assert.NoError(t, err)
+	assert.Equal(t, ".a", doc.headerFilter)
+	assert.Equal(t, "{\n  \"a\": 1\n}\n", doc.input)
+
+	assert.NoError(t, os.WriteFile(file, []byte("{a: }"), 0644))
+	err = doc.ReadFiles([]string{file})
+	assert.EqualError(t, err, file+": invalid JSON5 at line 1, column 5: invalid character '}' looking for beginning of value")
+
+	_, err = doc.ReadFrom(strings.NewReader("[1"))
+	assert.EqualError(t, err, "standard input: invalid JSON5 at line 1, column 3: unexpected EOF")
+}
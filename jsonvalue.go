@@ -0,0 +1,256 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type jsonKind int
+
+const (
+	jsonNull jsonKind = iota
+	jsonBool
+	jsonNumber
+	jsonString
+	jsonArray
+	jsonObject
+)
+
+type jsonMember struct {
+	key   string
+	value *jsonValue
+}
+
+// A parsed JSON value. Unlike the values produced by encoding/json, object
+// members are kept in their original order and numbers are kept in their
+// original textual form.
+type jsonValue struct {
+	kind    jsonKind
+	scalar  string // the JSON text of a null, bool, or number
+	str     string // the decoded value of a string
+	items   []*jsonValue
+	members []jsonMember
+}
+
+// Parse a stream of whitespace separated JSON values
+func parseJSONStream(data []byte) ([]*jsonValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var values []*jsonValue
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := parseJSONToken(dec, tok)
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+}
+
+// Return the type of the first value in a stream of JSON values, as named by
+// jq's type builtin, or "" if the stream is empty or does not begin with a
+// JSON value
+func firstValueType(data string) string {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return ""
+	}
+
+	switch tok.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	}
+
+	if tok == json.Delim('[') {
+		return "array"
+	}
+
+	return "object"
+}
+
+func parseJSONValue(dec *json.Decoder) (*jsonValue, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseJSONToken(dec, tok)
+}
+
+func parseJSONToken(dec *json.Decoder, tok json.Token) (*jsonValue, error) {
+	switch t := tok.(type) {
+	case nil:
+		return &jsonValue{kind: jsonNull, scalar: "null"}, nil
+	case bool:
+		return &jsonValue{kind: jsonBool, scalar: fmt.Sprint(t)}, nil
+	case json.Number:
+		return &jsonValue{kind: jsonNumber, scalar: t.String()}, nil
+	case string:
+		return &jsonValue{kind: jsonString, str: t}, nil
+	case json.Delim:
+		switch t {
+		case '[':
+			v := &jsonValue{kind: jsonArray}
+			for dec.More() {
+				item, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				v.items = append(v.items, item)
+			}
+
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+
+			return v, nil
+		case '{':
+			v := &jsonValue{kind: jsonObject}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := parseJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				v.members = append(v.members, jsonMember{keyTok.(string), value})
+			}
+
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// Encode a string as a JSON string literal without escaping HTML characters
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Write the value as JSON. If indent is empty the output is compact,
+// otherwise each nesting level is indented by the indent string.
+func (v *jsonValue) writeJSON(w *bytes.Buffer, indent string, level int) {
+	newline := func(level int) {
+		if indent != "" {
+			w.WriteByte('\n')
+			w.WriteString(strings.Repeat(indent, level))
+		}
+	}
+
+	switch v.kind {
+	case jsonString:
+		w.WriteString(quoteJSON(v.str))
+	case jsonArray:
+		if len(v.items) == 0 {
+			w.WriteString("[]")
+			return
+		}
+
+		w.WriteByte('[')
+		for i, item := range v.items {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			newline(level + 1)
+			item.writeJSON(w, indent, level+1)
+		}
+
+		newline(level)
+		w.WriteByte(']')
+	case jsonObject:
+		if len(v.members) == 0 {
+			w.WriteString("{}")
+			return
+		}
+
+		w.WriteByte('{')
+		for i, m := range v.members {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			newline(level + 1)
+			w.WriteString(quoteJSON(m.key))
+			w.WriteByte(':')
+			if indent != "" {
+				w.WriteByte(' ')
+			}
+
+			m.value.writeJSON(w, indent, level+1)
+		}
+
+		newline(level)
+		w.WriteByte('}')
+	default:
+		w.WriteString(v.scalar)
+	}
+}
+
+// Format a stream of values as JSON, one value per line (or per block when
+// indented)
+func formatJSONStream(values []*jsonValue, indent string) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		v.writeJSON(&buf, indent, 0)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
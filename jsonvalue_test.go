@@ -0,0 +1,52 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONStream(t *testing.T) {
+	values, err := parseJSONStream([]byte(`{"b":1,"a":[true,null,"x<y"]} 1.50`))
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+
+	assert.Equal(t, `{"b":1,"a":[true,null,"x<y"]}`+"\n1.50\n", string(formatJSONStream(values, "")))
+	assert.Equal(t, "{\n  \"b\": 1,\n  \"a\": [\n    true,\n    null,\n    \"x<y\"\n  ]\n}\n1.50\n", string(formatJSONStream(values, "  ")))
+
+	values, err = parseJSONStream([]byte(`{"a":[],"b":{}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n\t\"a\": [],\n\t\"b\": {}\n}\n", string(formatJSONStream(values, "\t")))
+
+	_, err = parseJSONStream([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestFirstValueType(t *testing.T) {
+	assert.Equal(t, "object", firstValueType(` {"a": [1]} [2]`))
+	assert.Equal(t, "array", firstValueType(`[{"a": 1}]`))
+	assert.Equal(t, "number", firstValueType("1.5\n\"x\""))
+	assert.Equal(t, "string", firstValueType(`"x"`))
+	assert.Equal(t, "boolean", firstValueType(`false`))
+	assert.Equal(t, "null", firstValueType(`null`))
+	assert.Equal(t, "", firstValueType(""))
+	assert.Equal(t, "", firstValueType("not json"))
+	assert.Equal(t, "", firstValueType("]"))
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "sync"
+
+// Computes the keys for completion in the background, running at most a
+// fixed number of computations, and so jq processes, at a time. Typing
+// quickly asks for the keys of the same prefix many times: a prefix whose
+// keys are already being computed, or waiting to be, is not computed again.
+type keyPool struct {
+	slots chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// At least one computation runs at a time
+func newKeyPool(jobs int) *keyPool {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	return &keyPool{slots: make(chan struct{}, jobs), pending: make(map[string]bool)}
+}
+
+// Run compute for the prefix in the background once fewer than the maximum
+// number of computations are running. Reports whether compute was started,
+// which it is not if the prefix is already pending.
+func (p *keyPool) Go(prefix string, compute func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending[prefix] {
+		return false
+	}
+
+	p.pending[prefix] = true
+	p.Run(func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.pending, prefix)
+			p.mu.Unlock()
+		}()
+
+		compute()
+	})
+
+	return true
+}
+
+// Run compute in the background once fewer than the maximum number of
+// computations are running, even if the keys it needs are pending
+func (p *keyPool) Run(compute func()) {
+	go func() {
+		p.slots <- struct{}{}
+		defer func() { <-p.slots }()
+		compute()
+	}()
+}
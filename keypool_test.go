@@ -0,0 +1,86 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPool(t *testing.T) {
+	pool := newKeyPool(2)
+
+	started := make(chan string, 4)
+	release := make(chan struct{})
+	compute := func(prefix string) func() {
+		return func() {
+			started <- prefix
+			<-release
+		}
+	}
+
+	for _, prefix := range []string{".a", ".b", ".c"} {
+		assert.True(t, pool.Go(prefix, compute(prefix)))
+	}
+
+	// A prefix that is pending is not computed again
+	assert.False(t, pool.Go(".a", compute(".a")))
+
+	// Only two computations run at a time
+	<-started
+	<-started
+	select {
+	case prefix := <-started:
+		t.Fatalf("%s started while two computations were running", prefix)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-started
+
+	// Once done, the keys of a prefix can be computed again
+	assert.Eventually(t, func() bool {
+		return pool.Go(".a", func() {})
+	}, time.Second, time.Millisecond)
+}
+
+func TestKeyPoolRun(t *testing.T) {
+	pool := newKeyPool(1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	assert.True(t, pool.Go(".a", func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	// Run does not wait for the prefix, but for a free slot
+	done := make(chan struct{})
+	pool.Run(func() { close(done) })
+	select {
+	case <-done:
+		t.Fatal("ran while another computation was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+}
@@ -20,6 +20,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -28,25 +29,103 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/kyoh86/xdg"
 	"github.com/rivo/tview"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"golang.org/x/term"
 )
 
 const DefaultCommand string = "jq"
 
+// Environment variable providing the default filter when none is given on the
+// command line
+const FilterEnvVar string = "IJQ_FILTER"
+
 // Special characters that, if present in a JSON key, need to be quoted in the
 // jq filter
 const SpecialChars string = ".-:$/"
 
 const Alphabet string = "abcdefghijklmnopqrstuvwxyz"
 
+// Character typed into the filter field to find the cursor. It is from the
+// Unicode private use area, so it does not appear in filters.
+const cursorMarker rune = '\ue000'
+
+// Number of rows used to show the complete filter text when the filter area
+// is expanded
+const expandedFilterHeight int = 8
+
+// Default size in bytes above which keys are not completed
+const DefaultCompleteMaxSize int = 64 << 20
+
+// Default number of jq processes computing keys for completion at a time
+const DefaultCompleteJobs int = 2
+
+// Default size in bytes from which the input is passed to jq in a
+// temporary file
+const DefaultInputFileSize int = 16 << 20
+
+// Default number of history entries suggested when the filter field is empty
+const DefaultHistorySuggest int = 20
+
+// Default maximum width of the filter field in columns, which keeps a filter
+// readable on wide screens
+const DefaultFilterWidth int = 120
+
+// How long messages are shown in the status line
+const statusDuration = 3 * time.Second
+
+// Default interval at which the output is rendered while the filter is
+// typed, about one frame of a 60 Hz display
+const DefaultRenderInterval = time.Second / 60
+
 var Version string
 
+// Ways of joining multiple input files
+const (
+	JoinModeStream = "stream"
+	JoinModeArray  = "array"
+)
+
+// Ways of formatting the input pane
+const (
+	InputViewOutput = "output"
+	InputViewPlain  = "plain"
+	InputViewSorted = "sorted"
+)
+
+// Strategies for merging the input values with -merge
+const (
+	MergeDeep    = "deep"
+	MergeShallow = "shallow"
+)
+
+// Ways of using standard input when input files are given
+const (
+	StdinIgnore = "ignore"
+	StdinBefore = "before"
+	StdinAfter  = "after"
+)
+
+// Actions of the Enter key in the filter field
+const (
+	EnterAccept  = "accept"
+	EnterNewline = "newline"
+	EnterRun     = "run"
+)
+
+// The input file name that stands for standard input
+const stdinName = "-"
+
 type Options struct {
 	compact     bool
 	command     string
@@ -57,7 +136,247 @@ type Options struct {
 	monochrome  bool
 	sortKeys    bool
 	historyFile string
+	configFile  string
 	forceColor  bool
+	maxResults  int
+	batch       bool
+
+	// Show the input exactly as it was read in the input pane instead of
+	// formatting it with jq
+	rawInputView bool
+
+	// The order in which history entries are suggested
+	historyOrder string
+
+	// The number of most recent history entries that are suggested
+	historySuggest int
+
+	// Prefix each value in the output pane with its index in the stream
+	numberValues bool
+
+	// Render the interface without colors. This does not affect jq's
+	// colors.
+	monoUI bool
+
+	// Render the panes without borders and titles
+	minimal bool
+
+	// A jq expression applied to the input before the filter
+	prefix string
+
+	// Files the result is also written to when the filter is accepted
+	outputs outputSpecs
+
+	// Variables bound to the contents of files
+	vars fileVars
+
+	// Log file recording each accepted filter
+	auditFile string
+
+	// How multiple input files are joined
+	joinMode string
+
+	// Whether standard input is read before or after the input files, or
+	// ignored, when input files are given
+	stdinMode string
+
+	// Keys are not completed for inputs larger than this many bytes
+	completeMaxSize int
+
+	// Write numbers in scientific notation in plain decimal notation
+	plainNumbers bool
+
+	// Show statistics about the input in the status line at startup
+	stats bool
+
+	// Read the input as raw text if it is not valid JSON
+	autoRaw bool
+
+	// Do not restore or save the layout of the last session
+	noRestoreLayout bool
+
+	// Whether the final output and output files end with a newline
+	trailingNewline trailingNewline
+
+	// Reject filters with side effects and run jq with an empty environment
+	noSideEffects bool
+
+	// Pass -- before the filter, which jq accepts since version 1.7.1
+	endOfOptions bool
+
+	// Show unprintable characters as escape codes in the output pane,
+	// which then also shows raw output
+	escapeView bool
+
+	// Write unprintable characters as escape codes in the final output
+	escapeOutput bool
+
+	// The output is rendered at most once per interval while the filter
+	// is typed
+	renderInterval time.Duration
+
+	// A shell command the input is run through when it is loaded
+	preCommand string
+
+	// File of filters that are each run to produce a report
+	filterQueue string
+
+	// A label for the session shown in the title of the filter field
+	title string
+
+	// Warn about duplicate object keys in the input at startup
+	warnDupKeys bool
+
+	// Show the top-level keys in the autocompletion list at startup
+	explore bool
+
+	// The file the filter was read from, which is loaded again when it
+	// changes
+	filterFile string
+
+	// A file with the output that the filter is expected to produce
+	expectFile string
+
+	// The encoding of the final output and output files, which is UTF-8
+	// if empty
+	encoding string
+
+	// A shell script reproducing the output is written to this file when
+	// the filter is accepted
+	scriptFile string
+
+	// The string each level of pretty-printed output is indented by,
+	// instead of jq's two spaces
+	indentString string
+
+	// Show the results in the output pane in pages of this many values
+	pageSize int
+
+	// The page of results shown in the output pane, starting from 0
+	page int
+
+	// Run jq with --unbuffered and show the output in the output pane as
+	// it is produced
+	unbuffered bool
+
+	// Write the record separator before each value with jq's --seq
+	seq bool
+
+	// Catch errors in the output pane, so that the results produced before
+	// an error are shown followed by the error message
+	softErrors bool
+
+	// Strings longer than this many characters are shortened in the
+	// output pane
+	foldStrings int
+
+	// Lines of the output pane on which long strings are shown in full
+	unfolded map[int]bool
+
+	// Show the trace of jq running the filter at startup
+	trace bool
+
+	// The output of each filter that runs successfully is also written to
+	// this file while the filter is edited
+	teeFile string
+
+	// A JSON Schema file the output is validated against
+	schemaFile string
+
+	// Whether the input pane is formatted like the output or with jq's
+	// defaults
+	inputView string
+
+	// Read the filter from the file named by the filter argument if it
+	// is not a filter
+	filterFileAuto bool
+
+	// How the input values are merged into a single object, if at all
+	mergeMode string
+
+	// The lexer that raw output is highlighted with in the output pane,
+	// which then shows raw output
+	highlight string
+
+	// Also highlight the final output when it is colored
+	highlightOutput bool
+
+	// Show the byte offset and length in the input of the value on the
+	// top line of the input pane
+	offsets bool
+
+	// Read the history file for suggestions but never write to it
+	noHistory bool
+
+	// The maximum width of the filter field and the error pane in columns,
+	// or 0 for no maximum
+	filterWidth int
+
+	// Only format the input with the output options and exit, without
+	// taking a filter
+	formatOnly bool
+
+	// Sort the keys of objects in natural order in a pass after jq,
+	// instead of jq's -S
+	sortKeysNatural bool
+
+	// Apply the filter to only the first this many elements of arrays in
+	// the output pane
+	sample int
+
+	// Read the input as JSON5 and convert it to JSON for jq
+	json5 bool
+
+	// What Enter does in the filter field, one of the Enter actions, or
+	// empty to use the action of the config
+	enterAction string
+
+	// Show the number of results in the title of the output pane
+	countResults bool
+
+	// A jq path by which the input array is sorted before the filter
+	sortBy string
+
+	// The maximum number of jq processes computing keys for completion
+	// at a time
+	completeJobs int
+
+	// What to do when the filter reorders the keys of the input, one of
+	// the preserve order modes, or empty to not check the order
+	preserveOrder string
+
+	// A file with a catalog of shared filters, or empty to use the
+	// catalog of the config
+	catalogFile string
+
+	// Objects and arrays nested deeper than this many levels are collapsed
+	// in the output pane
+	maxDepth int
+
+	// Clear the output pane when the filter fails instead of keeping the
+	// last good output
+	clearOnError bool
+
+	// How control characters are shown in the output pane, by character
+	controlChars map[rune]ControlChar
+
+	// Inputs of at least this many bytes are passed to jq in a temporary
+	// file instead of on standard input, or 0 to always use standard input
+	inputFileSize int
+
+	// Warn about builtins in the filter that the installed jq does not have
+	compatWarnings bool
+
+	// Read filters from a prompt and write their output instead of
+	// running the interactive interface
+	repl bool
+
+	// Write the output of jq to the writer as it is produced when no pass
+	// after jq needs the whole output
+	stream bool
+
+	// A jq path to the part of the input that the filter is applied to
+	selection string
 }
 
 // Convert the Options struct to a string slice of option flags that gets
@@ -97,378 +416,3697 @@ func (o *Options) ToSlice() []string {
 		opts = append(opts, "-S")
 	}
 
+	if o.unbuffered {
+		opts = append(opts, "--unbuffered")
+	}
+
+	if o.seq {
+		opts = append(opts, "--seq")
+	}
+
+	opts = append(opts, o.vars.args()...)
+
 	return opts
 }
 
+// Enable or disable colors depending on if the output is a tty, respecting
+// options set by the user
+func (o *Options) setColor(isTty bool) {
+	if !isTty && !o.forceColor {
+		o.monochrome = true
+	} else if isTty && !o.monochrome {
+		o.forceColor = true
+	}
+}
+
+// The options used to render the interactive panes. Output is always colored
+// and pretty-printed so that it is readable in the panes.
+func (o Options) preview() Options {
+	o.forceColor = true
+	o.monochrome = false
+	o.compact = false
+	o.rawOutput = false
+	return o
+}
+
+// The options used to render the input pane. With -input-view output the
+// input is formatted like the output. Otherwise only the options saying how
+// the input is read and how much of it is shown are kept, so that the input
+// is formatted with jq's defaults whatever the output options are.
+func (o Options) inputViewOptions() Options {
+	view := o
+	if o.inputView == InputViewPlain || o.inputView == InputViewSorted {
+		view = Options{
+			command:       o.command,
+			nullInput:     o.nullInput,
+			slurp:         o.slurp,
+			rawInput:      o.rawInput,
+			vars:          o.vars,
+			prefix:        o.prefix,
+			sortBy:        o.sortBy,
+			noSideEffects: o.noSideEffects,
+			endOfOptions:  o.endOfOptions,
+			maxResults:    o.maxResults,
+			sortKeys:      o.inputView == InputViewSorted,
+		}
+	}
+
+	// The input pane is pretty-printed even if the output is compact, and
+	// Alt-R shows the input as it was read instead. It shows the input
+	// itself, not the results.
+	view.compact = false
+	view.numberValues = false
+	view.foldStrings = 0
+	view.maxDepth = 0
+	view.selection = ""
+	view.sample = 0
+	return view
+}
+
+// Wrap a filter in parentheses so that it can be embedded in a larger
+// expression. The closing parenthesis is placed on its own line in case the
+// filter ends with a comment.
+func parenthesize(filter string) string {
+	return "(" + filter + "\n)"
+}
+
 type Document struct {
 	input   string
 	filter  string
 	options Options
-}
 
-func (d *Document) ReadFrom(r io.Reader) (n int64, err error) {
-	var buf bytes.Buffer
-	n, err = buf.ReadFrom(r)
-	d.input = buf.String()
-	return n, err
+	// The files the input was read from, if any
+	files []string
+
+	// Standard input, if it is one of the files
+	stdin []byte
+
+	// The filter given in the header of the input, if any
+	headerFilter string
+
+	// An error preparing the input, which is shown at startup
+	loadErr error
+
+	// The output that the filter is expected to produce, given with
+	// -expect
+	expected string
+
+	// The schema the output is validated against, given with -schema
+	schema *jsonschema.Schema
+
+	// The catalog of shared filters, given with -catalog
+	catalog *Catalog
+
+	// Called while the output pane shows partial output with -unbuffered,
+	// from the goroutine rendering the output
+	progress func()
+
+	// The temporary file passing a large input to jq, if any
+	inputFile *inputFile
+
+	// The key order of the input for -preserve-order, if it is cached
+	keyOrders *keyOrderCache
+
+	// The version of the installed jq, if it was detected for
+	// -compat-warnings
+	jqVersion *jqVersion
+
+	// Whether the input was edited in ijq, and the input before the edits
+	edited        bool
+	originalInput string
 }
 
-// Filter the document with the given jq filter and options
-func (d *Document) WriteTo(w io.Writer) (n int64, err error) {
-	opts := d.options
-	if _, ok := w.(*tview.TextView); ok {
-		// Writer is a TextView, so set options accordingly
-		opts.forceColor = true
-		opts.monochrome = false
-		opts.compact = false
-		opts.rawOutput = false
+// The prefix of the first line of a self-contained document holding both a
+// filter and the input, e.g.
+//
+//	#!ijq .items[] | .name
+//	{"items": [{"name": "a"}]}
+const documentHeader string = "#!ijq"
+
+// Split a document header from the data. If the data does not start with a
+// header it is returned unchanged.
+func splitHeader(data []byte) (filter string, rest []byte, ok bool) {
+	if !bytes.HasPrefix(data, []byte(documentHeader)) {
+		return "", data, false
 	}
 
-	args := append(opts.ToSlice(), d.filter)
-	cmd := exec.Command(d.options.command, args...)
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return 0, err
+	line, rest := data, []byte(nil)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line, rest = data[:i], data[i+1:]
 	}
 
-	go func() {
-		defer stdin.Close()
-		_, _ = io.WriteString(stdin, d.input)
-	}()
+	// The header must be followed by whitespace, so that e.g. "#!ijqx" is
+	// not mistaken for a header
+	filter = strings.TrimPrefix(string(line), documentHeader)
+	if filter != "" && filter[0] != ' ' && filter[0] != '\t' && filter[0] != '\r' {
+		return "", data, false
+	}
 
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		if exiterr, ok := err.(*exec.ExitError); ok {
-			// jq prints its error message to standard out, but we
-			// will deliver it in the Stderr field as this will
-			// most likely be an exec.ExitError.
-			exiterr.Stderr = out
+	return strings.TrimSpace(filter), rest, true
+}
+
+func (d *Document) ReadFrom(r io.Reader) (n int64, err error) {
+	var buf bytes.Buffer
+	n, err = buf.ReadFrom(r)
+	filter, rest, _ := splitHeader(buf.Bytes())
+	d.headerFilter = filter
+	d.input = string(rest)
+	if err == nil && d.options.json5 {
+		if rest, err = convertJSON5(rest); err != nil {
+			return n, fmt.Errorf("standard input: %w", err)
 		}
-		return 0, err
-	}
 
-	if tv, ok := w.(*tview.TextView); ok {
-		w = tview.ANSIWriter(tv)
-		tv.Clear()
+		d.input = string(rest)
 	}
 
-	m, err := w.Write(out)
-	n = int64(m)
 	return n, err
 }
 
-func parseArgs() (Options, string, []string) {
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "ijq - interactive jq\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: ijq [-cnsrRMSV] [-f file] [filter] [files ...]\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
+// The filter used for the interactive preview. This differs from the document
+// filter in that the number of results may be capped.
+func (d *Document) previewFilter() string {
+	filter := d.limitedFilter()
+	if d.options.pageSize > 0 {
+		start := d.options.page * d.options.pageSize
+		filter = fmt.Sprintf("[limit(%d; %s)] | .[%d:][]", start+d.options.pageSize, parenthesize(filter), start)
 	}
 
-	options := Options{}
-	flag.BoolVar(&options.compact, "c", false, "compact instead of pretty-printed output")
-	flag.BoolVar(&options.nullInput, "n", false, "use ```null` as the single input value")
-	flag.BoolVar(&options.slurp, "s", false, "read (slurp) all inputs into an array; apply filter to it")
-	flag.BoolVar(&options.rawOutput, "r", false, "output raw strings, not JSON texts")
-	flag.BoolVar(&options.rawInput, "R", false, "read raw strings, not JSON texts")
-	flag.BoolVar(&options.forceColor, "C", false, "force colorized JSON, even if writing to a pipe or file")
-	flag.BoolVar(&options.monochrome, "M", false, "monochrome (don't colorize JSON)")
-	flag.BoolVar(&options.sortKeys, "S", false, "sort keys of objects on output")
+	return filter
+}
 
-	flag.StringVar(
-		&options.command,
-		"jqbin",
-		DefaultCommand,
-		"name of or path to jq binary to use",
-	)
+// The filter applied to a sample of the input, if -sample is given. An array
+// is cut down to its first elements before the filter sees it, and other
+// values are left as they are.
+func (d *Document) sampledFilter() string {
+	if d.options.sample <= 0 {
+		return d.filter
+	}
 
-	flag.StringVar(
-		&options.historyFile,
-		"H",
-		filepath.Join(xdg.DataHome(), "ijq", "history"),
-		"set path to history file. Set to '' to disable history.",
-	)
+	return fmt.Sprintf(`(if type == "array" then .[:%d] else . end) | %s`, d.options.sample, parenthesize(d.filter))
+}
 
-	filterFile := flag.String("f", "", "read initial filter from `filename`")
-	version := flag.Bool("V", false, "print version and exit")
+// The filter with errors caught by -soft-errors and the number of results
+// capped by -max-results
+func (d *Document) limitedFilter() string {
+	filter := d.sampledFilter()
+	if d.options.softErrors {
+		filter = fmt.Sprintf(`try %s catch ("<error: \(.)>")`, parenthesize(filter))
+	}
 
-	flag.Parse()
+	if d.options.maxResults > 0 {
+		return fmt.Sprintf("limit(%d; %s)", d.options.maxResults, parenthesize(filter))
+	}
 
-	if *version {
-		fmt.Println("ijq " + Version)
-		os.Exit(0)
+	return filter
+}
+
+// Return the number of pages that the results are shown on, which is at
+// least 1
+func countPages(results, pageSize int) int {
+	if pageSize <= 0 || results <= 0 {
+		return 1
 	}
 
-	filter := "."
-	args := flag.Args()
+	return (results + pageSize - 1) / pageSize
+}
 
-	stdinIsTty := term.IsTerminal(int(os.Stdin.Fd()))
+// Return the number of results shown in the preview, across all pages.
+// Counting the results runs the filter on the whole input.
+func (d *Document) ResultCount() (int, bool) {
+	return d.count(d.limitedFilter())
+}
 
-	if *filterFile != "" {
-		contents, err := os.ReadFile(*filterFile)
-		if err != nil {
-			log.Fatalln(err)
-		}
+// Count the results of a filter
+func (d *Document) count(filter string) (int, bool) {
+	c := Document{
+		input:     d.input,
+		filter:    fmt.Sprintf("[%s] | length", parenthesize(filter)),
+		options:   d.options,
+		inputFile: d.inputFile,
+	}
+	c.options.maxResults = 0
+	c.options.compact = true
+	c.options.rawOutput = false
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.seq = false
 
-		filter = string(contents)
-	} else if len(args) > 1 || (len(args) > 0 && (!stdinIsTty || options.nullInput)) {
-		filter = args[0]
-		args = args[1:]
-	} else if len(args) == 0 && stdinIsTty && !options.nullInput {
-		flag.Usage()
-		os.Exit(1)
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return 0, false
 	}
 
-	return options, filter, args
+	count, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+	return count, err == nil
 }
 
-func scrollHalfPage(tv *tview.TextView, up bool) {
-	_, _, _, height := tv.GetInnerRect()
-	row, col := tv.GetScrollOffset()
-	if up {
-		tv.ScrollTo(row-height/2, col)
+// Return the paths of the keys of the values produced by prefix, e.g.
+// .items[].id for the prefix .items[]
+func (d *Document) Keys(prefix string) ([]string, error) {
+	var filt string
+	if prefix != "" {
+		filt = prefix + "| keys"
 	} else {
-		tv.ScrollTo(row+height/2, col)
+		filt = "keys"
 	}
-}
 
-func scrollHorizontally(tv *tview.TextView, end bool) {
-	if end {
-		text := tv.GetText(true)
-		_, _, width, height := tv.GetInnerRect()
-		row, _ := tv.GetScrollOffset()
-		maxLen := 0
-		for i, line := range strings.Split(text, "\n") {
-			if i < row {
-				continue
-			}
+	c := Document{
+		input:     d.input,
+		filter:    "[" + filt + "] | unique | first",
+		options:   d.options,
+		inputFile: d.inputFile,
+	}
 
-			if i > row+height {
-				break
-			}
+	// The keys are parsed, so jq must write plain JSON
+	c.options.maxResults = 0
+	c.options.compact = true
+	c.options.rawOutput = false
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.seq = false
 
-			if length := len(line); length > maxLen {
-				maxLen = length
-			}
-		}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return nil, err
+	}
 
-		if maxLen > width {
-			tv.ScrollTo(row, maxLen-width)
+	var keys []string
+	if err := json.Unmarshal(buf.Bytes(), &keys); err != nil {
+		return nil, err
+	}
+
+	entries := keys[:0]
+	for _, k := range keys {
+		if k == "" || strings.ContainsAny(k, SpecialChars) || !strings.Contains(Alphabet, string(k[0])) {
+			k = `"` + k + `"`
 		}
-	} else {
-		row, _ := tv.GetScrollOffset()
-		tv.ScrollTo(row, 0)
+		entries = append(entries, prefix+"."+k)
 	}
+
+	return entries, nil
 }
 
-func updateScrollIndicator(name string, lineCount int, tv *tview.TextView) {
-	row, _ := tv.GetScrollOffset()
-	if row <= 0 {
-		tv.SetTitle(fmt.Sprintf("%s (Top)", name))
-		return
+// The filter that is passed to jq, with the selection and the prefix applied.
+// For the preview the number of results may also be capped.
+func (d *Document) EffectiveFilter(preview bool) string {
+	filter := d.filter
+	if preview {
+		filter = d.previewFilter()
 	}
 
-	_, _, _, height := tv.GetInnerRect()
-	if row+height >= lineCount {
-		tv.SetTitle(fmt.Sprintf("%s (Bot)", name))
-		return
+	if d.options.selection != "" {
+		filter = d.options.selection + " | " + parenthesize(filter)
 	}
 
-	percent := row * 100 / lineCount
-	tv.SetTitle(fmt.Sprintf("%s (%d%%)", name, percent))
-}
+	if d.options.sortBy != "" {
+		filter = sortByFilter(d.options.sortBy) + " | " + parenthesize(filter)
+	}
 
-func createApp(doc Document) *tview.Application {
+	if d.options.prefix != "" {
+		filter = d.options.prefix + " | " + parenthesize(filter)
+	}
+
+	return filter
+}
+
+// Report whether the document filter produces more results than are shown in
+// the interactive preview
+func (d *Document) Truncated() bool {
+	if d.options.maxResults <= 0 {
+		return false
+	}
+
+	count, ok := d.count(fmt.Sprintf("limit(%d; %s)", d.options.maxResults+1, parenthesize(d.sampledFilter())))
+	return ok && count > d.options.maxResults
+}
+
+// Message shown when the input is not JSON but could be read with -json5
+const json5Notice string = "Input is not valid JSON but is valid JSON5, use -json5 to read it"
+
+// Report whether the input is not valid JSON but is valid JSON5, to suggest
+// -json5. JSON5 input is not read as raw text with -auto-raw.
+func (d *Document) suggestJSON5() bool {
+	if d.options.json5 || d.options.rawInput || d.options.nullInput {
+		return false
+	}
+
+	return looksLikeJSON5([]byte(d.input))
+}
+
+// Message shown when the input is read as raw text because of -auto-raw
+const autoRawNotice string = "Input is not valid JSON, reading it as raw text (-R)"
+
+// Enable raw input if the input is not valid JSON. Report whether raw input
+// was enabled, along with the message of jq about the input, so that the
+// user can tell a typo in a JSON input from text that is not meant to be
+// JSON. The input is checked once, and raw input is never disabled again.
+func (d *Document) detectRawInput() (string, bool) {
+	if d.options.rawInput || d.options.nullInput {
+		return "", false
+	}
+
+	c := Document{input: d.input, filter: "empty", options: d.options}
+	c.options.prefix = ""
+	_, err := c.WriteTo(io.Discard)
+	if err == nil {
+		return "", false
+	}
+
+	d.options.rawInput = true
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return strings.TrimSpace(string(exitErr.Stderr)), true
+	}
+
+	return err.Error(), true
+}
+
+// Report whether the filter argument rather names a filter file, for
+// -f-auto. To be conservative the argument must name a regular file and must
+// fail to compile as a filter, which jq reports with exit status 3. The
+// filter is compiled without being run.
+func isFilterFile(command, filter string, vars fileVars) bool {
+	info, err := os.Stat(filter)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	args := append([]string{"-n"}, vars.args()...)
+	args = append(args, "if false then "+parenthesize(filter)+" else empty end")
+	err = exec.Command(command, args...).Run()
+	exitErr, ok := err.(*exec.ExitError)
+	return ok && exitErr.ExitCode() == 3
+}
+
+// Return the files the input is read from, given the input files on the
+// command line. Standard input is added to the files before or after them
+// depending on the stdin mode, unless it is a terminal. No files means the
+// input is read from standard input alone.
+func inputFiles(files []string, mode string, stdinIsTty bool) []string {
+	if len(files) == 0 || stdinIsTty || contains(files, stdinName) {
+		return files
+	}
+
+	switch mode {
+	case StdinBefore:
+		return append([]string{stdinName}, files...)
+	case StdinAfter:
+		return append(append([]string(nil), files...), stdinName)
+	}
+
+	return files
+}
+
+// Read the document input from the given files. In stream mode (the default)
+// the files are concatenated, separated by newlines. In array mode each file
+// must contain a single JSON value and the input is an array of these values.
+// The file "-" is standard input, which must have been read into d.stdin.
+func (d *Document) ReadFiles(files []string) error {
+	array := d.options.joinMode == JoinModeArray
+
+	var buf bytes.Buffer
+	if array {
+		buf.WriteByte('[')
+	}
+
+	d.headerFilter = ""
+	for i, fname := range files {
+		var data []byte
+		var err error
+		if fname == stdinName {
+			data = d.stdin
+		} else if data, err = os.ReadFile(fname); err != nil {
+			return err
+		}
+
+		if i == 0 {
+			d.headerFilter, data, _ = splitHeader(data)
+		}
+
+		if d.options.json5 {
+			if data, err = convertJSON5(data); err != nil {
+				return fmt.Errorf("%s: %w", fname, err)
+			}
+		}
+
+		if array {
+			if !json.Valid(data) {
+				return fmt.Errorf("%s: must contain a single JSON value to be joined into an array", fname)
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.Write(bytes.TrimSpace(data))
+			continue
+		}
+
+		// Make sure values at the end of one file and the start of the
+		// next are not merged together
+		if i > 0 && buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+
+		buf.Write(data)
+	}
+
+	if array {
+		buf.WriteString("]\n")
+	}
+
+	d.input = buf.String()
+	d.files = files
+	d.edited, d.originalInput = false, ""
+	return nil
+}
+
+// The title of the session, which defaults to the names of the input files
+func (d *Document) Title() string {
+	if d.options.title != "" {
+		return d.options.title
+	}
+
+	return strings.Join(d.files, ", ")
+}
+
+// Run the input through the preprocessor command, if any. The command is run
+// with the shell, reading the input on standard input, and its standard output
+// becomes the new input. If the command fails the input is left unchanged.
+func (d *Document) Preprocess() error {
+	if d.options.preCommand == "" {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", d.options.preCommand)
+	cmd.Stdin = strings.NewReader(d.input)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("preprocessor %q failed: %v", d.options.preCommand, err)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v\n%s", err, msg)
+		}
+
+		return err
+	}
+
+	d.input = string(out)
+	return nil
+}
+
+// Filter the document with the given jq filter and options
+func (d *Document) WriteTo(w io.Writer) (n int64, err error) {
+	opts := d.options
+	_, preview := w.(*tview.TextView)
+	if preview {
+		// Writer is a TextView, so set options accordingly
+		opts = opts.preview()
+
+		// Raw output cannot disturb the pane once it is escaped
+		if opts.escapeView || opts.highlight != "" {
+			opts.rawOutput = d.options.rawOutput
+		}
+	}
+
+	// The highlighter colors the output itself, so jq must not
+	highlighted := opts.highlight != "" && opts.rawOutput && (preview || (opts.highlightOutput && !opts.monochrome))
+	if highlighted {
+		opts.forceColor = false
+		opts.monochrome = true
+	}
+
+	filter := d.EffectiveFilter(preview)
+	if opts.noSideEffects {
+		if err := checkSandbox(filter, opts.endOfOptions); err != nil {
+			return 0, err
+		}
+	}
+
+	// When ijq changes the results, jq runs twice: once to run the
+	// filter, and once to format the changed results
+	input, jqOpts := d.input, opts
+	depthLimited := preview && opts.maxDepth > 0
+	if opts.sortKeysNatural || opts.preserveOrder == PreserveOrderRestore || depthLimited {
+		input, err = d.postProcessed(filter, opts, depthLimited)
+		if err != nil {
+			return 0, err
+		}
+
+		filter, jqOpts = ".", opts.formatOptions()
+	}
+
+	args := jqOpts.ToSlice()
+	if opts.endOfOptions {
+		args = append(args, "--")
+	}
+
+	args = append(args, filter)
+
+	// The input changed by ijq is different on every run
+	path, inFile := "", false
+	if input == d.input {
+		path, inFile, err = d.inputFile.Path(input)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if inFile {
+		args = append(args, path)
+	}
+
+	cmd := exec.Command(d.options.command, args...)
+	if opts.noSideEffects {
+		cmd.Env = []string{}
+	}
+
+	if !inFile {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return 0, err
+		}
+
+		go func() {
+			defer stdin.Close()
+			_, _ = io.WriteString(stdin, input)
+		}()
+	}
+
+	// The path of the input file is hidden from the whole output, so the
+	// output of jq can only be streamed when it is read from standard input
+	streamed := opts.stream && !preview && !inFile && !highlighted && !opts.escapeOutput &&
+		!opts.trailingNewline.set && (opts.rawOutput || (opts.indentString == "" && !opts.plainNumbers))
+	if streamed {
+		return runStreaming(cmd, w)
+	}
+
+	// The output shown while jq is still running goes through the same
+	// passes as the whole output
+	process := func(out []byte) ([]byte, error) {
+		return d.processOutput(out, opts, preview, highlighted, depthLimited)
+	}
+
+	var out []byte
+	if tv, ok := w.(*tview.TextView); ok && opts.unbuffered {
+		out, err = d.runProgressively(cmd, tv, opts.renderInterval, func(out []byte) ([]byte, error) {
+			if inFile {
+				out = hideInputFile(out, path)
+			}
+			return process(out)
+		})
+	} else {
+		out, err = cmd.CombinedOutput()
+	}
+
+	if inFile {
+		out = hideInputFile(out, path)
+	}
+
+	if err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			// jq prints its error message to standard out, but we
+			// will deliver it in the Stderr field as this will
+			// most likely be an exec.ExitError.
+			exiterr.Stderr = out
+		}
+		return 0, err
+	}
+
+	out, err = process(out)
+	if err != nil {
+		return 0, err
+	}
+
+	if !preview {
+		out = opts.trailingNewline.apply(out)
+	}
+
+	if tv, ok := w.(*tview.TextView); ok {
+		w = tview.ANSIWriter(tv)
+		tv.Clear()
+	}
+
+	m, err := w.Write(out)
+	n = int64(m)
+	return n, err
+}
+
+// Change the output of jq as the options say before it is shown or written
+func (d *Document) processOutput(out []byte, opts Options, preview, highlighted, depthLimited bool) ([]byte, error) {
+	var err error
+	if depthLimited {
+		out = expandDepthMarkers(out)
+	}
+
+	if opts.indentString != "" && !opts.compact && !opts.rawOutput {
+		out = reindent(out, opts.indentString)
+	}
+
+	// Raw output is not JSON, so numbers cannot be told apart from text
+	if opts.plainNumbers && !opts.rawOutput {
+		out = plainNumbers(out)
+	}
+
+	// The glyphs are printable, so they are kept when the output is escaped
+	if preview {
+		out = visualizeControlChars(out, opts.controlChars)
+	}
+
+	if (preview && (opts.escapeView || highlighted)) || (!preview && opts.escapeOutput) {
+		out = escapeNonPrintable(out)
+	}
+
+	if highlighted {
+		out, err = highlight(out, opts.highlight)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if preview && opts.numberValues {
+		out = numberValues(out, d.options.page*d.options.pageSize)
+	}
+
+	if preview && opts.foldStrings > 0 && !opts.rawOutput {
+		out = foldStrings(out, opts.foldStrings, opts.unfolded)
+	}
+
+	return out, nil
+}
+
+// Run the filter and change its results as the options say: sort their keys
+// in natural order, put their keys back in the order of the input, and
+// collapse the values nested deeper than -max-depth if the depth is limited.
+// The results are returned as a stream of compact JSON values, so that jq can
+// then format them with the output options.
+func (d *Document) postProcessed(filter string, opts Options, depthLimited bool) (string, error) {
+	var order *keyOrder
+	if opts.preserveOrder == PreserveOrderRestore {
+		var err error
+		if order, err = d.keyOrder(); err != nil {
+			return "", err
+		}
+	}
+
+	values, err := d.results(filter, opts)
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range values {
+		if opts.sortKeysNatural {
+			v.sortKeysNaturally()
+		}
+
+		if order != nil {
+			order.restore(v)
+		}
+
+		if depthLimited {
+			v.collapseBelow(opts.maxDepth)
+		}
+	}
+
+	return string(formatJSONStream(values, "")), nil
+}
+
+// Run the command like CombinedOutput, while also showing its standard
+// output in the TextView as it is produced. At most once per interval, the
+// complete lines read so far are passed through process and shown, and the
+// progress function of the document is called so that the screen can be
+// redrawn. The messages jq writes to standard error follow the output
+// instead of being interleaved with it.
+func (d *Document) runProgressively(cmd *exec.Cmd, tv *tview.TextView, interval time.Duration, process func([]byte) ([]byte, error)) ([]byte, error) {
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	cmd.Stdout = pw
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+
+	tv.Clear()
+
+	var out bytes.Buffer
+	var drawn time.Time
+	chunk := make([]byte, 32<<10)
+	for {
+		n, readErr := pr.Read(chunk)
+		if n > 0 {
+			out.Write(chunk[:n])
+			if time.Since(drawn) >= interval {
+				d.showProgress(tv, out.Bytes(), process)
+				drawn = time.Now()
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	out.Write(stderr.Bytes())
+	return out.Bytes(), <-done
+}
+
+// Show the complete lines of the partial output in the TextView. A line cut
+// off in the middle could be taken for a different value, so it waits for
+// the next read. If the passes fail on the partial output, such as when the
+// highlighter cannot make sense of it, the view keeps what it showed before.
+func (d *Document) showProgress(tv *tview.TextView, out []byte, process func([]byte) ([]byte, error)) {
+	end := bytes.LastIndexByte(out, '\n')
+	if end < 0 {
+		return
+	}
+
+	shown, err := process(append([]byte(nil), out[:end+1]...))
+	if err != nil {
+		return
+	}
+
+	tv.Clear()
+	_, _ = tview.ANSIWriter(tv).Write(shown)
+	if d.progress != nil {
+		d.progress()
+	}
+}
+
+// Run the command and write its standard output to w as it is produced. The
+// messages jq writes to standard error are delivered in the Stderr field of
+// the error if the command fails.
+func runStreaming(cmd *exec.Cmd, w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	var stderr bytes.Buffer
+	cmd.Stdout = cw
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		exiterr.Stderr = stderr.Bytes()
+	}
+
+	return cw.n, err
+}
+
+// A writer that counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var ansiEscapePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Insert a dimmed comment with the index of each value, counting from first,
+// before the value in pretty-printed jq output. In pretty-printed output
+// every line that is not indented begins a new value, except for the closing
+// bracket of an array or object.
+func numberValues(out []byte, first int) []byte {
+	var buf bytes.Buffer
+	i := first
+	for _, line := range bytes.SplitAfter(out, []byte{'\n'}) {
+		plain := ansiEscapePattern.ReplaceAll(line, nil)
+		trimmed := string(bytes.TrimSpace(plain))
+		if len(plain) > 0 && plain[0] != ' ' && plain[0] != '\t' && trimmed != "]" && trimmed != "}" {
+			fmt.Fprintf(&buf, "\x1b[2m# %d\x1b[0m\n", i)
+			i++
+		}
+
+		buf.Write(line)
+	}
+
+	return buf.Bytes()
+}
+
+func parseArgs() (Options, string, []string) {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "ijq - interactive jq\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: ijq [-cnsrRMSV] [-f file] [-o file:format] [filter] [files ...]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+
+	options := Options{}
+	flag.BoolVar(&options.compact, "c", false, "compact instead of pretty-printed output")
+	flag.BoolVar(&options.nullInput, "n", false, "use ```null` as the single input value")
+	flag.BoolVar(&options.slurp, "s", false, "read (slurp) all inputs into an array; apply filter to it")
+	flag.BoolVar(&options.rawOutput, "r", false, "output raw strings, not JSON texts")
+	flag.BoolVar(&options.rawInput, "R", false, "read raw strings, not JSON texts")
+	flag.BoolVar(&options.forceColor, "C", false, "force colorized JSON, even if writing to a pipe or file")
+	flag.BoolVar(&options.monochrome, "M", false, "monochrome (don't colorize JSON)")
+	flag.BoolVar(&options.sortKeys, "S", false, "sort keys of objects on output")
+
+	flag.StringVar(
+		&options.command,
+		"jqbin",
+		DefaultCommand,
+		"name of or path to jq binary to use",
+	)
+
+	flag.StringVar(
+		&options.historyFile,
+		"H",
+		filepath.Join(xdg.DataHome(), "ijq", "history"),
+		"set path to history file. Set to '' to disable history.",
+	)
+
+	// The panes are always pretty-printed, so -c only changes the output
+	// written on exit. -emit-compact says so for those looking for it.
+	flag.BoolVar(
+		&options.compact,
+		"emit-compact",
+		false,
+		"same as -c: write compact output on exit while the panes stay pretty-printed",
+	)
+
+	flag.BoolVar(
+		&options.formatOnly,
+		"format-only",
+		false,
+		"write the input formatted with the output options and exit; all arguments are input files",
+	)
+
+	flag.BoolVar(
+		&options.noHistory,
+		"no-history",
+		false,
+		"suggest filters from the history file but do not write the filters of this session to it",
+	)
+
+	flag.BoolVar(
+		&options.batch,
+		"batch",
+		false,
+		"run the filter without the interactive interface and print the result",
+	)
+
+	flag.BoolVar(
+		&options.rawInputView,
+		"raw-input-view",
+		false,
+		"show the input as is in the input pane instead of formatting it with jq",
+	)
+
+	flag.StringVar(
+		&options.inputView,
+		"input-view",
+		InputViewOutput,
+		"how the input pane is formatted: output (like the output), plain (with jq's defaults), or sorted (plain with sorted keys)",
+	)
+
+	flag.StringVar(
+		&options.historyOrder,
+		"history-order",
+		HistoryOrderOldest,
+		"order of history suggestions: oldest, recent, or alpha",
+	)
+
+	flag.IntVar(
+		&options.historySuggest,
+		"history-suggest",
+		DefaultHistorySuggest,
+		"suggest the `N` most recent history entries (0 for all)",
+	)
+
+	flag.IntVar(
+		&options.maxResults,
+		"max-results",
+		0,
+		"show at most `N` results in the output pane (0 for no limit)",
+	)
+
+	flag.BoolVar(
+		&options.numberValues,
+		"number-values",
+		false,
+		"prefix each value in the output pane with its index",
+	)
+
+	flag.BoolVar(
+		&options.monoUI,
+		"mono-ui",
+		false,
+		"render the interface without colors (does not affect jq output)",
+	)
+
+	flag.Var(
+		&options.outputs,
+		"o",
+		"also write the result to `file:format` on exit (json, compact, raw, or yaml); may be repeated",
+	)
+
+	flag.BoolVar(
+		&options.minimal,
+		"minimal",
+		false,
+		"render the panes without borders and titles",
+	)
+
+	flag.Var(
+		&options.vars,
+		"var",
+		"bind the contents of a file to a variable with `name=file`; may be repeated",
+	)
+
+	flag.StringVar(
+		&options.auditFile,
+		"audit",
+		"",
+		"append a record of each accepted filter to `file`",
+	)
+
+	flag.StringVar(
+		&options.joinMode,
+		"join-mode",
+		JoinModeStream,
+		"how multiple input files are joined: stream or array",
+	)
+
+	flag.StringVar(
+		&options.stdinMode,
+		"stdin",
+		StdinIgnore,
+		"when input files are given, read standard input `before` or after them, or ignore it",
+	)
+
+	flag.StringVar(
+		&options.enterAction,
+		"enter",
+		"",
+		"what Enter does in the filter field: `accept` the filter, insert a newline, or run the filter (default from the config, or accept)",
+	)
+
+	flag.IntVar(
+		&options.completeMaxSize,
+		"complete-max-size",
+		DefaultCompleteMaxSize,
+		"do not complete keys for inputs larger than `N` bytes (0 for no limit)",
+	)
+
+	flag.BoolVar(
+		&options.plainNumbers,
+		"plain-numbers",
+		false,
+		"write numbers in plain decimal instead of scientific notation",
+	)
+
+	flag.BoolVar(
+		&options.stats,
+		"stats",
+		false,
+		"show statistics about the input in the status line at startup",
+	)
+
+	flag.BoolVar(
+		&options.autoRaw,
+		"auto-raw",
+		false,
+		"read the input as raw text (-R) if it is not valid JSON",
+	)
+
+	flag.BoolVar(
+		&options.json5,
+		"json5",
+		false,
+		"read the input as JSON5 and convert it to JSON for jq",
+	)
+
+	flag.BoolVar(
+		&options.noRestoreLayout,
+		"no-restore-layout",
+		false,
+		"do not restore the layout of the last session",
+	)
+
+	flag.Var(
+		&options.trailingNewline,
+		"trailing-newline",
+		"end the output with a newline (true) or without one (false); default is jq's output as is",
+	)
+
+	flag.BoolVar(
+		&options.noSideEffects,
+		"no-side-effects",
+		false,
+		"reject filters that read other data than the input and run jq with an empty environment",
+	)
+
+	flag.BoolVar(
+		&options.escapeView,
+		"escape-view",
+		false,
+		"show unprintable characters in the output pane as escape codes",
+	)
+
+	flag.BoolVar(
+		&options.escapeOutput,
+		"escape-output",
+		false,
+		"write unprintable characters in the output as escape codes",
+	)
+
+	flag.DurationVar(
+		&options.renderInterval,
+		"render-interval",
+		DefaultRenderInterval,
+		"render the output at most once per `interval` while typing",
+	)
+
+	flag.StringVar(
+		&options.preCommand,
+		"pre",
+		"",
+		"run the input through the shell `command` before filtering it",
+	)
+
+	flag.StringVar(
+		&options.filterQueue,
+		"filters",
+		"",
+		"run each filter in `file` without the interactive interface and print a JSON report",
+	)
+
+	flag.IntVar(
+		&options.pageSize,
+		"page-size",
+		0,
+		"show the results in the output pane in pages of `N` values (0 for no pages)",
+	)
+
+	flag.IntVar(
+		&options.foldStrings,
+		"fold-strings",
+		0,
+		"shorten strings longer than `N` characters in the output pane (0 to show them in full)",
+	)
+
+	flag.StringVar(
+		&options.indentString,
+		"indent-string",
+		"",
+		"indent each level of pretty-printed output by `string` of spaces and tabs",
+	)
+
+	flag.StringVar(
+		&options.scriptFile,
+		"script",
+		"",
+		"when the filter is accepted, write a shell script running jq with it to `file`",
+	)
+
+	flag.StringVar(
+		&options.encoding,
+		"encoding",
+		"",
+		"write the output in `encoding`, e.g. latin1 or utf-16 (default UTF-8)",
+	)
+
+	flag.StringVar(
+		&options.expectFile,
+		"expect",
+		"",
+		"show a diff between the output and the expected output in `file`",
+	)
+
+	flag.BoolVar(
+		&options.unbuffered,
+		"unbuffered",
+		false,
+		"run jq with --unbuffered and show the output pane as the output is produced",
+	)
+
+	flag.BoolVar(
+		&options.seq,
+		"seq",
+		false,
+		"write the record separator before each value, like jq --seq",
+	)
+
+	flag.BoolVar(
+		&options.softErrors,
+		"soft-errors",
+		false,
+		"show errors in the output pane after the results produced before them",
+	)
+
+	flag.StringVar(
+		&options.title,
+		"title",
+		"",
+		"show `text` as the title of the session (default is the names of the input files)",
+	)
+
+	flag.BoolVar(
+		&options.warnDupKeys,
+		"warn-dup-keys",
+		false,
+		"warn about duplicate object keys in the input at startup",
+	)
+
+	flag.BoolVar(
+		&options.explore,
+		"explore",
+		false,
+		"show the top-level keys of the input for completion at startup",
+	)
+
+	flag.BoolVar(
+		&options.trace,
+		"trace",
+		false,
+		"show how jq executes the filter in a trace pane at startup (requires jq 1.5 or later)",
+	)
+
+	flag.StringVar(
+		&options.teeFile,
+		"tee",
+		"",
+		"also write the output of each filter that runs successfully to `file` while the filter is edited",
+	)
+
+	flag.StringVar(
+		&options.schemaFile,
+		"schema",
+		"",
+		"validate the output against the JSON Schema in `file` and show the errors",
+	)
+
+	flag.StringVar(
+		&options.mergeMode,
+		"merge",
+		"",
+		"merge the input objects into one before applying the filter: deep or shallow",
+	)
+
+	flag.StringVar(
+		&options.highlight,
+		"highlight",
+		"",
+		"show raw output in the output pane highlighted with `lexer` (e.g. csv, log, or yaml)",
+	)
+
+	flag.BoolVar(
+		&options.highlightOutput,
+		"highlight-output",
+		false,
+		"also highlight the colored output with the -highlight lexer",
+	)
+
+	flag.BoolVar(
+		&options.offsets,
+		"offsets",
+		false,
+		"show the byte offset and length in the input of the value on the top line of the input pane",
+	)
+
+	flag.BoolVar(
+		&options.sortKeysNatural,
+		"sort-keys-natural",
+		false,
+		"sort keys of objects on output in natural order, so that item2 comes before item10 (overrides -S)",
+	)
+
+	flag.IntVar(
+		&options.filterWidth,
+		"filter-width",
+		DefaultFilterWidth,
+		"make the filter field at most `N` columns wide (0 for no maximum)",
+	)
+
+	flag.IntVar(
+		&options.sample,
+		"sample",
+		0,
+		"apply the filter to only the first `N` elements of arrays in the output pane (0 for the whole input)",
+	)
+
+	flag.BoolVar(
+		&options.countResults,
+		"count",
+		false,
+		"show the number of results in the title of the output pane",
+	)
+
+	flag.StringVar(
+		&options.sortBy,
+		"sort-by",
+		"",
+		"sort the input array by the jq `path` before the filter, e.g. .date",
+	)
+
+	flag.IntVar(
+		&options.completeJobs,
+		"complete-jobs",
+		DefaultCompleteJobs,
+		"run at most `N` jq processes at a time to compute keys for completion",
+	)
+
+	flag.StringVar(
+		&options.preserveOrder,
+		"preserve-order",
+		"",
+		"`warn` when the filter reorders the keys of the input, or restore their order",
+	)
+
+	flag.StringVar(
+		&options.catalogFile,
+		"catalog",
+		"",
+		"load a catalog of shared filters from `file`, picked with Alt-Q (default from the config)",
+	)
+
+	flag.IntVar(
+		&options.maxDepth,
+		"max-depth",
+		0,
+		"collapse objects and arrays nested deeper than `N` levels in the output pane (0 for no limit)",
+	)
+
+	flag.BoolVar(
+		&options.clearOnError,
+		"clear-on-error",
+		false,
+		"clear the output pane when the filter fails instead of keeping the last good output",
+	)
+
+	flag.IntVar(
+		&options.inputFileSize,
+		"input-file-size",
+		DefaultInputFileSize,
+		"pass inputs of at least `N` bytes to jq in a temporary file instead of on standard input (0 disables)",
+	)
+
+	flag.BoolVar(
+		&options.compatWarnings,
+		"compat-warnings",
+		false,
+		"warn about builtins in the filter that the installed version of jq does not have",
+	)
+
+	flag.BoolVar(
+		&options.repl,
+		"repl",
+		false,
+		"read filters from a prompt and print their output instead of running the interactive interface",
+	)
+
+	colorFile := flag.Bool("color-file", false, "keep colors in the output when it is not written to a terminal (cannot be used with -M)")
+	pointer := flag.String("pointer", "", "apply the filter to the value at JSON `pointer` within the input")
+	flag.StringVar(
+		&options.configFile,
+		"config",
+		filepath.Join(xdg.ConfigHome(), "ijq", "config.json"),
+		"set path to config file",
+	)
+
+	flag.StringVar(&options.filterFile, "f", "", "read initial filter from `filename`")
+	flag.BoolVar(&options.filterFileAuto, "f-auto", false, "read the filter from the file named by the filter argument if it is one and is not a valid filter")
+	version := flag.Bool("V", false, "print version and exit")
+
+	cmdline, err := expandResponseFiles(os.Args[1:], flag.CommandLine)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// The default flag set exits on errors
+	_ = flag.CommandLine.Parse(cmdline)
+
+	if *version {
+		fmt.Println("ijq " + Version)
+		os.Exit(0)
+	}
+
+	switch options.historyOrder {
+	case HistoryOrderOldest, HistoryOrderRecent, HistoryOrderAlpha:
+	default:
+		log.Fatalf("invalid history order %q: must be one of oldest, recent, or alpha\n", options.historyOrder)
+	}
+
+	switch options.joinMode {
+	case JoinModeStream:
+	case JoinModeArray:
+		if options.rawInput {
+			log.Fatalln("-join-mode array cannot be used with -R")
+		}
+	default:
+		log.Fatalf("invalid join mode %q: must be one of stream or array\n", options.joinMode)
+	}
+
+	switch options.inputView {
+	case InputViewOutput, InputViewPlain, InputViewSorted:
+	default:
+		log.Fatalf("invalid input view %q: must be one of output, plain, or sorted\n", options.inputView)
+	}
+
+	switch options.mergeMode {
+	case "":
+	case MergeDeep, MergeShallow:
+		if options.rawInput {
+			log.Fatalln("-merge cannot be used with -R")
+		}
+
+		if options.joinMode == JoinModeArray {
+			log.Fatalln("-merge cannot be used with -join-mode array")
+		}
+	default:
+		log.Fatalf("invalid merge strategy %q: must be one of deep or shallow\n", options.mergeMode)
+	}
+
+	if options.json5 && (options.rawInput || options.autoRaw) {
+		log.Fatalln("-json5 cannot be used with -R or -auto-raw")
+	}
+
+	switch options.stdinMode {
+	case StdinIgnore, StdinBefore, StdinAfter:
+	default:
+		log.Fatalf("invalid stdin mode %q: must be one of ignore, before, or after\n", options.stdinMode)
+	}
+
+	if options.enterAction != "" && !enterActions[options.enterAction] {
+		log.Fatalf("invalid Enter action %q: must be one of accept, newline, or run\n", options.enterAction)
+	}
+
+	if options.completeJobs < 1 {
+		log.Fatalln("-complete-jobs must be at least 1")
+	}
+
+	if options.maxResults < 0 {
+		log.Fatalln("-max-results cannot be negative")
+	}
+
+	if options.repl && options.batch {
+		log.Fatalln("-repl cannot be used with -batch")
+	}
+
+	if options.preserveOrder != "" {
+		if !preserveOrderModes[options.preserveOrder] {
+			log.Fatalf("invalid preserve order mode %q: must be one of warn or restore\n", options.preserveOrder)
+		}
+
+		if options.rawInput || options.nullInput || options.autoRaw || options.sortKeys || options.sortKeysNatural {
+			log.Fatalln("-preserve-order requires JSON input and cannot be used with -R, -n, -auto-raw, -S, or -sort-keys-natural")
+		}
+	}
+
+	if _, err := lookupEncoding(options.encoding); err != nil {
+		log.Fatalln(err)
+	}
+
+	if options.indentString != "" {
+		if err := checkIndentString(options.indentString); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if options.highlight != "" {
+		if _, err := findLexer(options.highlight); err != nil {
+			log.Fatalln(err)
+		}
+	} else if options.highlightOutput {
+		log.Fatalln("-highlight-output requires -highlight")
+	}
+
+	// Colors are kept by asking jq to color its output unconditionally,
+	// while -M asks it to never color its output
+	if *colorFile {
+		if options.monochrome {
+			log.Fatalln("-color-file cannot be used with -M")
+		}
+
+		options.forceColor = true
+	}
+
+	if *pointer != "" {
+		prefix, err := pointerToFilter(*pointer)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		options.prefix = prefix
+	}
+
+	if options.formatOnly {
+		if options.nullInput || options.filterFile != "" || options.filterQueue != "" {
+			log.Fatalln("-format-only cannot be used with -n, -f, or -filters")
+		}
+
+		options.batch = true
+	}
+
+	if options.offsets && !offsetsAvailable(options) {
+		log.Fatalln("-offsets requires JSON input and cannot be used with -n, -pointer, -json5, or -sort-by")
+	}
+
+	// The filter is empty if none is given on the command line
+	filter := ""
+	args := flag.Args()
+
+	stdinIsTty := term.IsTerminal(int(os.Stdin.Fd()))
+
+	if options.filterFile != "" {
+		contents, err := os.ReadFile(options.filterFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		filter = string(contents)
+	} else if options.filterQueue != "" {
+		// The filters are read from the queue file, so all positional
+		// arguments are input files
+		if len(args) == 0 && stdinIsTty && !options.nullInput {
+			flag.Usage()
+			os.Exit(1)
+		}
+	} else if options.formatOnly {
+		// The input is written as is, so all positional arguments are
+		// input files
+		if len(args) == 0 && stdinIsTty {
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		filter = "."
+	} else if len(args) > 1 || (len(args) > 0 && (!stdinIsTty || options.nullInput)) {
+		filter = args[0]
+		args = args[1:]
+
+		if options.filterFileAuto && isFilterFile(options.command, filter, options.vars) {
+			contents, err := os.ReadFile(filter)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			options.filterFile = filter
+			filter = string(contents)
+		}
+	} else if len(args) == 0 && stdinIsTty && !options.nullInput {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	return options, filter, args
+}
+
+func scrollHalfPage(tv *tview.TextView, up bool) {
+	_, _, _, height := tv.GetInnerRect()
+	row, col := tv.GetScrollOffset()
+	if up {
+		tv.ScrollTo(row-height/2, col)
+	} else {
+		tv.ScrollTo(row+height/2, col)
+	}
+}
+
+func scrollHorizontally(tv *tview.TextView, end bool) {
+	if end {
+		text := tv.GetText(true)
+		_, _, width, height := tv.GetInnerRect()
+		row, _ := tv.GetScrollOffset()
+		maxLen := 0
+		for i, line := range strings.Split(text, "\n") {
+			if i < row {
+				continue
+			}
+
+			if i > row+height {
+				break
+			}
+
+			if length := len(line); length > maxLen {
+				maxLen = length
+			}
+		}
+
+		if maxLen > width {
+			tv.ScrollTo(row, maxLen-width)
+		}
+	} else {
+		row, _ := tv.GetScrollOffset()
+		tv.ScrollTo(row, 0)
+	}
+}
+
+// Center a primitive with the given size on the screen, for use as a modal
+// dialog over the main view
+func modal(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false)
+}
+
+func updateScrollIndicator(name string, lineCount int, tv *tview.TextView) {
+	row, _ := tv.GetScrollOffset()
+	if row <= 0 {
+		tv.SetTitle(fmt.Sprintf("%s (Top)", name))
+		return
+	}
+
+	_, _, _, height := tv.GetInnerRect()
+	if row+height >= lineCount {
+		tv.SetTitle(fmt.Sprintf("%s (Bot)", name))
+		return
+	}
+
+	percent := row * 100 / lineCount
+	tv.SetTitle(fmt.Sprintf("%s (%d%%)", name, percent))
+}
+
+// Create the interactive interface for the document. The accept function is
+// called with the document when the user accepts the filter with Enter,
+// after the application has stopped.
+func createApp(doc Document, config *Config, accept func(Document)) *tview.Application {
 	app := tview.NewApplication()
 
-	// tview uses colors for a dark background by default, so reset some of
-	// the styles to simply use the colors from the terminal to better
-	// support light color themes
-	tview.Styles.PrimaryTextColor = tcell.ColorDefault
-	tview.Styles.PrimitiveBackgroundColor = tcell.ColorDefault
-	tview.Styles.BorderColor = tcell.ColorDefault
-	tview.Styles.TitleColor = tcell.ColorDefault
-	tview.Styles.GraphicsColor = tcell.ColorDefault
+	// The output is rendered on the event loop, so the screen can be
+	// redrawn directly to show partial output
+	doc.progress = func() {
+		app.ForceDraw()
+	}
+
+	// The filter runs on every change, so the key order of the input is
+	// only found again when the input changes
+	doc.keyOrders = &keyOrderCache{}
+
+	// tview uses colors for a dark background by default, so reset some of
+	// the styles to simply use the colors from the terminal to better
+	// support light color themes
+	tview.Styles.PrimaryTextColor = tcell.ColorDefault
+	tview.Styles.PrimitiveBackgroundColor = tcell.ColorDefault
+	tview.Styles.BorderColor = tcell.ColorDefault
+	tview.Styles.TitleColor = tcell.ColorDefault
+	tview.Styles.GraphicsColor = tcell.ColorDefault
+
+	autocompleteBackground := tcell.ColorBlack
+	if doc.options.monoUI {
+		tview.Styles.ContrastBackgroundColor = tcell.ColorDefault
+		tview.Styles.MoreContrastBackgroundColor = tcell.ColorDefault
+		tview.Styles.SecondaryTextColor = tcell.ColorDefault
+		tview.Styles.TertiaryTextColor = tcell.ColorDefault
+		tview.Styles.InverseTextColor = tcell.ColorDefault
+		tview.Styles.ContrastSecondaryTextColor = tcell.ColorDefault
+		autocompleteBackground = tcell.ColorDefault
+	}
+
+	// In minimal mode the panes have no borders, and so no titles, which
+	// saves two rows and columns for each pane
+	bordered := !doc.options.minimal
+	borderSize := 0
+	if bordered {
+		borderSize = 2
+	}
+
+	inputView := tview.NewTextView()
+	inputView.SetDynamicColors(true).SetWrap(false).SetBorder(bordered)
+
+	outputView := tview.NewTextView()
+	outputView.SetDynamicColors(true).SetWrap(false).SetBorder(bordered)
+
+	errorView := tview.NewTextView()
+	errorView.SetDynamicColors(true).SetTitle("Error").SetBorder(bordered)
+
+	statusView := tview.NewTextView()
+	statusView.SetDynamicColors(true)
+
+	// Shows the jq path of the value at the top of the output pane
+	pathView := tview.NewTextView()
+	pathView.SetTextAlign(tview.AlignRight)
+
+	// Show a message in the status line for a short time. Each message
+	// replaces the previous one. This must be called from the main
+	// goroutine.
+	var statusGeneration int
+	flashStatus := func(message string) {
+		statusGeneration++
+		generation := statusGeneration
+		statusView.SetText(message)
+		go func() {
+			time.Sleep(statusDuration)
+			app.QueueUpdateDraw(func() {
+				if statusGeneration == generation {
+					statusView.Clear()
+				}
+			})
+		}()
+	}
+
+	filterFull := tview.NewTextView()
+	filterFull.SetWrap(true).SetTitle("Full filter").SetBorder(bordered)
+
+	// The filter actually run by jq, shown dimmed when enabled
+	effectiveView := tview.NewTextView()
+	effectiveView.SetWrap(true).SetTextStyle(tcell.StyleDefault.Dim(true))
+	effectiveView.SetTitle("Effective filter").SetBorder(bordered)
+
+	// Follows the output of the filter with -tee
+	var tee *teeWriter
+	if doc.options.teeFile != "" {
+		f, err := os.OpenFile(doc.options.teeFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			doc.inputFile.fatal(err)
+		}
+
+		tee = newTeeWriter(f, teeInterval)
+	}
+
+	// The input and output panes, and the trace pane when it is shown
+	panes := tview.NewFlex().
+		AddItem(inputView, 0, 1, false).
+		AddItem(outputView, 0, 1, false)
+
+	// Shows the trace of jq running the filter. The trace is computed in
+	// the background, since jq runs the filter again to produce it, and a
+	// trace that is outdated by the time it is done is discarded.
+	traceView := tview.NewTextView()
+	traceView.SetWrap(false).SetTitle("Trace").SetBorder(bordered)
+	traceShown := false
+	var traceGeneration int
+	updateTrace := func() {
+		if !traceShown {
+			return
+		}
+
+		traceGeneration++
+		generation := traceGeneration
+		d := doc
+		go func() {
+			trace, err := d.Trace()
+			if err != nil {
+				trace = err.Error()
+			}
+
+			app.QueueUpdateDraw(func() {
+				if traceGeneration == generation {
+					traceView.SetText(trace).ScrollToBeginning()
+				}
+			})
+		}()
+	}
+
+	if doc.options.trace {
+		traceShown = true
+		panes.AddItem(traceView, 0, 1, false)
+	}
+
+	// Shows the paths that the filter adds, removes, or changes in the
+	// input. Like the trace, the paths are computed in the background.
+	changesView := tview.NewTextView()
+	changesView.SetDynamicColors(true).SetWrap(false).SetTitle("Changes").SetBorder(bordered)
+	changesShown := false
+	var changesGeneration int
+	updateChanges := func() {
+		if !changesShown {
+			return
+		}
+
+		changesGeneration++
+		generation := changesGeneration
+		d := doc
+		go func() {
+			changes, err := d.Changes()
+			title, text := "Changes", tview.Escape(fmt.Sprint(err))
+			if err == nil {
+				text = formatChanges(changes, !d.options.monoUI)
+			}
+
+			if len(changes) > 0 {
+				title += " (" + plural(len(changes), "path", "paths") + ")"
+			}
+
+			app.QueueUpdateDraw(func() {
+				if changesGeneration == generation {
+					changesView.SetTitle(title)
+					changesView.SetText(text).ScrollToBeginning()
+				}
+			})
+		}()
+	}
+
+	// Whether the output conforms to the -schema, which is known once it
+	// has been validated
+	schemaValid := false
+
+	filterHistory := history{readOnly: doc.options.noHistory}
+	filterHistory.Init(doc.options.historyFile)
+
+	var inputLineCount int
+	var outputLineCount int
+
+	// When enabled, the output pane shows a diff between the input and the
+	// filtered output, or between the expected output and the output with
+	// -expect, which starts in diff mode
+	diffMode := doc.options.expectFile != ""
+
+	// Whether the output is the expected output, which is only known in
+	// diff mode
+	expectedMatch := false
+
+	outputTitle := "Output"
+	pageCount := 1
+	resultCount, resultsCounted := 0, false
+	keysReordered := false
+	truncated := false
+	updateOutputTitle := func() {
+		if diffMode && doc.options.expectFile != "" {
+			outputTitle = "Diff with " + tview.Escape(doc.options.expectFile)
+			if expectedMatch {
+				outputTitle += " (matches)"
+			}
+		} else if diffMode {
+			outputTitle = "Diff"
+		} else if truncated {
+			outputTitle = fmt.Sprintf("Output (showing first %d of many)", doc.options.maxResults)
+		} else {
+			outputTitle = "Output"
+		}
+
+		if doc.options.pageSize > 0 && !diffMode {
+			outputTitle += fmt.Sprintf(" (page %d/%d)", doc.options.page+1, pageCount)
+		}
+
+		// A truncated output already says that there are many results
+		if doc.options.countResults && !diffMode && !truncated && resultsCounted {
+			outputTitle += " (" + plural(resultCount, "result", "results") + ")"
+		}
+
+		if doc.options.escapeView && !diffMode {
+			outputTitle += " (escaped)"
+		}
+
+		if doc.options.softErrors && !diffMode {
+			outputTitle += " (soft errors)"
+		}
+
+		if doc.options.sample > 0 && !diffMode {
+			outputTitle += fmt.Sprintf(" (sampled, first %d elements)", doc.options.sample)
+		}
+
+		if schemaValid && !diffMode {
+			outputTitle += " (valid)"
+		}
+
+		if doc.options.preserveOrder != "" && !diffMode && keysReordered {
+			if doc.options.preserveOrder == PreserveOrderRestore {
+				outputTitle += " (key order restored)"
+			} else {
+				outputTitle += " (keys reordered)"
+			}
+		}
+	}
+
+	// Counting the results, checking whether there are more than
+	// -max-results, and looking for reordered keys run the filter on the
+	// whole input, so they are done in the background when the output
+	// changes, and the title shows what was last found until then. The
+	// pages are counted from the same count. Counts that are outdated by
+	// the time they are done are discarded.
+	var countsGeneration int
+	updateOutputCounts := func() {
+		countsGeneration++
+		generation := countsGeneration
+		countResults := doc.options.pageSize > 0 || doc.options.countResults
+		checkOrder := doc.options.preserveOrder != ""
+		checkTruncated := doc.options.maxResults > 0
+		if (!countResults && !checkOrder && !checkTruncated) || diffMode {
+			return
+		}
+
+		d := doc
+		go func() {
+			count, ok := 0, false
+			if countResults {
+				count, ok = d.ResultCount()
+			}
+
+			reordered := checkOrder && d.reordersKeys(d.limitedFilter())
+			more := checkTruncated && d.Truncated()
+			app.QueueUpdateDraw(func() {
+				if countsGeneration == generation {
+					resultCount, resultsCounted = count, ok
+					pageCount = countPages(count, d.options.pageSize)
+					keysReordered = reordered
+					truncated = more
+					updateOutputTitle()
+				}
+			})
+		}()
+	}
+
+	// The jq path of the value on each line of the output pane
+	var outputPaths []string
+
+	// Whether the output pane says so when the filter produces no results
+	markEmpty := true
+
+	// Scroll positions in the output pane that were marked with m and a
+	// letter or digit, like marks in vim. The marks belong to the output
+	// they were set in and are cleared when the output changes.
+	type outputMark struct {
+		row, col int
+	}
+	marks := map[rune]outputMark{}
+	markedOutput := ""
+
+	// Validate the output against the -schema in the background and show
+	// the errors in the error pane. A validation that is outdated by the
+	// time it is done is discarded.
+	var schemaGeneration int
+	validateSchema := func() {
+		if doc.schema == nil {
+			return
+		}
+
+		schemaGeneration++
+		generation := schemaGeneration
+		schemaValid = false
+		d := doc
+		go func() {
+			messages, err := d.ValidateSchema(d.schema)
+			app.QueueUpdateDraw(func() {
+				// A failing filter has already shown its error
+				if schemaGeneration != generation || err != nil {
+					return
+				}
+
+				schemaValid = len(messages) == 0
+				updateOutputTitle()
+				if !schemaValid {
+					fmt.Fprintf(errorView, "The output does not conform to %s:\n", tview.Escape(d.options.schemaFile))
+					for _, message := range messages {
+						fmt.Fprintln(errorView, tview.Escape(message))
+					}
+				}
+			})
+		}()
+	}
+
+	// Update state derived from the contents of the output pane
+	outputChanged := func() {
+		outputLineCount = strings.Count(outputView.GetText(false), "\n")
+		updateOutputCounts()
+		updateOutputTitle()
+		text := outputView.GetText(true)
+		if diffMode {
+			outputPaths = nil
+		} else {
+			outputPaths = linePaths(text)
+		}
+
+		if text != markedOutput {
+			marks = map[rune]outputMark{}
+			markedOutput = text
+		}
+
+		// An empty output pane would look like the filter did not run
+		if markEmpty && !diffMode && outputLineCount == 0 && outputView.GetText(false) == "" {
+			outputView.SetText("[::d](no results)[::-]")
+		}
+
+		updateTrace()
+		updateChanges()
+		validateSchema()
+		if tee != nil {
+			tee.Update(doc)
+		}
+	}
+
+	renderOutput := func() error {
+		outputView.ScrollToBeginning()
+		expectedMatch = false
+		if diffMode && doc.options.expectFile != "" {
+			match, err := doc.WriteExpectedDiffTo(outputView)
+			expectedMatch = match && err == nil
+			if expectedMatch && !doc.options.monoUI {
+				outputView.SetBorderColor(tcell.ColorGreen)
+			} else {
+				outputView.SetBorderColor(tview.Styles.BorderColor)
+			}
+
+			return err
+		}
+
+		outputView.SetBorderColor(tview.Styles.BorderColor)
+		if diffMode {
+			return doc.WriteDiffTo(outputView)
+		}
+
+		_, err := doc.WriteTo(outputView)
+		return err
+	}
+
+	var mutex sync.Mutex
+	filterMap := make(map[string][]string)
+	keysGeneration := 0
+	keyJobs := newKeyPool(doc.options.completeJobs)
+
+	// Only builtins supported by the installed version of jq are completed
+	builtins := availableBuiltins(detectJQVersion(doc.options.command))
+
+	// Computing keys for completion runs jq on the whole input, which is
+	// too slow for very large inputs
+	keyCompletion := func() bool {
+		return doc.options.completeMaxSize <= 0 || len(doc.input) <= doc.options.completeMaxSize
+	}
+
+	// Return the paths of the keys of the values produced by prefix in the
+	// document d, e.g. .items[].id for the prefix .items[]. The keys are
+	// computed with jq and cached. Returns false if the keys could not be
+	// computed. This runs in the background, so d is a copy of the
+	// document.
+	discoverKeys := func(d Document, prefix string) ([]string, bool) {
+		mutex.Lock()
+		entries, ok := filterMap[prefix]
+		generation := keysGeneration
+		mutex.Unlock()
+		if ok {
+			return entries, true
+		}
+
+		entries, err := d.Keys(prefix)
+		if err != nil {
+			return nil, false
+		}
+
+		// The keys of an input that has since changed are not kept
+		mutex.Lock()
+		if generation == keysGeneration {
+			filterMap[prefix] = entries
+		}
+		mutex.Unlock()
+
+		return entries, true
+	}
+
+	// Forget the keys computed for completion, which are those of an input
+	// that changed
+	resetKeys := func() {
+		mutex.Lock()
+		filterMap = make(map[string][]string)
+		keysGeneration++
+		mutex.Unlock()
+	}
+
+	filterInput := tview.NewInputField()
+
+	// Indicate whether the current filter failed. In monochrome mode the
+	// filter is underlined instead of colored.
+	markFilter := func(failed bool) {
+		style := tcell.StyleDefault
+		if failed && doc.options.monoUI {
+			style = style.Underline(true)
+		} else if failed {
+			style = style.Foreground(tcell.ColorMaroon)
+		}
+
+		filterInput.SetFieldStyle(style)
+	}
+
+	// The last good output while the output pane is cleared because the
+	// filter failed, which is shown again if errors stop clearing it
+	type clearedOutput struct {
+		text      string
+		lineCount int
+		paths     []string
+	}
+
+	var cleared clearedOutput
+	outputCleared := false
+
+	// The warning about duplicate keys in the input with -warn-dup-keys,
+	// which is found at startup. Running the filter clears the error pane,
+	// so the warning is written again on every run until the input changes.
+	dupKeysWarning := ""
+	writeDupKeysWarning := func() {
+		if dupKeysWarning != "" {
+			fmt.Fprintln(errorView, tview.Escape(dupKeysWarning))
+		}
+	}
+
+	// Warn about builtins that jq does not have with -compat-warnings. The
+	// warning does not stop the filter from running, and it explains why
+	// jq fails.
+	writeCompatWarning := func() {
+		if warning := doc.CompatWarning(); warning != "" && doc.options.monoUI {
+			fmt.Fprintln(errorView, tview.Escape(warning))
+		} else if warning != "" {
+			fmt.Fprintln(errorView, "[yellow]"+tview.Escape(warning)+"[-]")
+		}
+	}
+
+	// Run the current filter and update the output and error panes. This
+	// must be called from the main goroutine.
+	runFilter := func() {
+		errorView.Clear()
+		effectiveView.SetText(doc.EffectiveFilter(true))
+		writeDupKeysWarning()
+		writeCompatWarning()
+		err := renderOutput()
+		if err != nil {
+			markFilter(true)
+			exitErr, ok := err.(*exec.ExitError)
+			if ok {
+				fmt.Fprint(tview.ANSIWriter(errorView), string(exitErr.Stderr))
+			} else {
+				fmt.Fprint(errorView, err.Error())
+			}
+
+			if doc.options.clearOnError && !outputCleared {
+				cleared = clearedOutput{outputView.GetText(false), outputLineCount, outputPaths}
+				outputCleared = true
+				outputView.SetText("[::d](the filter failed)[::-]")
+				outputLineCount, outputPaths = 0, nil
+			} else if !doc.options.clearOnError && outputCleared {
+				outputCleared = false
+				outputView.SetText(cleared.text)
+				outputLineCount, outputPaths = cleared.lineCount, cleared.paths
+			}
+
+			// The trace shows how far the filter got
+			updateTrace()
+			return
+		}
+
+		outputCleared = false
+		outputChanged()
+		markFilter(false)
+	}
+
+	// The input field does not expose its cursor, so the cursor is moved
+	// by sending key events to the field. Move the cursor to the given byte
+	// offset in the filter.
+	moveFilterCursor := func(offset int) {
+		handler := filterInput.InputHandler()
+		handler(tcell.NewEventKey(tcell.KeyHome, ' ', tcell.ModNone), nil)
+		for i := utf8.RuneCountInString(filterInput.GetText()[:offset]); i > 0; i-- {
+			handler(tcell.NewEventKey(tcell.KeyRight, ' ', tcell.ModNone), nil)
+		}
+	}
+
+	// Replace the next placeholder in the filter with its default text and
+	// move the cursor to the end of it.
+	jumpToPlaceholder := func() bool {
+		text := filterInput.GetText()
+		start, end, def, ok := nextPlaceholder(text)
+		if !ok {
+			return false
+		}
+
+		filterInput.SetText(text[:start] + def + text[end:])
+		moveFilterCursor(start + len(def))
+		return true
+	}
+
+	// Make the text the filter of the document and run it
+	applyFilter := func(text string) {
+		doc.filter = text
+		doc.options.page = 0
+		doc.options.unfolded = nil
+		filterFull.SetText(text)
+		runFilter()
+	}
+
+	// While live filtering is paused the filter of the document follows
+	// the text, so that Enter accepts what was typed, but the filter is
+	// only run on demand
+	paused := false
+
+	// Changes to the filter are coalesced, so that the output is rendered
+	// at most once per render interval, with the latest filter, however
+	// fast the filter is typed. Both the changes and the rendering happen
+	// on the event loop, so the state needs no locking.
+	pendingFilter := ""
+	renderPending := false
+	filterChanged := func(text string) {
+		// Say what a filter picked from the history is for
+		if note := filterHistory.Note(text); note != "" {
+			flashStatus(tview.Escape("Note: " + note))
+		}
+
+		if paused {
+			doc.filter = text
+			filterFull.SetText(text)
+			return
+		}
+
+		pendingFilter = text
+		if renderPending {
+			return
+		}
+
+		renderPending = true
+		time.AfterFunc(doc.options.renderInterval, func() {
+			app.QueueUpdateDraw(func() {
+				renderPending = false
+				if !paused {
+					applyFilter(pendingFilter)
+				}
+			})
+		})
+	}
+
+	// Return the byte offset of the cursor in the filter. A marker is typed
+	// at the cursor to find it and is deleted again, without running the
+	// filter.
+	filterCursor := func() int {
+		text := filterInput.GetText()
+		handler := filterInput.InputHandler()
+		filterInput.SetChangedFunc(nil)
+		handler(tcell.NewEventKey(tcell.KeyRune, cursorMarker, tcell.ModNone), nil)
+		offset := strings.IndexRune(filterInput.GetText(), cursorMarker)
+		handler(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone), nil)
+		// Close the autocompletion list opened by the edits
+		handler(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), nil)
+		filterInput.SetChangedFunc(filterChanged)
+		if offset < 0 || filterInput.GetText() != text {
+			return len(filterInput.GetText())
+		}
+
+		return offset
+	}
+
+	// Exit and write the output of the filter
+	acceptFilter := func() {
+		// The text may not have been rendered yet
+		doc.filter = filterInput.GetText()
+		app.Stop()
+		filterHistory.Add(doc.filter)
+		accept(doc)
+	}
+
+	// Insert text into the filter at the given byte offset and move the
+	// cursor to the end of the inserted text
+	insertFilter := func(offset int, text string) {
+		filter := filterInput.GetText()
+		filterInput.SetText(filter[:offset] + text + filter[offset:])
+		moveFilterCursor(offset + len(text))
+	}
+
+	// The entries of the autocompletion list as they were last computed,
+	// and the text they were computed for. The list is open from when it
+	// has entries until a key closes it.
+	var completion struct {
+		mutex   sync.Mutex
+		text    string
+		entries []string
+		open    bool
+	}
+
+	// Return the entry that the open autocompletion list highlights
+	highlightedCompletion := func() (string, bool) {
+		completion.mutex.Lock()
+		defer completion.mutex.Unlock()
+		if !completion.open {
+			return "", false
+		}
+
+		// Moving through the list puts the entry in the field
+		text := filterInput.GetText()
+		if text != completion.text {
+			return text, true
+		}
+
+		// The list first highlights the first entry that begins with
+		// the text
+		for _, entry := range completion.entries {
+			if strings.HasPrefix(tview.Escape(entry), text) {
+				return entry, true
+			}
+		}
+
+		return "", false
+	}
+
+	complete := func(text string) []string {
+		if text == "" {
+			suggestions := filterHistory.Suggestions(doc.options.historyOrder, doc.options.historySuggest)
+			return config.PinFavorites(text, suggestions)
+		}
+
+		if entries := completeBuiltin(text, builtins); len(entries) > 0 {
+			return config.PinFavorites(text, entries)
+		}
+
+		if pos := strings.LastIndexByte(text, '.'); pos != -1 && keyCompletion() {
+			prefix := text[0:pos]
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			candidates, ok := filterMap[prefix]
+			if ok {
+				cur := text[pos+1:]
+				var entries []string
+				for _, c := range candidates {
+					key := c[pos+1:]
+					if strings.HasPrefix(key, cur) {
+						entries = append(entries, c)
+					}
+				}
+
+				return config.PinFavorites(text, entries)
+			}
+
+			d := doc
+			keyJobs.Go(prefix, func() {
+				if _, ok := discoverKeys(d, prefix); !ok {
+					return
+				}
+
+				filterInput.Autocomplete()
+
+				app.Draw()
+			})
+		}
+
+		return config.PinFavorites(text, nil)
+	}
+
+	filterInput.
+		SetText(doc.filter).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetFieldTextColor(tcell.ColorDefault).
+		SetChangedFunc(filterChanged).
+		SetDoneFunc(func(key tcell.Key) {
+			if key != tcell.KeyEnter {
+				return
+			}
+
+			switch doc.options.enterAction {
+			case EnterNewline:
+				// The field handles no other key until it is done
+				// with this one, and finding the cursor types keys
+				go app.QueueUpdateDraw(func() {
+					insertFilter(filterCursor(), "\n")
+				})
+			case EnterRun:
+				applyFilter(filterInput.GetText())
+			default:
+				acceptFilter()
+			}
+		}).
+		SetAutocompleteFunc(func(text string) []string {
+			entries := complete(text)
+
+			completion.mutex.Lock()
+			completion.text, completion.entries, completion.open = text, entries, len(entries) > 0
+			completion.mutex.Unlock()
+
+			escaped := make([]string, len(entries))
+			for i, entry := range entries {
+				escaped[i] = tview.Escape(entry)
+			}
+
+			return escaped
+		}).
+		SetAutocompleteStyles(autocompleteBackground, tcell.StyleDefault, tcell.StyleDefault.Reverse(true)).
+		SetBorder(bordered)
+
+	// These keys close the autocompletion list
+	filterInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyEnter, tcell.KeyTab:
+			completion.mutex.Lock()
+			completion.open = false
+			completion.mutex.Unlock()
+		}
+
+		return event
+	})
+
+	// The title of the filter field shows the current slot once slots are
+	// used and whether key completion is disabled
+	filterSlotLabel := ""
+	updateFilterTitle := func() {
+		var labels []string
+		if filterSlotLabel != "" {
+			labels = append(labels, filterSlotLabel)
+		}
+
+		if !keyCompletion() {
+			labels = append(labels, "no key completion")
+		}
+
+		if paused {
+			labels = append(labels, "paused, Ctrl-R runs")
+		}
+
+		title := "Filter"
+		if sessionTitle := doc.Title(); sessionTitle != "" {
+			title += ": " + tview.Escape(sessionTitle)
+		}
+
+		if len(labels) > 0 {
+			title += " (" + strings.Join(labels, ", ") + ")"
+		}
+
+		filterInput.SetTitle(title)
+	}
+
+	updateFilterTitle()
+
+	// Pause or resume running the filter as it is typed. Resuming runs the
+	// current filter.
+	togglePause := func() {
+		paused = !paused
+		updateFilterTitle()
+		if !paused {
+			applyFilter(filterInput.GetText())
+		}
+	}
+
+	// The jq path of each line of the formatted input
+	var inputPaths []string
+
+	// The byte offsets of the values in the input, and the top-level value
+	// that each line of the formatted input is part of. The input is only
+	// parsed again when it changes.
+	var offsets inputOffsets
+	var offsetsInput string
+	var inputRoots []int
+	updateOffsets := func() {
+		if !doc.options.offsets || inputPaths == nil {
+			inputRoots = nil
+			return
+		}
+
+		inputRoots = lineRoots(inputView.GetText(true))
+		if offsets.spans == nil || offsetsInput != doc.input {
+			offsets = parseOffsets(doc.input, maxOffsetValues)
+			offsetsInput = doc.input
+		}
+	}
+
+	inputTitle := "Input"
+	renderInput := func() error {
+		if doc.options.rawInputView {
+			inputPaths = nil
+			inputRoots = nil
+			inputTitle = "Input (raw)"
+			inputView.SetText(tview.Escape(doc.input))
+			inputLineCount = strings.Count(doc.input, "\n")
+			return nil
+		}
+
+		inputTitle = "Input"
+		if doc.options.sortBy != "" {
+			inputTitle = "Input (sorted by " + tview.Escape(doc.options.sortBy) + ")"
+		}
+
+		if doc.edited {
+			inputTitle += " (edited)"
+		}
+
+		d := Document{input: doc.input, filter: ".", options: doc.options.inputViewOptions()}
+		if _, err := d.WriteTo(inputView); err != nil {
+			return err
+		}
+
+		inputLineCount = strings.Count(inputView.GetText(false), "\n")
+		inputPaths = linePaths(inputView.GetText(true))
+		updateOffsets()
+		return nil
+	}
+
+	// Raw input is not JSON and there is no input with -n
+	if doc.options.stats && !doc.options.rawInput && !doc.options.nullInput {
+		go func() {
+			stats := inputStats(doc.input)
+			app.QueueUpdateDraw(func() {
+				flashStatus(tview.Escape(stats))
+			})
+		}()
+	}
+
+	if doc.options.warnDupKeys && !doc.options.rawInput && !doc.options.nullInput {
+		input := doc.input
+		go func() {
+			warning := duplicateKeysWarning(input)
+			if warning == "" {
+				return
+			}
+
+			app.QueueUpdateDraw(func() {
+				// The input may have changed in the meantime
+				if doc.input != input {
+					return
+				}
+
+				dupKeysWarning = warning
+				writeDupKeysWarning()
+			})
+		}()
+	}
+
+	// Load the filter again when the filter file or a module it includes
+	// changes on disk. The filter is run again even if only a module
+	// changed.
+	if doc.options.filterFile != "" {
+		watcher, err := newFilterWatcher(doc.options.filterFile, moduleSearchPath(doc.options.command))
+		if err != nil {
+			fmt.Fprintln(errorView, tview.Escape("Cannot watch the filter file: "+err.Error()))
+		} else {
+			go watcher.run(watchDelay, func(filter string) {
+				app.QueueUpdateDraw(func() {
+					if filterInput.GetText() == filter {
+						runFilter()
+					} else {
+						filterInput.SetText(filter)
+					}
+
+					flashStatus(tview.Escape("Reloaded " + doc.options.filterFile))
+				})
+			})
+		}
+	}
+
+	// Show the keys of the input in the autocompletion list, so that the
+	// input can be explored without typing. This only applies while the
+	// filter is ".". This runs in the background on a copy of the document.
+	explore := func(d Document) {
+		entries, ok := discoverKeys(d, "")
+		app.QueueUpdateDraw(func() {
+			if filterInput.GetText() != "." {
+				return
+			}
+
+			if !ok || len(entries) == 0 {
+				flashStatus("The input is not an object with keys to explore")
+				return
+			}
+
+			app.SetFocus(filterInput)
+			filterInput.Autocomplete()
+		})
+	}
+
+	// Generate formatted input and output with original filter
+	go app.QueueUpdateDraw(func() {
+		json5Input := doc.suggestJSON5()
+		if doc.options.autoRaw && !json5Input {
+			if message, ok := doc.detectRawInput(); ok {
+				flashStatus(autoRawNotice)
+				errorView.SetText(tview.Escape(autoRawNotice + ":\n" + message))
+			}
+		}
+
+		// The input may not be valid if the preprocessor failed, in
+		// which case the error of the preprocessor is shown instead
+		if err := renderInput(); err != nil && doc.loadErr == nil {
+			if json5Input {
+				doc.inputFile.fatal(json5Notice)
+			}
+
+			doc.inputFile.fatal(err)
+		}
+
+		if doc.loadErr != nil {
+			errorView.SetText(tview.Escape(doc.loadErr.Error()))
+		} else if doc.options.sortBy != "" && !doc.sortable() {
+			flashStatus(unsortedNotice)
+		}
+
+		if jumpToPlaceholder() {
+			// The filter was changed and is run again by the
+			// changed handler
+			return
+		}
+
+		// A shared filter may have been written for a newer jq
+		writeCompatWarning()
+		if err := renderOutput(); err != nil {
+			markFilter(true)
+		}
+
+		outputChanged()
+
+		if doc.options.explore && keyCompletion() {
+			go explore(doc)
+		}
+	})
+
+	// The filter area holds the filter input field and, when expanded, a
+	// wrapped view of the complete filter text
+	filterArea := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(filterInput, 1+borderSize, 0, true)
+
+	filterRow := tview.NewFlex().
+		AddItem(tview.NewBox(), 0, 1, false).
+		AddItem(filterArea, 0, 4, true).
+		AddItem(tview.NewBox(), 0, 1, false)
+
+	errorRow := tview.NewFlex().
+		AddItem(tview.NewBox(), 0, 1, false).
+		AddItem(errorView, 0, 4, false).
+		AddItem(tview.NewBox(), 0, 1, false)
+
+	// The filter field and the error pane take two thirds of the width of
+	// the screen, but at most the maximum filter width, and stay centered
+	fitFilterWidth := func(width int) {
+		if max := doc.options.filterWidth; max > 0 && width*4/6 > max {
+			filterRow.ResizeItem(filterArea, max, 0)
+			errorRow.ResizeItem(errorView, max, 0)
+		} else {
+			filterRow.ResizeItem(filterArea, 0, 4)
+			errorRow.ResizeItem(errorView, 0, 4)
+		}
+	}
+
+	grid := tview.NewGrid().
+		SetRows(0, 1+borderSize, 2+borderSize, 1).
+		SetColumns(0).
+		AddItem(panes, 0, 0, 1, 1, 0, 0, false).
+		AddItem(filterRow, 1, 0, 1, 1, 0, 0, true).
+		AddItem(errorRow, 2, 0, 1, 1, 0, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(tview.NewBox(), 0, 1, false).
+			AddItem(statusView, 0, 3, false).
+			AddItem(pathView, 0, 2, false).
+			AddItem(tview.NewBox(), 0, 1, false), 3, 0, 1, 1, 0, 0, false)
+
+	filterExpanded := false
+	errorExpanded := false
+	effectiveShown := false
+	updateRows := func() {
+		filterHeight := 1 + borderSize
+		if filterExpanded {
+			filterHeight += expandedFilterHeight
+		}
+
+		if effectiveShown {
+			filterHeight += expandedFilterHeight
+		}
+
+		// When expanded the error pane shares the available space
+		// equally with the input and output panes
+		errorHeight := 2 + borderSize
+		if errorExpanded {
+			errorHeight = 0
+		}
+
+		grid.SetRows(0, filterHeight, errorHeight, 1)
+	}
+
+	// Remember the layout for the next session
+	recordLayout := func() {
+		if !doc.options.noRestoreLayout {
+			config.Layout = Layout{
+				ExpandFilter: filterExpanded,
+				ExpandError:  errorExpanded,
+				RawInput:     doc.options.rawInputView,
+			}
+		}
+	}
+
+	toggleFilterExpanded := func() {
+		filterExpanded = !filterExpanded
+		if filterExpanded {
+			filterFull.SetText(doc.filter)
+			filterArea.AddItem(filterFull, 0, 1, false)
+		} else {
+			filterArea.RemoveItem(filterFull)
+		}
+
+		updateRows()
+		recordLayout()
+	}
+
+	toggleEffectiveFilter := func() {
+		effectiveShown = !effectiveShown
+		if effectiveShown {
+			effectiveView.SetText(doc.EffectiveFilter(true))
+			filterArea.AddItem(effectiveView, 0, 1, false)
+		} else {
+			filterArea.RemoveItem(effectiveView)
+		}
+
+		updateRows()
+	}
+
+	toggleErrorExpanded := func() {
+		errorExpanded = !errorExpanded
+		if errorExpanded {
+			app.SetFocus(errorView)
+		} else if errorView.HasFocus() {
+			app.SetFocus(filterInput)
+		}
+
+		updateRows()
+		recordLayout()
+	}
+
+	// Expanding the filter records the layout, so the rest of it is
+	// restored first
+	if !doc.options.noRestoreLayout {
+		// Unlike toggling, restoring does not move the focus
+		errorExpanded = config.Layout.ExpandError
+		doc.options.rawInputView = doc.options.rawInputView || config.Layout.RawInput
+		if config.Layout.ExpandFilter {
+			toggleFilterExpanded()
+		} else {
+			updateRows()
+		}
+	}
+
+	// An edit of the input that was not valid, which is edited again
+	// instead of the input so that it is not lost
+	inputDraft := ""
+
+	reloadInput := func() {
+		if len(doc.files) == 0 {
+			flashStatus("Cannot reload input read from standard input")
+			return
+		}
+
+		if err := doc.ReadFiles(doc.files); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		inputDraft = ""
+		dupKeysWarning = ""
+		resetKeys()
+
+		if err := doc.Preprocess(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		if err := doc.Merge(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		doc.options.page = 0
+		doc.options.unfolded = nil
+		updateFilterTitle()
+
+		if err := renderInput(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		runFilter()
+		flashStatus("Reloaded " + strings.Join(doc.files, ", "))
+	}
+
+	// Show the new input and run the filter on it
+	inputChanged := func(message string) {
+		dupKeysWarning = ""
+		resetKeys()
+		doc.options.page = 0
+		doc.options.unfolded = nil
+		updateFilterTitle()
+
+		if err := renderInput(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		runFilter()
+		flashStatus(message)
+	}
+
+	// Edit the input in $EDITOR and run the filter on the edited input
+	editInput := func() {
+		text := doc.input
+		if inputDraft != "" {
+			text = inputDraft
+		}
+
+		var edited string
+		var err error
+		app.Suspend(func() {
+			edited, err = editText(editorCommand(), text)
+		})
+		if err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+			return
+		}
+
+		if edited == doc.input {
+			inputDraft = ""
+			flashStatus("The input is unchanged")
+			return
+		}
+
+		if err := doc.EditInput(edited); err != nil {
+			inputDraft = edited
+			errorView.SetText(tview.Escape("The input is unchanged, press Alt-J to fix the edit:\n" + err.Error()))
+			return
+		}
+
+		inputDraft = ""
+		inputChanged("Edited the input, press Alt-U to undo the edits")
+	}
+
+	// Undo the edits of the input
+	revertInput := func() {
+		inputDraft = ""
+		if !doc.RevertInput() {
+			flashStatus("The input has not been edited")
+			return
+		}
+
+		inputChanged("Restored the original input")
+	}
+
+	// Switch the input pane between the input as it was read and the input
+	// as formatted by jq
+	toggleRawInput := func() {
+		doc.options.rawInputView = !doc.options.rawInputView
+		inputView.ScrollToBeginning()
+		if err := renderInput(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+		}
+
+		recordLayout()
+	}
 
-	inputView := tview.NewTextView()
-	inputView.SetDynamicColors(true).SetWrap(false).SetBorder(true)
+	// Show the next or previous page of results
+	turnPage := func(delta int) {
+		if doc.options.pageSize <= 0 {
+			flashStatus("Pages are not enabled, see -page-size")
+			return
+		}
 
-	outputView := tview.NewTextView()
-	outputView.SetDynamicColors(true).SetWrap(false).SetBorder(true)
+		page := doc.options.page + delta
+		if page < 0 || page >= pageCount {
+			return
+		}
 
-	errorView := tview.NewTextView()
-	errorView.SetDynamicColors(true).SetTitle("Error").SetBorder(true)
+		doc.options.page = page
+		doc.options.unfolded = nil
+		runFilter()
+	}
 
-	var filterHistory history
-	filterHistory.Init(doc.options.historyFile)
+	// Show the long string on the top line of the output pane in full, or
+	// shorten it again
+	toggleFold := func() {
+		if doc.options.foldStrings <= 0 {
+			flashStatus("Strings are not shortened, see -fold-strings")
+			return
+		}
 
-	var inputLineCount int
-	var outputLineCount int
+		if diffMode {
+			return
+		}
 
-	var mutex sync.Mutex
-	filterMap := make(map[string][]string)
-	filterInput := tview.NewInputField()
-	filterInput.
-		SetText(doc.filter).
-		SetFieldBackgroundColor(tcell.ColorDefault).
-		SetFieldTextColor(tcell.ColorDefault).
-		SetChangedFunc(func(text string) {
-			go app.QueueUpdateDraw(func() {
-				errorView.Clear()
-				doc.filter = text
-				outputView.ScrollToBeginning()
-				_, err := doc.WriteTo(outputView)
-				if err != nil {
-					filterInput.SetFieldTextColor(tcell.ColorMaroon)
-					exitErr, ok := err.(*exec.ExitError)
-					if ok {
-						fmt.Fprint(tview.ANSIWriter(errorView), string(exitErr.Stderr))
+		row, col := outputView.GetScrollOffset()
+		if doc.options.unfolded[row] {
+			delete(doc.options.unfolded, row)
+		} else {
+			if doc.options.unfolded == nil {
+				doc.options.unfolded = make(map[int]bool)
+			}
+
+			doc.options.unfolded[row] = true
+		}
+
+		runFilter()
+		outputView.ScrollTo(row, col)
+	}
+
+	toggleEscapeView := func() {
+		doc.options.escapeView = !doc.options.escapeView
+		runFilter()
+	}
+
+	// Apply the filter only to the value on the top line of the input pane,
+	// or to the whole input again if there is a selection already
+	toggleSelection := func() {
+		// The keys of the selection are not those of the whole input
+		if doc.options.selection != "" {
+			doc.options.selection = ""
+			doc.options.page = 0
+			doc.options.unfolded = nil
+			resetKeys()
+			flashStatus("Filtering the whole input")
+			runFilter()
+			return
+		}
+
+		row, _ := inputView.GetScrollOffset()
+		if row < 0 || row >= len(inputPaths) {
+			flashStatus("Selecting requires the formatted input")
+			return
+		}
+
+		path := inputPaths[row]
+		if path == "." || path == "" {
+			flashStatus("Scroll the input so that a value starts on the top line")
+			return
+		}
+
+		if strings.HasPrefix(path, "[") {
+			path = "." + path
+		}
+
+		// Make sure that the path selects valid JSON in the input
+		d := Document{input: doc.input, filter: ".", options: doc.options}
+		d.options.selection = path
+		d.options.compact = true
+		d.options.maxResults = 0
+		if _, err := d.WriteTo(io.Discard); err != nil {
+			flashStatus(tview.Escape("Cannot select " + path))
+			return
+		}
+
+		doc.options.selection = path
+		doc.options.page = 0
+		doc.options.unfolded = nil
+		resetKeys()
+		flashStatus(tview.Escape("Filtering " + path))
+		runFilter()
+	}
+
+	pages := tview.NewPages().AddPage("main", grid, true, true)
+
+	// Prompt the user for a line of text in a dialog over the main view.
+	// When the user presses Enter the accept function is called with the
+	// entered text. Escape cancels the prompt.
+	prompt := func(title, text string, accept func(text string)) {
+		focused := app.GetFocus()
+		field := tview.NewInputField().
+			SetText(text).
+			SetFieldBackgroundColor(tcell.ColorDefault).
+			SetFieldTextColor(tcell.ColorDefault)
+		field.SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				pages.RemovePage("prompt")
+				app.SetFocus(focused)
+				accept(field.GetText())
+			case tcell.KeyEscape:
+				pages.RemovePage("prompt")
+				app.SetFocus(focused)
+			}
+		})
+		field.SetTitle(title).SetBorder(true)
+		pages.AddPage("prompt", modal(field, 60, 3), true, true)
+		app.SetFocus(field)
+	}
+
+	// Ask for a note saying what the filter is for, then save the filter
+	// to the history with the note, exit, and write the output. The note
+	// of a filter in the history already can be changed, or removed by
+	// clearing it.
+	acceptWithNote := func() {
+		if doc.options.historyFile == "" {
+			flashStatus("The history is disabled, so the note cannot be saved")
+			return
+		}
+
+		if doc.options.noHistory {
+			flashStatus("The history is read-only (-no-history), so the note cannot be saved")
+			return
+		}
+
+		filter := filterInput.GetText()
+		prompt("Note for the history", filterHistory.Note(filter), func(note string) {
+			if err := filterHistory.AddNote(filter, note); err != nil {
+				flashStatus(tview.Escape(err.Error()))
+				return
+			}
+
+			acceptFilter()
+		})
+	}
+
+	// Show the variables in a list, with the values they are bound to.
+	// Enter edits the value of the selected variable, or adds a variable,
+	// and the filter is run again with the new value, which is kept for
+	// the rest of the session. Escape closes the list.
+	showVariables := func() {
+		focused := app.GetFocus()
+		closeList := func() {
+			pages.RemovePage("variables")
+			app.SetFocus(focused)
+		}
+
+		setValue := func(name, value string) {
+			vars, err := doc.options.vars.withValue(name, value)
+			if err != nil {
+				flashStatus(tview.Escape(err.Error()))
+				return
+			}
+
+			doc.options.vars = vars
+			runFilter()
+		}
+
+		list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+		for _, fv := range doc.options.vars {
+			name := fv.name
+			value, err := fv.valueText()
+			if err != nil {
+				list.AddItem(tview.Escape(fmt.Sprintf("$%s: %v", name, err)), "", 0, nil)
+				continue
+			}
+
+			list.AddItem(tview.Escape(fmt.Sprintf("$%s = %s", name, value)), "", 0, func() {
+				closeList()
+				prompt("$"+name, value, func(text string) {
+					setValue(name, text)
+				})
+			})
+		}
+
+		list.AddItem("New variable", "", 0, func() {
+			closeList()
+			prompt("Variable name", "", func(name string) {
+				name = strings.TrimPrefix(name, "$")
+				if name == "" {
+					return
+				}
+
+				if !variableNamePattern.MatchString(name) {
+					flashStatus(tview.Escape(fmt.Sprintf("Invalid variable name %q", name)))
+					return
+				}
+
+				prompt("$"+name, "null", func(text string) {
+					setValue(name, text)
+				})
+			})
+		})
+
+		list.SetDoneFunc(closeList)
+		list.SetTitle("Variables").SetBorder(true)
+		pages.AddPage("variables", modal(list, 60, list.GetItemCount()+2), true, true)
+		app.SetFocus(list)
+	}
+
+	// Show the aggregations in a list. Choosing one, by its first letter
+	// or with Enter, wraps the whole filter in it and moves the cursor to
+	// the first placeholder in the template, if any. Escape closes the
+	// list.
+	showAggregations := func() {
+		focused := app.GetFocus()
+		closeList := func() {
+			pages.RemovePage("aggregations")
+			app.SetFocus(focused)
+		}
+
+		list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+		for _, a := range aggregations {
+			a := a
+			list.AddItem(tview.Escape(fmt.Sprintf("%-10s %s", a.name, a.description)), "", rune(a.name[0]), func() {
+				pages.RemovePage("aggregations")
+				filterInput.SetText(a.wrap(filterInput.GetText()))
+				if !jumpToPlaceholder() {
+					moveFilterCursor(len(filterInput.GetText()))
+				}
+
+				app.SetFocus(filterInput)
+			})
+		}
+
+		list.SetDoneFunc(closeList)
+		list.SetTitle("Wrap the filter in").SetBorder(true)
+		pages.AddPage("aggregations", modal(list, 60, list.GetItemCount()+2), true, true)
+		app.SetFocus(list)
+	}
+
+	// Show the filters of the catalog in a searchable list with their
+	// descriptions and tags. Typing narrows the list, and a word starting
+	// with # keeps the filters with a tag starting with the word. Enter
+	// replaces the filter with the selected one, and Escape closes the
+	// list.
+	showCatalog := func() {
+		if doc.catalog == nil || len(doc.catalog.Filters) == 0 {
+			flashStatus("No catalog is loaded, see -catalog")
+			return
+		}
+
+		focused := app.GetFocus()
+		list := tview.NewList().SetHighlightFullLine(true)
+		search := tview.NewInputField().
+			SetLabel("> ").
+			SetFieldBackgroundColor(tcell.ColorDefault).
+			SetFieldTextColor(tcell.ColorDefault)
+
+		var matches []CatalogEntry
+		update := func(query string) {
+			list.Clear()
+			matches = doc.catalog.Search(query)
+			for _, e := range matches {
+				list.AddItem(tview.Escape(e.Name), tview.Escape(e.summary()), 0, nil)
+			}
+		}
+
+		closeCatalog := func() {
+			pages.RemovePage("catalog")
+			app.SetFocus(focused)
+		}
+
+		search.SetChangedFunc(update)
+		search.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyUp, tcell.KeyDown, tcell.KeyCtrlP, tcell.KeyCtrlN:
+				key := tcell.KeyDown
+				if event.Key() == tcell.KeyUp || event.Key() == tcell.KeyCtrlP {
+					key = tcell.KeyUp
+				}
+
+				list.InputHandler()(tcell.NewEventKey(key, 0, tcell.ModNone), nil)
+				return nil
+			case tcell.KeyEnter:
+				closeCatalog()
+				if i := list.GetCurrentItem(); i >= 0 && i < len(matches) {
+					filterInput.SetText(matches[i].Filter)
+					if !jumpToPlaceholder() {
+						moveFilterCursor(len(filterInput.GetText()))
+					}
+
+					app.SetFocus(filterInput)
+				}
+
+				return nil
+			case tcell.KeyEscape:
+				closeCatalog()
+				return nil
+			}
+
+			return event
+		})
+
+		update("")
+
+		picker := tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(search, 1, 0, true).
+			AddItem(list, 0, 1, false)
+		picker.SetTitle("Catalog (#tag narrows by tag)").SetBorder(true)
+		// The list scrolls through large catalogs
+		rows := len(matches)
+		if rows > 10 {
+			rows = 10
+		}
+
+		pages.AddPage("catalog", modal(picker, 70, 2*rows+3), true, true)
+		app.SetFocus(search)
+	}
+
+	// Show the filter history in a list, in the order in which it is
+	// suggested. Space marks the selected entry, e exports the marked
+	// entries (or the selected one) to a file of filters, and d deletes
+	// them from the history. Enter replaces the filter with the selected
+	// entry, and Escape closes the list.
+	showHistory := func() {
+		if len(filterHistory.Items) == 0 {
+			flashStatus("The history is empty")
+			return
+		}
+
+		focused := app.GetFocus()
+		closeList := func() {
+			pages.RemovePage("history")
+			app.SetFocus(focused)
+		}
+
+		list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+		list.SetTitle("History (Space marks, e exports, d deletes)").SetBorder(true)
+
+		var items []string
+		marked := map[string]bool{}
+		itemText := func(item string) string {
+			// tview would take [x] for a tag
+			box := "[ ] "
+			if marked[item] {
+				box = "[x] "
+			}
+
+			text := tview.Escape(box + item)
+
+			if note := filterHistory.Note(item); note != "" {
+				text += "  [::d]# " + tview.Escape(note) + "[::-]"
+			}
+
+			return text
+		}
+
+		update := func() {
+			current := list.GetCurrentItem()
+			list.Clear()
+			items = filterHistory.Ordered(doc.options.historyOrder)
+			for _, item := range items {
+				item := item
+				list.AddItem(itemText(item), "", 0, func() {
+					closeList()
+					filterInput.SetText(item)
+					app.SetFocus(filterInput)
+				})
+			}
+
+			list.SetCurrentItem(current)
+		}
+
+		// The marked entries in the order of the list, or the selected
+		// entry if none are marked
+		chosen := func() []string {
+			var entries []string
+			for _, item := range items {
+				if marked[item] {
+					entries = append(entries, item)
+				}
+			}
+
+			if len(entries) == 0 && len(items) > 0 {
+				entries = append(entries, items[list.GetCurrentItem()])
+			}
+
+			return entries
+		}
+
+		list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() != tcell.KeyRune || len(items) == 0 {
+				return event
+			}
+
+			switch event.Rune() {
+			case ' ':
+				i := list.GetCurrentItem()
+				marked[items[i]] = !marked[items[i]]
+				list.SetItemText(i, itemText(items[i]), "")
+				if i < len(items)-1 {
+					list.SetCurrentItem(i + 1)
+				}
+			case 'e':
+				entries := chosen()
+				prompt("Export filters to", "filters.txt", func(filename string) {
+					if filename == "" {
+						return
+					}
+
+					n, err := exportFilters(filename, entries)
+					if err != nil {
+						flashStatus(tview.Escape(err.Error()))
+						return
 					}
 
-					return
-				}
+					flashStatus(tview.Escape(fmt.Sprintf("Exported %d filters to %s", n, filename)))
+				})
+			case 'd':
+				entries := chosen()
+				if err := filterHistory.Remove(entries); err != nil {
+					flashStatus(tview.Escape(err.Error()))
+					return nil
+				}
+
+				for _, entry := range entries {
+					delete(marked, entry)
+				}
+
+				update()
+				message := fmt.Sprintf("Deleted %d history entries", len(entries))
+				if doc.options.noHistory {
+					message += " for this session (-no-history)"
+				}
+
+				flashStatus(message)
+			default:
+				return event
+			}
+
+			return nil
+		})
+
+		update()
+		list.SetDoneFunc(closeList)
+		pages.AddPage("history", modal(list, 80, 20), true, true)
+		app.SetFocus(list)
+	}
+
+	// Show text in a scrollable dialog over the main view. Escape or q
+	// closes the dialog.
+	showText := func(title, text string) {
+		focused := app.GetFocus()
+		tv := tview.NewTextView().SetText(text)
+		tv.SetDoneFunc(func(key tcell.Key) {
+			pages.RemovePage("text")
+			app.SetFocus(focused)
+		})
+		tv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Rune() == 'q' {
+				pages.RemovePage("text")
+				app.SetFocus(focused)
+				return nil
+			}
+
+			return event
+		})
+		tv.SetTitle(title).SetBorder(true)
+		pages.AddPage("text", modal(tv, 100, 20), true, true)
+		app.SetFocus(tv)
+	}
+
+	exportHTML := func() {
+		prompt("Export HTML to", "output.html", func(filename string) {
+			if filename == "" {
+				return
+			}
+
+			if err := doc.ExportHTML(filename); err != nil {
+				errorView.SetText(tview.Escape(err.Error()))
+				return
+			}
+
+			flashStatus("Exported output to " + filename)
+		})
+	}
+
+	// The remembered output of a slot is shown until its filter is run
+	// again
+	slots := newSlotSet()
+	switchSlot := func(slot int) {
+		if slot == slots.current {
+			return
+		}
+
+		filter, output := slots.Switch(slot, filterInput.GetText(), outputView.GetText(false))
+		filterSlotLabel = fmt.Sprintf("slot %d", slot+1)
+		updateFilterTitle()
+
+		// The output of the slot is shown until the filter runs again
+		outputView.SetText(output)
+		outputChanged()
+		filterInput.SetText(filter)
+	}
+
+	toggleDiff := func() {
+		diffMode = !diffMode
+		runFilter()
+	}
+
+	// Set a mark at the scroll position of the output pane (command m) or
+	// jump to a mark (command '). Jumping to the mark ' returns to the
+	// position before the last jump.
+	pendingMark := rune(0)
+	markOutput := func(command, name rune) {
+		if command == 'm' && !unicode.IsLetter(name) && !unicode.IsDigit(name) {
+			return
+		}
+
+		row, col := outputView.GetScrollOffset()
+		if command == 'm' {
+			marks[name] = outputMark{row, col}
+			flashStatus(tview.Escape(fmt.Sprintf("Marked %c", name)))
+			return
+		}
+
+		mark, ok := marks[name]
+		if !ok {
+			flashStatus(tview.Escape(fmt.Sprintf("Mark %c is not set", name)))
+			return
+		}
+
+		marks['\''] = outputMark{row, col}
+		outputView.ScrollTo(mark.row, mark.col)
+	}
+
+	toggleMarkEmpty := func() {
+		markEmpty = !markEmpty
+		runFilter()
+	}
+
+	// Choose whether a filter that fails clears the output pane or keeps
+	// the last good output
+	toggleClearOnError := func() {
+		doc.options.clearOnError = !doc.options.clearOnError
+		if doc.options.clearOnError {
+			flashStatus("A filter that fails clears the output")
+		} else {
+			flashStatus("A filter that fails keeps the last good output")
+		}
+
+		runFilter()
+	}
+
+	toggleOffsets := func() {
+		if !offsetsAvailable(doc.options) {
+			flashStatus("Byte offsets require JSON input and cannot be shown with -n or -pointer")
+			return
+		}
+
+		doc.options.offsets = !doc.options.offsets
+		updateOffsets()
+		if doc.options.offsets && inputPaths == nil {
+			flashStatus("Byte offsets are shown for the formatted input, see Alt-R")
+		}
+	}
+
+	// Tracing is assumed to be possible if the version of jq is unknown
+	toggleTrace := func() {
+		if version, ok := detectJQVersion(doc.options.command); ok && version.less(debugTraceVersion) {
+			flashStatus("Tracing requires jq 1.5 or later")
+			return
+		}
+
+		traceShown = !traceShown
+		if traceShown {
+			panes.AddItem(traceView, 0, 1, false)
+			updateTrace()
+		} else {
+			if traceView.HasFocus() {
+				app.SetFocus(filterInput)
+			}
+
+			panes.RemoveItem(traceView)
+		}
+	}
+
+	// Show or hide the paths changed by the filter, alongside the diff
+	// mode that compares the whole input and output
+	toggleChanges := func() {
+		changesShown = !changesShown
+		if changesShown {
+			panes.AddItem(changesView, 0, 1, false)
+			updateChanges()
+		} else {
+			if changesView.HasFocus() {
+				app.SetFocus(filterInput)
+			}
+
+			panes.RemoveItem(changesView)
+		}
+	}
+
+	// Pin the highlighted entry while the autocompletion list is open
+	toggleFavorite := func() {
+		text := filterInput.GetText()
+		if entry, ok := highlightedCompletion(); ok {
+			text = entry
+		}
+
+		if text == "" {
+			return
+		}
+
+		message := "Unpinned " + text
+		if config.ToggleFavorite(text) {
+			message = "Pinned " + text
+		}
+
+		if err := config.Save(); err != nil {
+			errorView.SetText(tview.Escape(err.Error()))
+		}
+
+		flashStatus(tview.Escape(message))
+	}
+
+	explain := func() {
+		showText("Filter explanation", explainFilter(doc.filter))
+	}
+
+	showManual := func() {
+		name, ok := tokenAt(filterInput.GetText(), filterCursor())
+		if !ok {
+			flashStatus("Move the cursor to a builtin to read its manual")
+			return
+		}
+
+		showText(tview.Escape("Manual: "+name), manualPage(name))
+	}
+
+	// Exit and run a new ijq on the output. This does not return.
+	relaunch := func() {
+		app.Stop()
+
+		// The new ijq reads the output from its standard input, and this
+		// one only waits for it to exit
+		doc.inputFile.Remove()
+		filterHistory.Add(doc.filter)
+		if err := config.Save(); err != nil {
+			log.Println(err)
+		}
+
+		code, err := doc.Relaunch(relaunchArgs(flag.CommandLine))
+		if err != nil {
+			doc.inputFile.fatal(err)
+		}
+
+		doc.inputFile.exit(code)
+	}
+
+	saveOutput := func() {
+		prompt("Save output to", "output.json", func(filename string) {
+			if filename == "" {
+				return
+			}
+
+			f, err := os.Create(filename)
+			if err != nil {
+				errorView.SetText(tview.Escape(err.Error()))
+				return
+			}
+
+			c := Document{input: doc.input, filter: doc.filter, options: doc.options}
+			c.options.setColor(false)
+			_, err = c.WriteTo(f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				errorView.SetText(tview.Escape(err.Error()))
+				return
+			}
+
+			flashStatus("Saved output to " + filename)
+		})
+	}
+
+	// Open the select() builder for the fields, inserting the expression at
+	// the byte offset
+	openSelectBuilder := func(offset int, fields []string, iterate bool) {
+		focused := app.GetFocus()
+		before := filterInput.GetText()[:offset]
+		closeBuilder := func() {
+			pages.RemovePage("builder")
+			app.SetFocus(focused)
+		}
 
-				outputLineCount = strings.Count(outputView.GetText(false), "\n")
-				filterInput.SetFieldTextColor(tcell.ColorDefault)
-			})
-		}).
-		SetDoneFunc(func(key tcell.Key) {
-			switch key {
-			case tcell.KeyEnter:
-				app.Stop()
-
-				fmt.Fprintln(os.Stderr, doc.filter)
-
-				// Enable or disable colors depending on if
-				// stdout is a tty, respecting options set by
-				// the user
-				isTty := term.IsTerminal(int(os.Stdout.Fd()))
-				if !isTty && !doc.options.forceColor {
-					doc.options.monochrome = true
-				} else if isTty && !doc.options.monochrome {
-					doc.options.forceColor = true
+		form := tview.NewForm()
+		if len(fields) > 0 {
+			form.AddDropDown("Field", fields, 0, nil)
+		} else {
+			form.AddInputField("Field", ".", 40, nil, nil)
+		}
+
+		form.AddDropDown("Operator", selectOperators, 0, nil).
+			AddInputField("Value", "", 40, nil, nil).
+			AddButton("Insert", func() {
+				var field string
+				switch item := form.GetFormItem(0).(type) {
+				case *tview.DropDown:
+					_, field = item.GetCurrentOption()
+				case *tview.InputField:
+					field = item.GetText()
 				}
 
-				filterHistory.Add(doc.filter)
+				_, op := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+				value := form.GetFormItem(2).(*tview.InputField).GetText()
 
-				if _, err := doc.WriteTo(os.Stdout); err != nil {
-					log.Fatalln(err)
-				}
-			}
-		}).
-		SetAutocompleteFunc(func(text string) []string {
-			if text == "" {
-				var entries []string
-				for _, item := range filterHistory.Items {
-					entries = append(entries, tview.Escape(item))
+				expr := buildSelect(field, op, value)
+				if iterate {
+					expr = ".[] | " + expr
 				}
-				return entries
-			}
-
-			if pos := strings.LastIndexByte(text, '.'); pos != -1 {
-				prefix := text[0:pos]
-
-				mutex.Lock()
-				defer mutex.Unlock()
-				candidates, ok := filterMap[prefix]
-				if ok {
-					cur := text[pos+1:]
-					var entries []string
-					for _, c := range candidates {
-						key := c[pos+1:]
-						if strings.HasPrefix(key, cur) {
-							entries = append(entries, c)
-						}
-					}
 
-					return entries
-				}
+				closeBuilder()
+				insertFilter(offset, selectInsertion(before, expr))
+				app.SetFocus(filterInput)
+			}).
+			AddButton("Cancel", closeBuilder).
+			SetCancelFunc(closeBuilder)
+		form.SetTitle("Insert select()").SetBorder(true)
+		pages.AddPage("builder", modal(form, 60, 11), true, true)
+		app.SetFocus(form)
+	}
 
-				go func() {
-					var filt string
-					if prefix != "" {
-						filt = prefix + "| keys"
-					} else {
-						filt = "keys"
-					}
+	// Build a select() expression from a field, an operator, and a value
+	// and insert it into the filter at the cursor. The fields are the keys
+	// of the values produced by the filter before the cursor, or of the
+	// elements of those values if they are arrays. The fields are found in
+	// the background, and the builder opens once they are found unless the
+	// filter changed in the meantime.
+	showSelectBuilder := func() {
+		offset := filterCursor()
+		text := filterInput.GetText()
+		if !keyCompletion() {
+			openSelectBuilder(offset, nil, false)
+			return
+		}
 
-					d := Document{
-						input:   doc.input,
-						filter:  "[" + filt + "] | unique | first",
-						options: doc.options,
-					}
+		context := selectContext(text[:offset])
+		d := doc
+		keyJobs.Run(func() {
+			prefix := context
+			entries, _ := discoverKeys(d, prefix)
+			iterate := false
+			if len(entries) == 0 {
+				if prefix == "" {
+					prefix = "."
+				}
 
-					var buf bytes.Buffer
-					_, err := d.WriteTo(&buf)
-					if err != nil {
-						return
-					}
+				prefix += "[]"
+				entries, _ = discoverKeys(d, prefix)
+				iterate = len(entries) > 0
+			}
 
-					var keys []string
-					if err := json.Unmarshal(buf.Bytes(), &keys); err != nil {
-						return
-					}
+			var fields []string
+			for _, entry := range entries {
+				fields = append(fields, strings.TrimPrefix(entry, prefix))
+			}
 
-					entries := keys[:0]
-					for _, k := range keys {
-						if strings.ContainsAny(k, SpecialChars) || !strings.Contains(Alphabet, string(k[0])) {
-							k = `"` + k + `"`
-						}
-						entries = append(entries, prefix+"."+k)
-					}
+			app.QueueUpdateDraw(func() {
+				if front, _ := pages.GetFrontPage(); front == "main" && filterInput.GetText() == text {
+					openSelectBuilder(offset, fields, iterate)
+				}
+			})
+		})
+	}
 
-					mutex.Lock()
-					filterMap[prefix] = entries
-					mutex.Unlock()
+	actions := []paletteAction{
+		{"Toggle diff mode", "Alt-D", toggleDiff},
+		{"Show or hide the paths changed by the filter", "Alt-Shift-D", toggleChanges},
+		{"Expand or collapse the filter area", "Alt-E", toggleFilterExpanded},
+		{"Expand or collapse the error pane", "Alt-Z", toggleErrorExpanded},
+		{"Show or hide the effective filter", "Alt-I", toggleEffectiveFilter},
+		{"Toggle raw input", "Alt-R", toggleRawInput},
+		{"Select the value at the top of the input", "Alt-S", toggleSelection},
+		{"Insert a select() condition", "Alt-W", showSelectBuilder},
+		{"Show the next page of results", "Alt-.", func() { turnPage(1) }},
+		{"Show the previous page of results", "Alt-,", func() { turnPage(-1) }},
+		{"Escape unprintable characters in the output", "Alt-O", toggleEscapeView},
+		{"Expand the long string at the top of the output", "Alt-L", toggleFold},
+		{"Say when the filter produces no results", "Alt-M", toggleMarkEmpty},
+		{"Clear the output when the filter fails", "Alt-Shift-E", toggleClearOnError},
+		{"Show or hide the trace of jq running the filter", "Alt-T", toggleTrace},
+		{"Show the byte offset of the value at the top of the input", "Alt-Y", toggleOffsets},
+		{"Edit the values of variables", "Alt-A", showVariables},
+		{"Manage and export the filter history", "Alt-K", showHistory},
+		{"Pick a filter from the catalog", "Alt-Q", showCatalog},
+		{"Wrap the filter in an aggregation", "Alt-G", showAggregations},
+		{"Pin or unpin the filter as a favorite", "Alt-P", toggleFavorite},
+		{"Explain the filter", "Alt-X", explain},
+		{"Show the manual of the builtin at the cursor", "F1", showManual},
+		{"Export output to HTML", "Alt-H", exportHTML},
+		{"Save output to a file", "", saveOutput},
+		{"Reload input", "F5", reloadInput},
+		{"Edit the input in $EDITOR", "Alt-J", editInput},
+		{"Undo the edits of the input", "Alt-U", revertInput},
+		{"Pause or resume live filtering", "Ctrl-Space", togglePause},
+		{"Run the filter", "Ctrl-R", func() { applyFilter(filterInput.GetText()) }},
+		{"Restart ijq on the output", "Alt-N", relaunch},
+		{"Accept the filter and exit", "Alt-Return", acceptFilter},
+		{"Accept the filter with a note in the history", "Alt-Shift-A", acceptWithNote},
+	}
 
-					filterInput.Autocomplete()
+	// Show a searchable list of actions. Typing narrows the list, Up and
+	// Down select an action, Enter runs it, and Escape closes the palette.
+	showPalette := func() {
+		focused := app.GetFocus()
+		list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+		search := tview.NewInputField().
+			SetLabel("> ").
+			SetFieldBackgroundColor(tcell.ColorDefault).
+			SetFieldTextColor(tcell.ColorDefault)
 
-					app.Draw()
-				}()
+		var matches []paletteAction
+		update := func(query string) {
+			list.Clear()
+			matches = filterActions(actions, query)
+			for _, a := range matches {
+				list.AddItem(fmt.Sprintf("%-44s %s", a.name, a.key), "", 0, nil)
 			}
-
-			return nil
-		}).
-		SetAutocompleteStyles(tcell.ColorBlack, tcell.StyleDefault, tcell.StyleDefault.Reverse(true)).
-		SetTitle("Filter").
-		SetBorder(true)
-
-	// Generate formatted input and output with original filter
-	go app.QueueUpdateDraw(func() {
-		d := Document{input: doc.input, filter: ".", options: doc.options}
-		if _, err := d.WriteTo(inputView); err != nil {
-			log.Fatalln(err)
 		}
 
-		outputView.ScrollToBeginning()
-		if _, err := doc.WriteTo(outputView); err != nil {
-			filterInput.SetFieldTextColor(tcell.ColorMaroon)
+		closePalette := func() {
+			pages.RemovePage("palette")
+			app.SetFocus(focused)
 		}
 
-		inputLineCount = strings.Count(inputView.GetText(false), "\n")
-		outputLineCount = strings.Count(outputView.GetText(false), "\n")
-	})
+		search.SetChangedFunc(update)
+		search.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyUp, tcell.KeyDown, tcell.KeyCtrlP, tcell.KeyCtrlN:
+				key := tcell.KeyDown
+				if event.Key() == tcell.KeyUp || event.Key() == tcell.KeyCtrlP {
+					key = tcell.KeyUp
+				}
 
-	grid := tview.NewGrid().
-		SetRows(0, 3, 4).
-		SetColumns(0).
-		AddItem(tview.NewFlex().
-			AddItem(inputView, 0, 1, false).
-			AddItem(outputView, 0, 1, false), 0, 0, 1, 1, 0, 0, false).
-		AddItem(tview.NewFlex().
-			AddItem(tview.NewBox(), 0, 1, false).
-			AddItem(filterInput, 0, 4, true).
-			AddItem(tview.NewBox(), 0, 1, false), 1, 0, 1, 1, 0, 0, true).
-		AddItem(tview.NewFlex().
-			AddItem(tview.NewBox(), 0, 1, false).
-			AddItem(errorView, 0, 4, false).
-			AddItem(tview.NewBox(), 0, 1, false), 2, 0, 1, 1, 0, 0, false)
+				list.InputHandler()(tcell.NewEventKey(key, 0, tcell.ModNone), nil)
+				return nil
+			case tcell.KeyEnter:
+				closePalette()
+				if i := list.GetCurrentItem(); i >= 0 && i < len(matches) {
+					matches[i].run()
+				}
+
+				return nil
+			case tcell.KeyEscape:
+				closePalette()
+				return nil
+			}
+
+			return event
+		})
+
+		update("")
+
+		palette := tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(search, 1, 0, true).
+			AddItem(list, 0, 1, false)
+		palette.SetTitle("Commands").SetBorder(true)
+		pages.AddPage("palette", modal(palette, 60, len(actions)+3), true, true)
+		app.SetFocus(search)
+	}
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Dialogs handle their own input
+		if front, _ := pages.GetFrontPage(); front != "main" {
+			return event
+		}
+
 		shift := event.Modifiers()&tcell.ModShift != 0
 		focused := app.GetFocus()
 
 		switch key := event.Key(); key {
+		case tcell.KeyEnter:
+			// Alt-Return accepts the filter whatever Enter does
+			if event.Modifiers()&tcell.ModAlt != 0 {
+				acceptFilter()
+				return nil
+			}
+		case tcell.KeyF1:
+			showManual()
+			return nil
+		case tcell.KeyF5:
+			reloadInput()
+			return nil
+		case tcell.KeyCtrlSpace:
+			togglePause()
+			return nil
+		case tcell.KeyCtrlR:
+			applyFilter(filterInput.GetText())
+			return nil
 		case tcell.KeyCtrlN:
 			return tcell.NewEventKey(tcell.KeyDown, ' ', tcell.ModNone)
 		case tcell.KeyCtrlP:
@@ -499,6 +4137,9 @@ func createApp(doc Document) *tview.Application {
 			if shift && filterInput.HasFocus() {
 				app.SetFocus(inputView)
 				return nil
+			} else if shift && errorView.HasFocus() {
+				app.SetFocus(filterInput)
+				return nil
 			}
 		case tcell.KeyLeft:
 			if shift {
@@ -511,7 +4152,10 @@ func createApp(doc Document) *tview.Application {
 				return nil
 			}
 		case tcell.KeyDown:
-			if shift {
+			if shift && filterInput.HasFocus() {
+				app.SetFocus(errorView)
+				return nil
+			} else if shift {
 				app.SetFocus(filterInput)
 				return nil
 			}
@@ -519,10 +4163,23 @@ func createApp(doc Document) *tview.Application {
 			if inputView.HasFocus() {
 				app.SetFocus(outputView)
 				return nil
-			} else if outputView.HasFocus() {
+			} else if outputView.HasFocus() && traceShown {
+				app.SetFocus(traceView)
+				return nil
+			} else if (outputView.HasFocus() || traceView.HasFocus()) && changesShown {
+				app.SetFocus(changesView)
+				return nil
+			} else if outputView.HasFocus() || traceView.HasFocus() || changesView.HasFocus() {
 				app.SetFocus(filterInput)
 				return nil
+			} else if errorView.HasFocus() {
+				app.SetFocus(inputView)
+				return nil
 			} else if filterInput.HasFocus() {
+				if jumpToPlaceholder() {
+					return nil
+				}
+
 				return tcell.NewEventKey(tcell.KeyDown, ' ', tcell.ModNone)
 			}
 		case tcell.KeyBacktab:
@@ -532,11 +4189,129 @@ func createApp(doc Document) *tview.Application {
 			} else if outputView.HasFocus() {
 				app.SetFocus(inputView)
 				return nil
+			} else if changesView.HasFocus() && traceShown {
+				app.SetFocus(traceView)
+				return nil
+			} else if errorView.HasFocus() || traceView.HasFocus() || changesView.HasFocus() {
+				app.SetFocus(outputView)
+				return nil
 			} else if filterInput.HasFocus() {
 				return tcell.NewEventKey(tcell.KeyUp, ' ', tcell.ModNone)
 			}
 		}
 
+		if event.Modifiers()&tcell.ModAlt != 0 {
+			switch event.Rune() {
+			case 'c':
+				showPalette()
+				return nil
+			case 'd':
+				toggleDiff()
+				return nil
+			case 'D':
+				toggleChanges()
+				return nil
+			case 'E':
+				toggleClearOnError()
+				return nil
+			case 'A':
+				acceptWithNote()
+				return nil
+			case 'j':
+				editInput()
+				return nil
+			case 'u':
+				revertInput()
+				return nil
+			case 'e':
+				toggleFilterExpanded()
+				return nil
+			case 'z':
+				toggleErrorExpanded()
+				return nil
+			case 'i':
+				toggleEffectiveFilter()
+				return nil
+			case 'h':
+				exportHTML()
+				return nil
+			case 'p':
+				toggleFavorite()
+				return nil
+			case 'r':
+				toggleRawInput()
+				return nil
+			case 's':
+				toggleSelection()
+				return nil
+			case 'w':
+				showSelectBuilder()
+				return nil
+			case 'o':
+				toggleEscapeView()
+				return nil
+			case 'l':
+				toggleFold()
+				return nil
+			case 'm':
+				toggleMarkEmpty()
+				return nil
+			case 't':
+				toggleTrace()
+				return nil
+			case 'y':
+				toggleOffsets()
+				return nil
+			case 'a':
+				showVariables()
+				return nil
+			case 'k':
+				showHistory()
+				return nil
+			case 'q':
+				showCatalog()
+				return nil
+			case 'g':
+				showAggregations()
+				return nil
+			case '.':
+				turnPage(1)
+				return nil
+			case ',':
+				turnPage(-1)
+				return nil
+			case 'n':
+				relaunch()
+			case 'x':
+				explain()
+				return nil
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				switchSlot(int(event.Rune() - '1'))
+				return nil
+			}
+
+			if text, ok := config.Insertion(event.Rune()); ok && filterInput.HasFocus() {
+				insertFilter(filterCursor(), text)
+				return nil
+			}
+		}
+
+		// The second key of a mark command names the mark
+		if command := pendingMark; command != 0 {
+			pendingMark = 0
+			if event.Key() == tcell.KeyRune {
+				markOutput(command, event.Rune())
+				return nil
+			}
+		}
+
+		if focused == outputView && event.Key() == tcell.KeyRune && event.Modifiers()&tcell.ModAlt == 0 {
+			if ru := event.Rune(); ru == 'm' || ru == '\'' {
+				pendingMark = ru
+				return nil
+			}
+		}
+
 		if tv, ok := focused.(*tview.TextView); ok {
 			switch ru := event.Rune(); ru {
 			case '0':
@@ -572,17 +4347,133 @@ func createApp(doc Document) *tview.Application {
 		return event
 	})
 
-	app.SetBeforeDrawFunc(func(_ tcell.Screen) bool {
-		updateScrollIndicator("Input", inputLineCount, inputView)
-		updateScrollIndicator("Output", outputLineCount, outputView)
+	app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		width, _ := screen.Size()
+		fitFilterWidth(width)
+
+		inputName := inputTitle
+		if doc.options.selection != "" {
+			inputName += " (selected " + tview.Escape(doc.options.selection) + ")"
+		}
+
+		if row, _ := inputView.GetScrollOffset(); doc.options.offsets && row >= 0 && row < len(inputPaths) && row < len(inputRoots) {
+			if text := offsets.describe(lineValueKey(inputRoots[row], inputPaths[row], doc.options.slurp)); text != "" {
+				inputName += " (" + tview.Escape(text) + ")"
+			}
+		}
+
+		updateScrollIndicator(inputName, inputLineCount, inputView)
+		updateScrollIndicator(outputTitle, outputLineCount, outputView)
+
+		if row, _ := outputView.GetScrollOffset(); row >= 0 && row < len(outputPaths) {
+			pathView.SetText(outputPaths[row])
+		} else {
+			pathView.Clear()
+		}
+
 		return false
 	})
 
-	app.SetRoot(grid, true).EnableMouse(true).SetFocus(grid)
+	app.SetRoot(pages, true).EnableMouse(true).SetFocus(grid)
 
 	return app
 }
 
+// Run the document filter without the interactive interface, writing the
+// result to standard output. Returns the exit status.
+func runBatch(doc Document) int {
+	if doc.loadErr != nil {
+		log.Println(doc.loadErr)
+		return 1
+	}
+
+	if doc.options.sortBy != "" && !doc.sortable() {
+		log.Println(unsortedNotice)
+	}
+
+	if doc.options.preserveOrder == PreserveOrderWarn && doc.reordersKeys(doc.filter) {
+		log.Println(reorderedNotice)
+	}
+
+	if warning := doc.CompatWarning(); warning != "" {
+		log.Println(warning)
+	}
+
+	if doc.suggestJSON5() {
+		log.Println(json5Notice)
+	} else if doc.options.autoRaw {
+		if message, ok := doc.detectRawInput(); ok {
+			log.Printf("%s:\n%s\n", autoRawNotice, message)
+		}
+	}
+
+	if doc.options.warnDupKeys && !doc.options.rawInput && !doc.options.nullInput {
+		if warning := duplicateKeysWarning(doc.input); warning != "" {
+			log.Println(warning)
+		}
+	}
+
+	doc.options.setColor(term.IsTerminal(int(os.Stdout.Fd())))
+	if _, err := doc.WriteTo(newEncodingWriter(os.Stdout, doc.options.encoding)); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Stderr.Write(exitErr.Stderr)
+			return exitErr.ExitCode()
+		}
+
+		log.Println(err)
+		return 1
+	}
+
+	if doc.schema != nil {
+		messages, err := doc.ValidateSchema(doc.schema)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		for _, message := range messages {
+			log.Println(message)
+		}
+
+		if len(messages) > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Write the filter accepted in the interactive interface to standard error
+// and its output to standard output and the output files
+func writeAccepted(doc Document) {
+	fmt.Fprintln(os.Stderr, doc.filter)
+
+	doc.options.setColor(term.IsTerminal(int(os.Stdout.Fd())))
+
+	hash := sha256.New()
+	w := newEncodingWriter(io.MultiWriter(os.Stdout, hash), doc.options.encoding)
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Fatalln(err)
+	}
+
+	if doc.options.auditFile != "" {
+		record := newAuditRecord(&doc, hash.Sum(nil), time.Now())
+		if err := appendAudit(doc.options.auditFile, record); err != nil {
+			log.Println(err)
+		}
+	}
+
+	for _, err := range doc.WriteOutputs(doc.options.outputs) {
+		log.Println(err)
+	}
+
+	if doc.options.scriptFile != "" {
+		if err := doc.WriteScript(doc.options.scriptFile); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
 func main() {
 	// Remove log prefix
 	log.SetFlags(0)
@@ -593,33 +4484,174 @@ func main() {
 		log.Fatalf("%s is not installed or could not be found: %s\n", options.command, err)
 	}
 
+	if options.noSideEffects {
+		version, ok := detectJQVersion(options.command)
+		options.endOfOptions = ok && !version.less(endOfOptionsVersion)
+	}
+
+	if options.sortBy != "" {
+		if err := checkSortPath(options.command, options.sortBy); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if options.trace {
+		if version, ok := detectJQVersion(options.command); ok && version.less(debugTraceVersion) {
+			log.Fatalln("-trace requires jq 1.5 or later")
+		}
+	}
+
 	doc := Document{filter: filter, options: options}
 
 	if !options.nullInput {
-		var in io.Reader = os.Stdin
 		if len(args) > 0 {
-			var files []io.Reader
-			for _, fname := range args {
-				f, err := os.Open(fname)
+			files := inputFiles(args, options.stdinMode, term.IsTerminal(int(os.Stdin.Fd())))
+			if contains(files, stdinName) {
+				stdin, err := io.ReadAll(os.Stdin)
 				if err != nil {
 					log.Fatalln(err)
 				}
 
-				defer f.Close()
+				doc.stdin = stdin
+			}
 
-				files = append(files, f)
+			if err := doc.ReadFiles(files); err != nil {
+				log.Fatalln(err)
 			}
+		} else if _, err := doc.ReadFrom(os.Stdin); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	doc.loadErr = doc.Preprocess()
+	if doc.loadErr == nil && !options.nullInput {
+		doc.loadErr = doc.Merge()
+	}
 
-			in = io.MultiReader(files...)
+	// The config is mostly about the interface, so without it a filter can
+	// still be run in batch mode or for a report
+	var config Config
+	if err := config.Load(options.configFile); err != nil {
+		if !options.batch && options.filterQueue == "" {
+			log.Fatalln(err)
 		}
 
-		if _, err := doc.ReadFrom(in); err != nil {
+		log.Printf("%v: ignoring the config\n", err)
+		config = Config{}
+	}
+
+	if doc.options.enterAction == "" {
+		doc.options.enterAction = config.Enter
+	}
+
+	if doc.options.catalogFile == "" {
+		doc.options.catalogFile = config.Catalog
+	}
+
+	doc.options.controlChars = config.ControlCharStyles()
+
+	if doc.options.catalogFile != "" {
+		catalog, err := loadCatalog(doc.options.catalogFile)
+		if err != nil {
 			log.Fatalln(err)
 		}
+
+		doc.catalog = catalog
 	}
 
-	app := createApp(doc)
-	if err := app.Run(); err != nil {
-		log.Fatalln(err)
+	// A filter given on the command line takes precedence over the filter
+	// in the document header, which takes precedence over the filter rules
+	// of the config and then the environment
+	if doc.filter == "" {
+		doc.filter = doc.headerFilter
+	}
+
+	if doc.filter == "" && !options.nullInput {
+		// Each line of raw input is a string
+		inputType := "string"
+		if !doc.options.rawInput {
+			inputType = firstValueType(doc.input)
+		}
+
+		doc.filter, _ = config.InitialFilter(doc.files, inputType)
+	}
+
+	if doc.filter == "" {
+		doc.filter = os.Getenv(FilterEnvVar)
+	}
+
+	if doc.filter == "" {
+		doc.filter = "."
+	}
+
+	if options.expectFile != "" {
+		expected, err := os.ReadFile(options.expectFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		doc.expected = doc.formatExpected(string(expected))
+	}
+
+	if options.schemaFile != "" {
+		schema, err := jsonschema.Compile(options.schemaFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		doc.schema = schema
+	}
+
+	if options.compatWarnings {
+		if version, ok := detectJQVersion(options.command); ok {
+			doc.jqVersion = &version
+		} else {
+			log.Println("cannot detect the version of jq, so -compat-warnings has no effect")
+		}
+	}
+
+	if options.filterQueue != "" {
+		os.Exit(runReport(doc))
+	}
+
+	if options.batch {
+		os.Exit(runBatch(doc))
+	}
+
+	// The filter runs on every change, so a large input is written to a
+	// file once instead of to jq's standard input on every run
+	doc.inputFile = newInputFile(options.inputFileSize)
+
+	if options.repl {
+		doc.inputFile.exit(runREPL(doc))
+	}
+
+	layout := config.Layout
+	var accepted *Document
+	app := createApp(doc, &config, func(d Document) {
+		accepted = &d
+	})
+
+	// The interface fails to start without a terminal it can draw on, in
+	// which case the REPL still works
+	err := app.Run()
+	if err != nil {
+		log.Printf("cannot start the interface: %v: falling back to -repl\n", err)
+		doc.inputFile.exit(runREPL(doc))
+	}
+
+	doc.inputFile.Remove()
+
+	if accepted != nil {
+		// The output is written once, so the file is not worth writing
+		// again
+		accepted.inputFile = nil
+		writeAccepted(*accepted)
+	}
+
+	if config.Layout != layout {
+		if err := config.Save(); err != nil {
+			doc.inputFile.fatal(err)
+		}
 	}
 }
@@ -20,10 +20,15 @@ package main
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,10 +70,24 @@ func TestOptionsToSlice(t *testing.T) {
 	opt.forceColor = false
 	assert.NotContains(t, opt.ToSlice(), "-C")
 
+	opt.batch = true
+	assert.Empty(t, opt.ToSlice())
+	opt.batch = false
+
 	opt.sortKeys = true
 	assert.Contains(t, opt.ToSlice(), "-S")
 	opt.sortKeys = false
 	assert.NotContains(t, opt.ToSlice(), "-S")
+
+	opt.unbuffered = true
+	assert.Contains(t, opt.ToSlice(), "--unbuffered")
+	opt.unbuffered = false
+	assert.NotContains(t, opt.ToSlice(), "--unbuffered")
+
+	opt.seq = true
+	assert.Contains(t, opt.ToSlice(), "--seq")
+	opt.seq = false
+	assert.NotContains(t, opt.ToSlice(), "--seq")
 }
 
 func TestDocumentReadFrom(t *testing.T) {
@@ -82,6 +101,195 @@ func TestDocumentReadFrom(t *testing.T) {
 	assert.Equal(t, len(testMsg), int(readCount))
 }
 
+func TestDocumentReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	assert.NoError(t, os.WriteFile(first, []byte("1\n"), 0644))
+	assert.NoError(t, os.WriteFile(second, []byte("2\n"), 0644))
+
+	doc := &Document{}
+	assert.NoError(t, doc.ReadFiles([]string{first, second}))
+	assert.Equal(t, "1\n2\n", doc.input)
+	assert.Equal(t, []string{first, second}, doc.files)
+
+	assert.Error(t, doc.ReadFiles([]string{filepath.Join(dir, "missing.json")}))
+}
+
+func TestIsFilterFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "filter.jq")
+	assert.NoError(t, os.WriteFile(file, []byte(".a"), 0644))
+
+	// Only a file that does not compile as a filter is a filter file
+	assert.True(t, isFilterFile("./testdata/compileerror", file, nil))
+	assert.False(t, isFilterFile("./testdata/cat", file, nil))
+	assert.False(t, isFilterFile("./testdata/caterror", file, nil))
+	assert.False(t, isFilterFile("./testdata/compileerror", file+".missing", nil))
+	assert.False(t, isFilterFile("./testdata/compileerror", filepath.Dir(file), nil))
+}
+
+func TestInputFiles(t *testing.T) {
+	files := []string{"a.json", "b.json"}
+
+	// Standard input is ignored by default
+	assert.Equal(t, files, inputFiles(files, StdinIgnore, false))
+	assert.Equal(t, []string{"-", "a.json", "b.json"}, inputFiles(files, StdinBefore, false))
+	assert.Equal(t, []string{"a.json", "b.json", "-"}, inputFiles(files, StdinAfter, false))
+	assert.Equal(t, []string{"a.json", "b.json"}, files)
+
+	// A terminal is never read as input
+	assert.Equal(t, files, inputFiles(files, StdinBefore, true))
+
+	// Without files, standard input is the input whatever the mode
+	assert.Empty(t, inputFiles(nil, StdinAfter, false))
+
+	// Standard input is read only once if it is already given as "-"
+	assert.Equal(t, []string{"a.json", "-"}, inputFiles([]string{"a.json", "-"}, StdinBefore, false))
+}
+
+func TestDocumentReadFilesStdin(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.json")
+	assert.NoError(t, os.WriteFile(file, []byte("1"), 0644))
+
+	doc := &Document{stdin: []byte("#!ijq .a\n2")}
+	assert.NoError(t, doc.ReadFiles([]string{"-", file}))
+	assert.Equal(t, "2\n1", doc.input)
+	assert.Equal(t, ".a", doc.headerFilter)
+
+	assert.NoError(t, doc.ReadFiles([]string{file, "-"}))
+	assert.Equal(t, "1\n#!ijq .a\n2", doc.input)
+	assert.Equal(t, "", doc.headerFilter)
+
+	doc.options.joinMode = JoinModeArray
+	doc.stdin = []byte("2\n")
+	assert.NoError(t, doc.ReadFiles([]string{file, "-"}))
+	assert.Equal(t, "[1,2]\n", doc.input)
+}
+
+func TestDocumentReadFilesJoinMode(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	stream := filepath.Join(dir, "stream.json")
+	assert.NoError(t, os.WriteFile(first, []byte(`{"a":1}`), 0644))
+	assert.NoError(t, os.WriteFile(second, []byte("[2]\n"), 0644))
+	assert.NoError(t, os.WriteFile(stream, []byte("1 2"), 0644))
+
+	doc := &Document{options: Options{joinMode: JoinModeStream}}
+	assert.NoError(t, doc.ReadFiles([]string{first, second}))
+	assert.Equal(t, "{\"a\":1}\n[2]\n", doc.input)
+
+	doc = &Document{options: Options{joinMode: JoinModeArray}}
+	assert.NoError(t, doc.ReadFiles([]string{first, second}))
+	assert.Equal(t, "[{\"a\":1},[2]]\n", doc.input)
+	assert.Equal(t, []string{first, second}, doc.files)
+
+	assert.Error(t, doc.ReadFiles([]string{first, stream}))
+}
+
+func TestDocumentTitle(t *testing.T) {
+	doc := &Document{}
+	assert.Empty(t, doc.Title())
+
+	doc.files = []string{"a.json", "b.json"}
+	assert.Equal(t, "a.json, b.json", doc.Title())
+
+	doc.options.title = "prod"
+	assert.Equal(t, "prod", doc.Title())
+}
+
+func TestDocumentPreprocess(t *testing.T) {
+	doc := &Document{input: "{a: 1}"}
+	assert.NoError(t, doc.Preprocess())
+	assert.Equal(t, "{a: 1}", doc.input)
+
+	doc.options.preCommand = "sed 's/a/\"a\"/'"
+	assert.NoError(t, doc.Preprocess())
+	assert.Equal(t, "{\"a\": 1}", doc.input)
+
+	doc.options.preCommand = "echo oops >&2; exit 3"
+	err := doc.Preprocess()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "oops")
+	assert.Equal(t, "{\"a\": 1}", doc.input)
+}
+
+func TestSplitHeader(t *testing.T) {
+	filter, rest, ok := splitHeader([]byte("#!ijq .a | .b\n{\"a\": 1}\n"))
+	assert.True(t, ok)
+	assert.Equal(t, ".a | .b", filter)
+	assert.Equal(t, "{\"a\": 1}\n", string(rest))
+
+	filter, rest, ok = splitHeader([]byte("#!ijq"))
+	assert.True(t, ok)
+	assert.Empty(t, filter)
+	assert.Empty(t, rest)
+
+	_, rest, ok = splitHeader([]byte("#!ijqx .a\n1"))
+	assert.False(t, ok)
+	assert.Equal(t, "#!ijqx .a\n1", string(rest))
+
+	_, rest, ok = splitHeader([]byte("{}"))
+	assert.False(t, ok)
+	assert.Equal(t, "{}", string(rest))
+}
+
+func TestDocumentReadHeader(t *testing.T) {
+	doc := &Document{}
+	_, err := doc.ReadFrom(strings.NewReader("#!ijq .a\n{\"a\": 1}"))
+	assert.NoError(t, err)
+	assert.Equal(t, ".a", doc.headerFilter)
+	assert.Equal(t, "{\"a\": 1}", doc.input)
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.ijq")
+	second := filepath.Join(dir, "second.json")
+	assert.NoError(t, os.WriteFile(first, []byte("#!ijq .[0]\n1\n"), 0644))
+	assert.NoError(t, os.WriteFile(second, []byte("2\n"), 0644))
+
+	doc = &Document{options: Options{joinMode: JoinModeArray}}
+	assert.NoError(t, doc.ReadFiles([]string{first, second}))
+	assert.Equal(t, ".[0]", doc.headerFilter)
+	assert.Equal(t, "[1,2]\n", doc.input)
+}
+
+func TestDocumentDetectRawInput(t *testing.T) {
+	doc := &Document{input: "{}", options: Options{command: "./testdata/cat"}}
+	_, ok := doc.detectRawInput()
+	assert.False(t, ok)
+	assert.False(t, doc.options.rawInput)
+
+	// The message is the output of caterror
+	doc = &Document{input: "hello\n", options: Options{command: "./testdata/caterror"}}
+	message, ok := doc.detectRawInput()
+	assert.True(t, ok)
+	assert.Equal(t, "hello", message)
+	assert.True(t, doc.options.rawInput)
+
+	// Raw input is only enabled once
+	_, ok = doc.detectRawInput()
+	assert.False(t, ok)
+
+	doc = &Document{options: Options{command: "./testdata/caterror", nullInput: true}}
+	_, ok = doc.detectRawInput()
+	assert.False(t, ok)
+}
+
+func TestDocumentKeys(t *testing.T) {
+	doc := &Document{input: `{"items": [{"id": 1, "a-b": 2}]}`, options: Options{command: "jq"}}
+	keys, err := doc.Keys(".items[]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`.items[]."a-b"`, ".items[].id"}, keys)
+
+	// The keys are read from plain JSON whatever the output options are
+	doc.options.seq = true
+	doc.options.forceColor = true
+	keys, err = doc.Keys("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".items"}, keys)
+}
+
 func TestDocumentWriteTo(t *testing.T) {
 	testMsg := "hello world"
 	testReader := strings.NewReader(testMsg)
@@ -133,3 +341,307 @@ func TestDocumentExecError(t *testing.T) {
 
 	assert.Empty(t, buffer.String())
 }
+
+func TestDocumentPreviewFilter(t *testing.T) {
+	doc := &Document{filter: ".[] # comment"}
+	assert.Equal(t, ".[] # comment", doc.previewFilter())
+
+	doc.options.maxResults = 10
+	assert.Equal(t, "limit(10; (.[] # comment\n))", doc.previewFilter())
+
+	doc.options.softErrors = true
+	assert.Equal(t, "limit(10; (try (.[] # comment\n) catch (\"<error: \\(.)>\")\n))", doc.previewFilter())
+	assert.Equal(t, ".[] # comment", doc.EffectiveFilter(false))
+}
+
+func TestDocumentSample(t *testing.T) {
+	doc := &Document{filter: ".[]", options: Options{sample: 100, maxResults: 10}}
+	assert.Equal(t, "limit(10; ((if type == \"array\" then .[:100] else . end) | (.[]\n)\n))", doc.previewFilter())
+
+	// The accepted output is computed from the whole input
+	assert.Equal(t, ".[]", doc.EffectiveFilter(false))
+
+	doc.options.sample = 0
+	assert.Equal(t, ".[]", doc.sampledFilter())
+}
+
+func TestOptionsInputViewOptions(t *testing.T) {
+	opts := Options{
+		command:      "jq",
+		slurp:        true,
+		compact:      true,
+		sortKeys:     true,
+		indentString: "\t",
+		pageSize:     10,
+		maxResults:   100,
+		numberValues: true,
+		selection:    ".a",
+		sample:       5,
+		inputView:    InputViewOutput,
+	}
+
+	view := opts.inputViewOptions()
+	assert.False(t, view.compact)
+	assert.True(t, view.sortKeys)
+	assert.Equal(t, "\t", view.indentString)
+	assert.Equal(t, 10, view.pageSize)
+	assert.False(t, view.numberValues)
+	assert.Empty(t, view.selection)
+	assert.Zero(t, view.sample)
+
+	opts.inputView = InputViewPlain
+	assert.Equal(t, Options{command: "jq", slurp: true, maxResults: 100}, opts.inputViewOptions())
+
+	opts.inputView = InputViewSorted
+	assert.Equal(t, Options{command: "jq", slurp: true, maxResults: 100, sortKeys: true}, opts.inputViewOptions())
+}
+
+func TestDocumentPages(t *testing.T) {
+	doc := &Document{filter: ".[]", options: Options{pageSize: 10, page: 2}}
+	assert.Equal(t, "[limit(30; (.[]\n))] | .[20:][]", doc.previewFilter())
+
+	doc.options.maxResults = 25
+	assert.Equal(t, "[limit(30; (limit(25; (.[]\n))\n))] | .[20:][]", doc.previewFilter())
+	assert.Equal(t, "limit(25; (.[]\n))", doc.limitedFilter())
+
+	assert.Equal(t, 3, countPages(7, 3))
+	assert.Equal(t, 1, countPages(0, 3))
+	assert.Equal(t, 1, countPages(7, 0))
+
+	// The results are counted across all pages, up to -max-results
+	doc = &Document{input: "[1, 2, 3, 4, 5, 6, 7]", filter: ".[]", options: Options{command: "jq", pageSize: 3, page: 1}}
+	count, ok := doc.ResultCount()
+	assert.True(t, ok)
+	assert.Equal(t, 7, count)
+
+	doc.options.maxResults = 5
+	count, ok = doc.ResultCount()
+	assert.True(t, ok)
+	assert.Equal(t, 5, count)
+
+	doc.options.command = "./testdata/caterror"
+	_, ok = doc.ResultCount()
+	assert.False(t, ok)
+}
+
+func TestDocumentEffectiveFilter(t *testing.T) {
+	doc := &Document{filter: ".[]"}
+	assert.Equal(t, ".[]", doc.EffectiveFilter(false))
+	assert.Equal(t, ".[]", doc.EffectiveFilter(true))
+
+	doc.options = Options{maxResults: 3, prefix: ".a", selection: ".b"}
+	assert.Equal(t, ".a | (.b | (.[]\n)\n)", doc.EffectiveFilter(false))
+	assert.Equal(t, ".a | (.b | (limit(3; (.[]\n))\n)\n)", doc.EffectiveFilter(true))
+}
+
+func TestDocumentWriteToPrefix(t *testing.T) {
+	doc := &Document{
+		input:  "hello world",
+		filter: "-",
+		options: Options{
+			command: "echo",
+			prefix:  ".foo",
+		},
+	}
+
+	buffer := bytes.Buffer{}
+	_, err := doc.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, ".foo | (-\n)\n", buffer.String())
+}
+
+func TestDocumentWriteToSelection(t *testing.T) {
+	doc := &Document{
+		input:  "hello world",
+		filter: "-",
+		options: Options{
+			command:   "echo",
+			prefix:    ".foo",
+			selection: ".bar[0]",
+		},
+	}
+
+	buffer := bytes.Buffer{}
+	_, err := doc.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, ".foo | (.bar[0] | (-\n)\n)\n", buffer.String())
+}
+
+func TestDocumentWriteToNoSideEffects(t *testing.T) {
+	doc := &Document{
+		input:  "hello world",
+		filter: "input",
+		options: Options{
+			command:       "echo",
+			noSideEffects: true,
+		},
+	}
+
+	buffer := bytes.Buffer{}
+	_, err := doc.WriteTo(&buffer)
+	assert.Error(t, err)
+	assert.Empty(t, buffer.String())
+
+	doc.filter = "."
+	doc.options.endOfOptions = true
+	_, err = doc.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "-- .\n", buffer.String())
+}
+
+func TestDocumentWriteToEscapeOutput(t *testing.T) {
+	doc := &Document{
+		input:   "a\x00\xff\n",
+		options: Options{command: "./testdata/cat"},
+	}
+
+	buffer := bytes.Buffer{}
+	_, err := doc.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\x00\xff\n", buffer.String())
+
+	doc.options.escapeOutput = true
+	buffer.Reset()
+	_, err = doc.WriteTo(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\\x00\\xff\n", buffer.String())
+}
+
+func TestOptionsPreview(t *testing.T) {
+	// The panes are pretty-printed and colored whatever the output
+	// options are
+	opts := Options{command: "jq", compact: true, rawOutput: true, monochrome: true}
+	assert.Equal(t, Options{command: "jq", forceColor: true}, opts.preview())
+}
+
+func TestDocumentWriteToUnbuffered(t *testing.T) {
+	progress := 0
+	doc := &Document{
+		input:    "1\n2\n",
+		options:  Options{command: "./testdata/cat", unbuffered: true},
+		progress: func() { progress++ },
+	}
+
+	tv := tview.NewTextView()
+	_, err := doc.WriteTo(tv)
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n2\n", tv.GetText(true))
+	assert.Equal(t, 1, progress)
+
+	// As with buffered output, the error holds the output
+	doc.options.command = "./testdata/caterror"
+	_, err = doc.WriteTo(tv)
+	exitErr, ok := err.(*exec.ExitError)
+	assert.True(t, ok)
+	assert.Equal(t, "1\n2\n", string(exitErr.Stderr))
+
+	// The output shown while jq runs is escaped like the whole output
+	var shown []string
+	doc = &Document{
+		input:   "a\x00\n",
+		options: Options{command: "./testdata/cat", unbuffered: true, escapeView: true},
+	}
+	doc.progress = func() { shown = append(shown, tv.GetText(true)) }
+	_, err = doc.WriteTo(tv)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a\\x00\n"}, shown)
+	assert.Equal(t, "a\\x00\n", tv.GetText(true))
+}
+
+func TestNumberValues(t *testing.T) {
+	out := []byte("1\n{\n  \"a\": 2\n\x1b[1;39m}\x1b[0m\n")
+	expected := "\x1b[2m# 0\x1b[0m\n1\n\x1b[2m# 1\x1b[0m\n{\n  \"a\": 2\n\x1b[1;39m}\x1b[0m\n"
+	assert.Equal(t, expected, string(numberValues(out, 0)))
+
+	expected = "\x1b[2m# 10\x1b[0m\n1\n"
+	assert.Equal(t, expected, string(numberValues([]byte("1\n"), 10)))
+
+	assert.Empty(t, numberValues(nil, 0))
+}
+
+func TestOptionsSetColor(t *testing.T) {
+	opt := Options{}
+	opt.setColor(false)
+	assert.True(t, opt.monochrome)
+	assert.False(t, opt.forceColor)
+
+	opt = Options{}
+	opt.setColor(true)
+	assert.False(t, opt.monochrome)
+	assert.True(t, opt.forceColor)
+
+	opt = Options{forceColor: true}
+	opt.setColor(false)
+	assert.False(t, opt.monochrome)
+
+	opt = Options{monochrome: true}
+	opt.setColor(true)
+	assert.False(t, opt.forceColor)
+}
+
+// Run the interactive interface for the document on a simulated screen and
+// send it the keys. Returns the document that was accepted, or nil if the
+// interface exited without accepting a filter. The keys are queued at once,
+// like keys typed faster than the filter is rendered.
+func simulateApp(t *testing.T, doc Document, config *Config, keys ...*tcell.EventKey) *Document {
+	var accepted *Document
+	app := createApp(doc, config, func(d Document) {
+		accepted = &d
+	})
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	app.SetScreen(screen)
+	done := make(chan error)
+	go func() {
+		done <- app.Run()
+	}()
+
+	for _, key := range keys {
+		screen.InjectKey(key.Key(), key.Rune(), key.Modifiers())
+	}
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the interactive interface did not exit")
+	}
+
+	return accepted
+}
+
+func TestCreateAppAccept(t *testing.T) {
+	doc := Document{input: "{\"ab\": 1}\n", filter: ".", options: Options{command: "./testdata/cat"}}
+
+	// The filter is accepted as it was typed, even before it is rendered
+	accepted := simulateApp(t, doc, &Config{},
+		tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+	)
+	if assert.NotNil(t, accepted) {
+		assert.Equal(t, ".ab", accepted.filter)
+	}
+
+	accepted = simulateApp(t, doc, &Config{},
+		tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModAlt),
+	)
+	if assert.NotNil(t, accepted) {
+		assert.Equal(t, ".a", accepted.filter)
+	}
+}
+
+func TestCreateAppQuit(t *testing.T) {
+	doc := Document{input: "{\"a\": 1}\n", filter: ".", options: Options{command: "./testdata/cat"}}
+	accepted := simulateApp(t, doc, &Config{}, tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl))
+	assert.Nil(t, accepted)
+}
+
+func TestCreateAppRestoreLayout(t *testing.T) {
+	doc := Document{input: "{}\n", filter: ".", options: Options{command: "./testdata/cat"}}
+	layout := Layout{ExpandFilter: true, ExpandError: true, RawInput: true}
+	config := Config{Layout: layout}
+	simulateApp(t, doc, &config, tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl))
+	assert.Equal(t, layout, config.Layout)
+}
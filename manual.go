@@ -0,0 +1,342 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An example of a builtin, as in the jq manual: the program run on the
+// input produces the outputs
+type manualExample struct {
+	program string
+	input   string
+	outputs []string
+}
+
+// The manual page of a builtin. The usage lists the forms of the builtin
+// with their arguments.
+type manualEntry struct {
+	usage    string
+	text     string
+	examples []manualExample
+}
+
+// A subset of the jq manual describing the most common builtins
+var builtinManual = map[string]manualEntry{
+	"add": {"add", "Takes an array and adds its elements together: numbers are summed, strings and arrays are concatenated, and objects are merged. An empty array gives null.", []manualExample{
+		{"add", `["a","b","c"]`, []string{`"abc"`}},
+		{"add", `[1,2,3]`, []string{`6`}},
+		{"add", `[]`, []string{`null`}},
+	}},
+	"all": {"all, all(condition), all(generator; condition)", "Outputs true if all of the elements of the input array are true, or if the condition is true for all of them.", []manualExample{
+		{"all", `[true,false]`, []string{`false`}},
+		{"all(. > 0)", `[1,2]`, []string{`true`}},
+	}},
+	"any": {"any, any(condition), any(generator; condition)", "Outputs true if any of the elements of the input array is true, or if the condition is true for any of them.", []manualExample{
+		{"any", `[true,false]`, []string{`true`}},
+		{"any(. > 1)", `[1,2]`, []string{`true`}},
+	}},
+	"ascii_downcase": {"ascii_downcase", "Converts the alphabetic ASCII characters of the input string to lowercase.", []manualExample{
+		{"ascii_downcase", `"useful but not for é"`, []string{`"useful but not for é"`}},
+	}},
+	"ascii_upcase": {"ascii_upcase", "Converts the alphabetic ASCII characters of the input string to uppercase.", []manualExample{
+		{"ascii_upcase", `"useful but not for é"`, []string{`"USEFUL BUT NOT FOR é"`}},
+	}},
+	"capture": {"capture(regex), capture(regex; flags)", "Collects the named capture groups of a regular expression match in an object, with the names as keys and the matched strings as values.", []manualExample{
+		{`capture("(?<a>[a-z]+)-(?<n>[0-9]+)")`, `"xyzzy-14"`, []string{`{"a":"xyzzy","n":"14"}`}},
+	}},
+	"contains": {"contains(element)", "Outputs true if the argument is completely contained within the input. A string is contained in another if it is a substring, an array if all of its elements are contained in some element of the input, and an object if all of its values are contained in the values of the same keys.", []manualExample{
+		{`contains("bar")`, `"foobar"`, []string{`true`}},
+		{`contains(["baz", "bar"])`, `["foobar", "foobaz", "blarp"]`, []string{`true`}},
+		{`contains({foo: 12, bar: [{barp: 12}]})`, `{"foo": 12, "bar":[1,2,{"barp":12, "blip":13}]}`, []string{`true`}},
+	}},
+	"del": {"del(path_expression)", "Removes the values at the paths of the expression from the input.", []manualExample{
+		{"del(.foo)", `{"foo": 42, "bar": 9001, "baz": 42}`, []string{`{"bar":9001,"baz":42}`}},
+		{"del(.[1, 2])", `["foo", "bar", "baz"]`, []string{`["foo"]`}},
+	}},
+	"empty": {"empty", "Produces no output at all, not even null.", []manualExample{
+		{"1, empty, 2", `null`, []string{`1`, `2`}},
+		{"[1,2,empty,3]", `null`, []string{`[1,2,3]`}},
+	}},
+	"endswith": {"endswith(str)", "Outputs true if the input string ends with the given string.", []manualExample{
+		{`[.[] | endswith("foo")]`, `["foobar", "barfoo"]`, []string{`[false,true]`}},
+	}},
+	"error": {"error, error(message)", "Raises an error with the input, or with the given message. The error can be caught with try and catch.", []manualExample{
+		{`try error("some exception") catch .`, `true`, []string{`"some exception"`}},
+	}},
+	"explode": {"explode", "Converts the input string into an array of the numbers of its codepoints.", []manualExample{
+		{"explode", `"foobar"`, []string{`[102,111,111,98,97,114]`}},
+	}},
+	"first": {"first, first(expr)", "Outputs the first element of the input array, or the first output of the expression.", []manualExample{
+		{"first", `[1,2,3]`, []string{`1`}},
+		{"first(range(10; 0; -1))", `null`, []string{`10`}},
+	}},
+	"flatten": {"flatten, flatten(depth)", "Flattens nested arrays into a single array, or only to the given depth.", []manualExample{
+		{"flatten", `[1, [2], [[3]]]`, []string{`[1,2,3]`}},
+		{"flatten(1)", `[1, [2], [[3]]]`, []string{`[1,2,[3]]`}},
+		{"flatten", `[{"foo": "bar"}, [{"foo": "baz"}]]`, []string{`[{"foo":"bar"},{"foo":"baz"}]`}},
+	}},
+	"from_entries": {"from_entries", "Converts an array of objects with key and value members into an object. The names k, name, and Name are also accepted for the key, and v and Value for the value.", []manualExample{
+		{"from_entries", `[{"key":"a", "value":1}, {"key":"b", "value":2}]`, []string{`{"a":1,"b":2}`}},
+	}},
+	"fromjson": {"fromjson", "Parses the input string as JSON.", []manualExample{
+		{"fromjson", `"[1,\"a\"]"`, []string{`[1,"a"]`}},
+	}},
+	"getpath": {"getpath(path)", "Outputs the value at the path, given as an array of keys and indices, or null if there is no value there.", []manualExample{
+		{`getpath(["a","b"])`, `{"a":{"b":0}}`, []string{`0`}},
+		{`[getpath(["a","b"], ["a","c"])]`, `{"a":{"b":0}}`, []string{`[0,null]`}},
+	}},
+	"group_by": {"group_by(path_expression)", "Groups the elements of the input array that have the same value of the expression into separate arrays, sorted by that value.", []manualExample{
+		{"group_by(.foo)", `[{"foo":1, "bar":10}, {"foo":3, "bar":100}, {"foo":1, "bar":1}]`, []string{`[[{"foo":1,"bar":10},{"foo":1,"bar":1}],[{"foo":3,"bar":100}]]`}},
+	}},
+	"gsub": {"gsub(regex; replacement), gsub(regex; replacement; flags)", "Replaces all matches of the regular expression in the input string. The replacement can refer to named capture groups as fields of its input.", []manualExample{
+		{`gsub("(?<x>[a-z])"; "\(.x)!")`, `"ab1"`, []string{`"a!b!1"`}},
+	}},
+	"has": {"has(key)", "Outputs true if the input object has the given key, or if the input array has an element at the given index.", []manualExample{
+		{`map(has("foo"))`, `[{"foo": 42}, {}]`, []string{`[true,false]`}},
+		{"map(has(2))", `[[0,1], ["a","b","c"]]`, []string{`[false,true]`}},
+	}},
+	"implode": {"implode", "Converts an array of codepoint numbers into a string.", []manualExample{
+		{"implode", `[65, 66, 67]`, []string{`"ABC"`}},
+	}},
+	"in": {"in(object), in(array)", "Outputs true if the input key is in the given object, or the input index is in the given array. This is has with its arguments swapped.", []manualExample{
+		{`.[] | in({"foo": 42})`, `["foo", "bar"]`, []string{`true`, `false`}},
+	}},
+	"index": {"index(s)", "Outputs the index of the first occurrence of s in the input string or array, or null if there is none.", []manualExample{
+		{`index(", ")`, `"a,b, cd, efg"`, []string{`3`}},
+	}},
+	"indices": {"indices(s)", "Outputs an array of the indices of all occurrences of s in the input string or array.", []manualExample{
+		{`indices(", ")`, `"a,b, cd, efg, hijk"`, []string{`[3,7,12]`}},
+		{"indices(1)", `[0,1,2,1,3,1,4]`, []string{`[1,3,5]`}},
+	}},
+	"inside": {"inside(value)", "Outputs true if the input is completely contained within the argument. This is contains with its arguments swapped.", []manualExample{
+		{`inside("foobar")`, `"bar"`, []string{`true`}},
+	}},
+	"join": {"join(separator)", "Joins the strings of the input array with the separator. Numbers and booleans are converted to strings and null is treated as an empty string.", []manualExample{
+		{`join(", ")`, `["a","b,c,d","e"]`, []string{`"a, b,c,d, e"`}},
+		{`join(" ")`, `["a",1,2.3,true,null,false]`, []string{`"a 1 2.3 true  false"`}},
+	}},
+	"keys": {"keys, keys_unsorted", "Outputs the keys of the input object as an array, sorted by unicode codepoint order, or the indices of the input array. keys_unsorted keeps the keys in their original order.", []manualExample{
+		{"keys", `{"abc": 1, "abcd": 2, "Foo": 3}`, []string{`["Foo","abc","abcd"]`}},
+		{"keys", `[42,3,35]`, []string{`[0,1,2]`}},
+	}},
+	"last": {"last, last(expr)", "Outputs the last element of the input array, or the last output of the expression.", []manualExample{
+		{"last", `[1,2,3]`, []string{`3`}},
+		{"last(range(5))", `null`, []string{`4`}},
+	}},
+	"length": {"length", "Outputs the number of codepoints of a string, the number of elements of an array, the number of members of an object, the absolute value of a number, or zero for null.", []manualExample{
+		{".[] | length", `[[1,2], "string", {"a":2}, null, -5]`, []string{`2`, `6`, `1`, `0`, `5`}},
+	}},
+	"limit": {"limit(n; expr)", "Outputs at most the first n outputs of the expression.", []manualExample{
+		{"[limit(3; .[])]", `[0,1,2,3,4,5,6,7,8,9]`, []string{`[0,1,2]`}},
+	}},
+	"ltrimstr": {"ltrimstr(str)", "Outputs the input with the given prefix removed, if it starts with it. Other inputs are output unchanged.", []manualExample{
+		{`[.[] | ltrimstr("foo")]`, `["fo", "foo", "barfoo", "foobar", "afoo"]`, []string{`["fo","","barfoo","bar","afoo"]`}},
+	}},
+	"map": {"map(f)", "Runs the filter on each element of the input array and collects the outputs in an array. map(f) is the same as [.[] | f].", []manualExample{
+		{"map(. + 1)", `[1,2,3]`, []string{`[2,3,4]`}},
+	}},
+	"map_values": {"map_values(f)", "Runs the filter on each value of the input object or array and replaces the value with the first output, or deletes it if there is none.", []manualExample{
+		{"map_values(. + 1)", `{"a": 1, "b": 2, "c": 3}`, []string{`{"a":2,"b":3,"c":4}`}},
+	}},
+	"match": {"match(regex), match(regex; flags)", "Outputs an object for each match of the regular expression in the input string, with the offset, length, and string of the match and its capture groups. The flag g matches all occurrences.", []manualExample{
+		{`match("(abc)+"; "g") | .string`, `"abc abc"`, []string{`"abc"`, `"abc"`}},
+		{`[match("a"; "gi") | .offset]`, `"aAa"`, []string{`[0,1,2]`}},
+	}},
+	"max": {"max, max_by(path_exp)", "Outputs the largest element of the input array, or null if it is empty. max_by compares the values of the expression instead.", []manualExample{
+		{"max", `[5,4,2,7]`, []string{`7`}},
+		{"max_by(.foo)", `[{"foo":1, "bar":14}, {"foo":2, "bar":3}]`, []string{`{"foo":2,"bar":3}`}},
+	}},
+	"min": {"min, min_by(path_exp)", "Outputs the smallest element of the input array, or null if it is empty. min_by compares the values of the expression instead.", []manualExample{
+		{"min", `[5,4,2,7]`, []string{`2`}},
+		{"min_by(.foo)", `[{"foo":1, "bar":14}, {"foo":2, "bar":3}]`, []string{`{"foo":1,"bar":14}`}},
+	}},
+	"not": {"not", "Outputs the logical negation of the input: true for false and null, and false for all other values.", []manualExample{
+		{"[true, false | not]", `null`, []string{`[false,true]`}},
+	}},
+	"path": {"path(path_expression)", "Outputs the paths of the values that the expression selects, as arrays of keys and indices.", []manualExample{
+		{"path(.a[0].b)", `null`, []string{`["a",0,"b"]`}},
+		{"[path(..)]", `{"a":[{"b":1}]}`, []string{`[[],["a"],["a",0],["a",0,"b"]]`}},
+	}},
+	"paths": {"paths, paths(node_filter)", "Outputs the paths of all values in the input, or of those for which the filter is true.", []manualExample{
+		{"[paths]", `[1,[[],{"a":2}]]`, []string{`[[0],[1],[1,0],[1,1],[1,1,"a"]]`}},
+		{"[paths(type == \"number\")]", `[1,[[],{"a":2}]]`, []string{`[[0],[1,1,"a"]]`}},
+	}},
+	"range": {"range(upto), range(from; upto), range(from; upto; by)", "Produces the numbers from from (by default 0) up to but not including upto, in steps of by (by default 1).", []manualExample{
+		{"[range(4)]", `null`, []string{`[0,1,2,3]`}},
+		{"[range(2; 4)]", `null`, []string{`[2,3]`}},
+		{"[range(0; 10; 3)]", `null`, []string{`[0,3,6,9]`}},
+	}},
+	"recurse": {"recurse, recurse(f), recurse(f; condition)", "Outputs the input and, recursively, every value the filter produces from it. recurse without arguments descends into all arrays and objects, like the .. operator.", []manualExample{
+		{"recurse(if . < 3 then . + 1 else empty end)", `0`, []string{`0`, `1`, `2`, `3`}},
+		{"[recurse]", `[[1]]`, []string{`[[[1]],[1],1]`}},
+	}},
+	"reverse": {"reverse", "Reverses the input array.", []manualExample{
+		{"reverse", `[1,2,3,4]`, []string{`[4,3,2,1]`}},
+	}},
+	"rtrimstr": {"rtrimstr(str)", "Outputs the input with the given suffix removed, if it ends with it. Other inputs are output unchanged.", []manualExample{
+		{`[.[] | rtrimstr("foo")]`, `["fo", "foo", "barfoo", "foobar", "foob"]`, []string{`["fo","","bar","foobar","foob"]`}},
+	}},
+	"scan": {"scan(regex), scan(regex; flags)", "Outputs each non-overlapping substring of the input that matches the regular expression.", []manualExample{
+		{`scan("c")`, `"abcdefabc"`, []string{`"c"`, `"c"`}},
+		{`[scan("[0-9]+")]`, `"a1 b22 c333"`, []string{`["1","22","333"]`}},
+	}},
+	"select": {"select(boolean_expression)", "Outputs the input unchanged if the expression is true for it, and nothing otherwise.", []manualExample{
+		{"map(select(. >= 2))", `[1,5,3,0,7]`, []string{`[5,3,7]`}},
+		{`.[] | select(.id == "second")`, `[{"id": "first", "val": 1}, {"id": "second", "val": 2}]`, []string{`{"id":"second","val":2}`}},
+	}},
+	"setpath": {"setpath(path; value)", "Sets the value at the path, given as an array of keys and indices, creating the objects and arrays on the way.", []manualExample{
+		{`setpath(["a","b"]; 1)`, `null`, []string{`{"a":{"b":1}}`}},
+		{`setpath([0,"a"]; 1)`, `null`, []string{`[{"a":1}]`}},
+	}},
+	"sort": {"sort, sort_by(path_expression)", "Sorts the input array. Values are sorted by type first: null, false, true, numbers, strings, arrays, and objects. sort_by sorts by the values of the expression instead.", []manualExample{
+		{"sort", `[8,3,null,6]`, []string{`[null,3,6,8]`}},
+		{"sort_by(.foo)", `[{"foo":4, "bar":10}, {"foo":3, "bar":100}, {"foo":2, "bar":1}]`, []string{`[{"foo":2,"bar":1},{"foo":3,"bar":100},{"foo":4,"bar":10}]`}},
+	}},
+	"split": {"split(str), split(regex; flags)", "Splits the input string at each occurrence of the separator. With two arguments the separator is a regular expression.", []manualExample{
+		{`split(", ")`, `"a, b,c,d, e, "`, []string{`["a","b,c,d","e",""]`}},
+		{`split(", *"; null)`, `"ab,cd, ef"`, []string{`["ab","cd","ef"]`}},
+	}},
+	"splits": {"splits(regex), splits(regex; flags)", "Outputs each part of the input string split at the matches of the regular expression.", []manualExample{
+		{`splits(", *"; null)`, `"ab,cd, ef"`, []string{`"ab"`, `"cd"`, `"ef"`}},
+	}},
+	"startswith": {"startswith(str)", "Outputs true if the input string starts with the given string.", []manualExample{
+		{`[.[] | startswith("foo")]`, `["fo", "foo", "barfoo", "foobar", "barfoob"]`, []string{`[false,true,false,true,false]`}},
+	}},
+	"sub": {"sub(regex; replacement), sub(regex; replacement; flags)", "Replaces the first match of the regular expression in the input string. The replacement can refer to named capture groups as fields of its input.", []manualExample{
+		{`sub("^[^a-z]*(?<x>[a-z]*).*"; "Z\(.x)")`, `"123abc456"`, []string{`"Zabc"`}},
+	}},
+	"test": {"test(regex), test(regex; flags)", "Outputs true if the input string matches the regular expression. The flag x allows extended regular expressions with white space and comments, and i ignores case.", []manualExample{
+		{`test("foo")`, `"foo"`, []string{`true`}},
+		{`.[] | test("a b c # spaces are ignored"; "ix")`, `["xabcd", "ABC"]`, []string{`true`, `true`}},
+	}},
+	"to_entries": {"to_entries, with_entries(f)", "Converts an object into an array of objects with the key and value of each member. with_entries(f) is to_entries | map(f) | from_entries, to change the keys or values of an object.", []manualExample{
+		{"to_entries", `{"a": 1, "b": 2}`, []string{`[{"key":"a","value":1},{"key":"b","value":2}]`}},
+		{`with_entries(.value += 1)`, `{"a": 1, "b": 2}`, []string{`{"a":2,"b":3}`}},
+	}},
+	"tojson": {"tojson", "Encodes the input as a JSON string.", []manualExample{
+		{"[.[] | tojson]", `[1, "foo", ["foo"]]`, []string{`["1","\"foo\"","[\"foo\"]"]`}},
+	}},
+	"tonumber": {"tonumber", "Parses the input string as a number. Numbers are output unchanged.", []manualExample{
+		{".[] | tonumber", `[1, "1"]`, []string{`1`, `1`}},
+	}},
+	"tostring": {"tostring", "Outputs the input unchanged if it is a string, and as JSON text otherwise.", []manualExample{
+		{".[] | tostring", `[1, "1", [1]]`, []string{`"1"`, `"1"`, `"[1]"`}},
+	}},
+	"transpose": {"transpose", "Transposes the input array of arrays, padding shorter rows with null.", []manualExample{
+		{"transpose", `[[1], [2,3]]`, []string{`[[1,2],[null,3]]`}},
+	}},
+	"type": {"type", "Outputs the type of the input as a string: null, boolean, number, string, array, or object.", []manualExample{
+		{"map(type)", `[0, false, [], {}, null, "hello"]`, []string{`["number","boolean","array","object","null","string"]`}},
+	}},
+	"unique": {"unique, unique_by(path_exp)", "Sorts the input array and removes duplicate elements. unique_by keeps one element for each value of the expression.", []manualExample{
+		{"unique", `[1,2,5,3,5,3,1,3]`, []string{`[1,2,3,5]`}},
+		{"unique_by(length)", `["chunky", "bacon", "kitten", "cicada", "asparagus"]`, []string{`["bacon","chunky","asparagus"]`}},
+	}},
+	"until": {"until(cond; update)", "Applies the update to the input until the condition is true, and outputs the result.", []manualExample{
+		{"[.[] | until(. > 100; . * 2)]", `[1, 2, 70]`, []string{`[128,128,140]`}},
+	}},
+	"walk": {"walk(f)", "Applies the filter recursively to every value of the input, innermost values first.", []manualExample{
+		{"walk(if type == \"array\" then sort else . end)", `[[4, 1, 7], [8, 5, 2], [3, 6, 9]]`, []string{`[[1,4,7],[2,5,8],[3,6,9]]`}},
+	}},
+	"while": {"while(cond; update)", "Outputs the input and each result of repeatedly applying the update while the condition is true.", []manualExample{
+		{"[while(. < 100; . * 2)]", `1`, []string{`[1,2,4,8,16,32,64]`}},
+	}},
+	"@base64": {"@base64, @base64d", "Encodes the input string as base64, or decodes it with @base64d.", []manualExample{
+		{"@base64", `"This is a message"`, []string{`"VGhpcyBpcyBhIG1lc3NhZ2U="`}},
+		{"@base64d", `"VGhpcyBpcyBhIG1lc3NhZ2U="`, []string{`"This is a message"`}},
+	}},
+	"@csv": {"@csv, @tsv", "Formats the input array as a row of CSV, with strings quoted, or of TSV. Use with -r to write the rows as text.", []manualExample{
+		{"@csv", `[1, "a,b", "c\"d"]`, []string{`"1,\"a,b\",\"c\"\"d\""`}},
+		{"@tsv", `[1, "a\tb"]`, []string{`"1\ta\\tb"`}},
+	}},
+}
+
+// Builtins documented on the page of another builtin
+var manualAliases = map[string]string{
+	"keys_unsorted": "keys",
+	"max_by":        "max",
+	"min_by":        "min",
+	"sort_by":       "sort",
+	"unique_by":     "unique",
+	"with_entries":  "to_entries",
+	"@base64d":      "@base64",
+	"@tsv":          "@csv",
+}
+
+// Return the name of the builtin, keyword, or operator at the byte offset in
+// the filter. The cursor may also be just after the name, where it is after
+// typing it.
+func tokenAt(filter string, offset int) (string, bool) {
+	var found *token
+	tokens := tokenizeFilter(filter)
+	for i, t := range tokens {
+		if t.start <= offset && offset <= t.end && (t.kind == tokenIdent || t.kind == tokenOperator) {
+			// A name takes precedence over an operator it touches
+			if found == nil || (found.kind == tokenOperator && t.kind == tokenIdent) {
+				found = &tokens[i]
+			}
+		}
+	}
+
+	if found == nil {
+		return "", false
+	}
+
+	return found.text, true
+}
+
+// Format the manual page of a builtin, keyword, or operator. Names without a
+// bundled page fall back to their short description, and the page says when
+// nothing is known about the name.
+func manualPage(name string) string {
+	if alias, ok := manualAliases[name]; ok {
+		name = alias
+	}
+
+	entry, ok := builtinManual[name]
+	if !ok {
+		doc, known := describeToken(token{kind: tokenIdent, text: name})
+		if !known {
+			doc, known = describeToken(token{kind: tokenOperator, text: name})
+		}
+
+		if !known {
+			return fmt.Sprintf("No documentation for %s.\n", name)
+		}
+
+		return fmt.Sprintf("%s\n\n%s.\n\nNo examples are bundled for %s, see the jq manual.\n", name, doc, name)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n\n%s\n", entry.usage, entry.text)
+	if len(entry.examples) > 0 {
+		sb.WriteString("\nExamples:\n")
+	}
+
+	for _, ex := range entry.examples {
+		fmt.Fprintf(&sb, "\n  jq %s\n     %s\n", shellQuote(ex.program), ex.input)
+		for _, out := range ex.outputs {
+			fmt.Fprintf(&sb, "  => %s\n", out)
+		}
+	}
+
+	return sb.String()
+}
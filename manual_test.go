@@ -0,0 +1,70 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenAt(t *testing.T) {
+	filter := `.[] | ltrimstr("x") | .a`
+	for offset, name := range map[int]string{6: "ltrimstr", 10: "ltrimstr", 14: "ltrimstr", 4: "|"} {
+		got, ok := tokenAt(filter, offset)
+		assert.True(t, ok, offset)
+		assert.Equal(t, name, got, offset)
+	}
+
+	_, ok := tokenAt(filter, 17)
+	assert.False(t, ok)
+	_, ok = tokenAt(filter, len(filter))
+	assert.False(t, ok)
+
+	// The name is preferred over an operator just before it
+	name, _ := tokenAt(".a|map(.)", 3)
+	assert.Equal(t, "map", name)
+}
+
+func TestManualPage(t *testing.T) {
+	page := manualPage("ltrimstr")
+	assert.Contains(t, page, "ltrimstr(str)\n\n")
+	assert.Contains(t, page, "  jq '[.[] | ltrimstr(\"foo\")]'\n     [\"fo\", \"foo\", \"barfoo\", \"foobar\", \"afoo\"]\n  => [\"fo\",\"\",\"barfoo\",\"bar\",\"afoo\"]\n")
+
+	assert.Equal(t, manualPage("sort"), manualPage("sort_by"))
+	assert.Equal(t, "reduce\n\n"+operatorDocs["reduce"]+".\n\nNo examples are bundled for reduce, see the jq manual.\n", manualPage("reduce"))
+	assert.Contains(t, manualPage("|="), operatorDocs["|="])
+	assert.Equal(t, "No documentation for frobnicate.\n", manualPage("frobnicate"))
+}
+
+func TestBuiltinManualNames(t *testing.T) {
+	builtins := map[string]bool{}
+	for _, name := range availableBuiltins(jqVersion{}, false) {
+		builtins[name] = true
+	}
+
+	for name, entry := range builtinManual {
+		assert.True(t, builtins[name], name)
+		assert.NotEmpty(t, entry.examples, name)
+	}
+
+	for alias, name := range manualAliases {
+		assert.True(t, builtins[alias], alias)
+		assert.Contains(t, builtinManual, name)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The filters merging the input values with each -merge strategy. A deep
+// merge merges nested objects recursively, while a shallow merge replaces
+// the value of a key with the value of the later input.
+var mergeFilters = map[string]string{
+	MergeDeep:    "reduce .[] as $x ({}; . * $x)",
+	MergeShallow: "reduce .[] as $x ({}; . + $x)",
+}
+
+// Merge the input values into a single object with the -merge strategy, so
+// that the filter is applied to the merged object. Later inputs take
+// precedence over earlier ones. All of the input values must be objects.
+func (d *Document) Merge() error {
+	if d.options.mergeMode == "" {
+		return nil
+	}
+
+	cmd := exec.Command(d.options.command, "-s", "-c", mergeFilters[d.options.mergeMode])
+	cmd.Stdin = strings.NewReader(d.input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("merging the inputs failed: %v", err)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v\n%s", err, msg)
+		}
+
+		return err
+	}
+
+	d.input = string(out)
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentMerge(t *testing.T) {
+	doc := &Document{input: "{\"a\": 1}\n{\"b\": 2}\n", options: Options{command: "./testdata/caterror"}}
+	assert.NoError(t, doc.Merge())
+	assert.Equal(t, "{\"a\": 1}\n{\"b\": 2}\n", doc.input)
+
+	doc.options.mergeMode = MergeDeep
+	err := doc.Merge()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "merging the inputs failed")
+	assert.Equal(t, "{\"a\": 1}\n{\"b\": 2}\n", doc.input)
+
+	// The merged input is the output of jq
+	doc.input = "{\"a\": 1}\n"
+	doc.options.command = "./testdata/cat"
+	assert.NoError(t, doc.Merge())
+	assert.Equal(t, "{\"a\": 1}\n", doc.input)
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Compare two strings in natural order, in which runs of digits are
+// compared by their numeric value, so that item2 sorts before item10. Other
+// text is compared by code point, as jq compares strings. Strings that only
+// differ in leading zeros are ordered by code point.
+func naturalLess(a, b string) bool {
+	x, y := a, b
+	for x != "" && y != "" {
+		dx, dy := digitPrefix(x), digitPrefix(y)
+		if dx > 0 && dy > 0 {
+			nx := strings.TrimLeft(x[:dx], "0")
+			ny := strings.TrimLeft(y[:dy], "0")
+			if len(nx) != len(ny) {
+				return len(nx) < len(ny)
+			}
+
+			if nx != ny {
+				return nx < ny
+			}
+
+			x, y = x[dx:], y[dy:]
+			continue
+		}
+
+		if x[0] != y[0] {
+			return x[0] < y[0]
+		}
+
+		x, y = x[1:], y[1:]
+	}
+
+	if x != "" || y != "" {
+		return x == ""
+	}
+
+	return a < b
+}
+
+// The number of ASCII digits at the start of a string
+func digitPrefix(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return i
+}
+
+// Sort the keys of the value's objects in natural order, including the
+// objects nested in it
+func (v *jsonValue) sortKeysNaturally() {
+	for _, item := range v.items {
+		item.sortKeysNaturally()
+	}
+
+	for _, m := range v.members {
+		m.value.sortKeysNaturally()
+	}
+
+	sort.SliceStable(v.members, func(i, j int) bool {
+		return naturalLess(v.members[i].key, v.members[j].key)
+	})
+}
+
+// Run the filter and parse its results, for the results to be changed before
+// jq formats them. The filter must already have the selection, the sorting
+// of -sort-by, and the prefix applied.
+func (d *Document) results(filter string, opts Options) ([]*jsonValue, error) {
+	c := Document{input: d.input, filter: filter, options: opts, inputFile: d.inputFile}
+	c.options.compact = true
+	c.options.rawOutput = false
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.sortKeys = false
+	c.options.sortKeysNatural = false
+	c.options.preserveOrder = ""
+	c.options.unbuffered = false
+	c.options.seq = false
+	c.options.plainNumbers = false
+	c.options.indentString = ""
+	c.options.escapeOutput = false
+	c.options.trailingNewline = trailingNewline{}
+
+	// The filter already has the selection, the sorting, and the prefix
+	// applied
+	c.options.selection = ""
+	c.options.sortBy = ""
+	c.options.prefix = ""
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return parseJSONStream(buf.Bytes())
+}
+
+// The options with which jq formats results that are already computed. The
+// options saying how the input is read and the filter is run are dropped.
+func (o Options) formatOptions() Options {
+	return Options{
+		command:    o.command,
+		compact:    o.compact,
+		rawOutput:  o.rawOutput,
+		monochrome: o.monochrome,
+		forceColor: o.forceColor,
+		unbuffered: o.unbuffered,
+		seq:        o.seq,
+	}
+}
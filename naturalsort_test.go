@@ -0,0 +1,63 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNaturalLess(t *testing.T) {
+	assert.True(t, naturalLess("item2", "item10"))
+	assert.False(t, naturalLess("item10", "item2"))
+	assert.True(t, naturalLess("a", "b"))
+	assert.True(t, naturalLess("B", "a"))
+	assert.True(t, naturalLess("x1y2", "x1y10"))
+	assert.True(t, naturalLess("item", "item1"))
+	assert.True(t, naturalLess("9", "a"))
+	assert.True(t, naturalLess("a01", "a1"))
+	assert.False(t, naturalLess("a1", "a01"))
+	assert.False(t, naturalLess("a1", "a1"))
+	assert.True(t, naturalLess("v99999999999999999999", "v100000000000000000000"))
+}
+
+func TestSortKeysNaturally(t *testing.T) {
+	values, err := parseJSONStream([]byte(`{"k10": [{"b": 1, "a2": 2, "a10": 3}], "k9": 1.50}`))
+	assert.Nil(t, err)
+	values[0].sortKeysNaturally()
+	assert.Equal(t, `{"k9":1.50,"k10":[{"a2":2,"a10":3,"b":1}]}`+"\n", string(formatJSONStream(values, "")))
+}
+
+func TestWriteToSortKeysNatural(t *testing.T) {
+	doc := Document{
+		input:   `{"n10": 1, "n2": {"y": 1, "x": 2}}`,
+		filter:  ".",
+		options: Options{command: "./testdata/cat", sortKeysNatural: true, sortKeys: true},
+	}
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"n2":{"x":2,"y":1},"n10":1}`+"\n", buf.String())
+
+	doc.options.command = "./testdata/caterror"
+	_, err = doc.WriteTo(&buf)
+	assert.NotNil(t, err)
+}
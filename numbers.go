@@ -0,0 +1,133 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Numbers in scientific notation with exponents beyond this magnitude are
+// left as is, as their plain form would be unreasonably long
+const maxPlainExponent = 100
+
+var scientificPattern = regexp.MustCompile(`^(-?)(\d+)(?:\.(\d+))?[eE]([+-]?\d+)$`)
+
+// Convert a number in scientific notation to plain decimal notation. The
+// digits are shifted as text, so no precision is lost beyond what was lost
+// when the number was formatted.
+func plainNumber(s string) (string, bool) {
+	m := scientificPattern.FindStringSubmatch(s)
+	if m == nil {
+		return s, false
+	}
+
+	exp, err := strconv.Atoi(m[4])
+	if err != nil || exp > maxPlainExponent || exp < -maxPlainExponent {
+		return s, false
+	}
+
+	digits := m[2] + m[3]
+	point := len(m[2]) + exp
+
+	var intPart, fracPart string
+	switch {
+	case point <= 0:
+		intPart, fracPart = "0", strings.Repeat("0", -point)+digits
+	case point >= len(digits):
+		intPart = digits + strings.Repeat("0", point-len(digits))
+	default:
+		intPart, fracPart = digits[:point], digits[point:]
+	}
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	fracPart = strings.TrimRight(fracPart, "0")
+	sign := m[1]
+	if intPart == "0" && fracPart == "" {
+		sign = ""
+	}
+
+	if fracPart == "" {
+		return sign + intPart, true
+	}
+
+	return sign + intPart + "." + fracPart, true
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
+
+// Rewrite numbers in scientific notation in jq output to plain decimal
+// notation. String literals and ANSI color sequences are skipped.
+func plainNumbers(out []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(out); {
+		c := out[i]
+		switch {
+		case c == '"':
+			// Copy the string literal, including escaped quotes
+			j := i + 1
+			for j < len(out) && out[j] != '"' {
+				if out[j] == '\\' {
+					j++
+				}
+				j++
+			}
+
+			if j < len(out) {
+				j++
+			}
+
+			buf.Write(out[i:j])
+			i = j
+		case c == '\x1b':
+			j := i + 1
+			for j < len(out) && out[j] != 'm' {
+				j++
+			}
+
+			if j < len(out) {
+				j++
+			}
+
+			buf.Write(out[i:j])
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(out) && isNumberByte(out[j]) {
+				j++
+			}
+
+			number, _ := plainNumber(string(out[i:j]))
+			buf.WriteString(number)
+			i = j
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainNumber(t *testing.T) {
+	tests := map[string]string{
+		"1e+20":       "100000000000000000000",
+		"1.5e-07":     "0.00000015",
+		"-1.2345E3":   "-1234.5",
+		"1.50e1":      "15",
+		"0.5e1":       "5",
+		"-0e5":        "0",
+		"1.7976e+308": "1.7976e+308",
+	}
+
+	for in, expected := range tests {
+		out, _ := plainNumber(in)
+		assert.Equal(t, expected, out, in)
+	}
+
+	_, ok := plainNumber("123")
+	assert.False(t, ok)
+}
+
+func TestPlainNumbers(t *testing.T) {
+	out := []byte("{\n  \"1e5\": \"a \\\"2e3\\\"\",\n  \"b\": [1e+20, -2.5e-3, 42]\n}\n")
+	expected := "{\n  \"1e5\": \"a \\\"2e3\\\"\",\n  \"b\": [100000000000000000000, -0.0025, 42]\n}\n"
+	assert.Equal(t, expected, string(plainNumbers(out)))
+
+	colored := []byte("\x1b[0;39m1e+20\x1b[0m\n")
+	assert.Equal(t, "\x1b[0;39m100000000000000000000\x1b[0m\n", string(plainNumbers(colored)))
+}
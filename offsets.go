@@ -0,0 +1,194 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The number of values whose byte offsets are recorded, which bounds the
+// work of parsing large inputs
+const maxOffsetValues = 200000
+
+// The position of a value in the input as a range of byte offsets
+type valueSpan struct {
+	start int
+	end   int
+}
+
+// A value of the input, identified by the position of the top-level value
+// it is part of in the input and its jq path within that value
+type valueKey struct {
+	root int
+	path string
+}
+
+// The byte offsets of the values in a JSON input
+type inputOffsets struct {
+	spans map[valueKey]valueSpan
+
+	// The number of values that were read and whether the whole input was
+	// read. Parsing stops after the maximum number of values or at the
+	// first error.
+	values   int
+	complete bool
+}
+
+// Record the byte offsets of at most max values of the input. The input is
+// read token by token and the offsets are taken from the positions of the
+// tokens, so the values need not be kept in memory.
+func parseOffsets(input string, max int) inputOffsets {
+	offsets := inputOffsets{spans: map[valueKey]valueSpan{}}
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var frames []pathFrame
+	var starts []int
+	root := -1
+	key := false // whether the next token is an object key
+	prev := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			offsets.complete = true
+			return offsets
+		}
+
+		if err != nil {
+			return offsets
+		}
+
+		// Only whitespace and separators come between tokens
+		end := int(dec.InputOffset())
+		start := end - len(strings.TrimLeft(input[prev:end], " \t\r\n,:"))
+		prev = end
+
+		if tok == json.Delim('}') || tok == json.Delim(']') {
+			n := len(frames) - 1
+			offsets.spans[valueKey{root, formatPath(frames[:n])}] = valueSpan{starts[n], end}
+			frames, starts = frames[:n], starts[:n]
+			key = n > 0 && !frames[n-1].array
+			continue
+		}
+
+		if key {
+			frames[len(frames)-1].key, _ = tok.(string)
+			key = false
+			continue
+		}
+
+		if offsets.values == max {
+			return offsets
+		}
+
+		offsets.values++
+		if n := len(frames); n == 0 {
+			root++
+		} else if frames[n-1].array {
+			frames[n-1].index++
+		}
+
+		if tok == json.Delim('{') || tok == json.Delim('[') {
+			frames = append(frames, pathFrame{array: tok == json.Delim('['), index: -1})
+			starts = append(starts, start)
+			key = tok == json.Delim('{')
+			continue
+		}
+
+		offsets.spans[valueKey{root, formatPath(frames)}] = valueSpan{start, end}
+		key = len(frames) > 0 && !frames[len(frames)-1].array
+	}
+}
+
+// Describe the position of a value in the input, or return an empty string
+// if the value is not known
+func (o inputOffsets) describe(k valueKey) string {
+	if span, ok := o.spans[k]; ok {
+		return fmt.Sprintf("byte %d, length %d", span.start, span.end-span.start)
+	}
+
+	if !o.complete {
+		return fmt.Sprintf("offset not known after the first %d values", o.values)
+	}
+
+	return ""
+}
+
+// Compute the position of the top-level value that each line of
+// pretty-printed JSON output is part of
+func lineRoots(text string) []int {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	roots := make([]int, len(lines))
+	root, depth := -1, 0
+	for i, line := range lines {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if depth == 0 && line != "" {
+			root++
+		}
+
+		switch {
+		case line == "}" || line == "]":
+			depth--
+		case strings.HasSuffix(line, "{") || strings.HasSuffix(line, "["):
+			depth++
+		}
+
+		roots[i] = root
+	}
+
+	return roots
+}
+
+// The index at the start of the path of a value within the slurped input
+var slurpedIndexPattern = regexp.MustCompile(`^\[(\d+)\]`)
+
+// Identify the value shown on a line of the input pane, given the path and
+// top-level value of the line. With -s the input pane shows a single array
+// of the input values, so its index identifies the top-level value.
+func lineValueKey(root int, path string, slurp bool) valueKey {
+	if !slurp {
+		return valueKey{root, path}
+	}
+
+	m := slurpedIndexPattern.FindStringSubmatch(path)
+	if m == nil {
+		return valueKey{-1, path}
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	path = path[len(m[0]):]
+	if path == "" {
+		path = "."
+	}
+
+	return valueKey{n, path}
+}
+
+// Whether the values of the input pane can be found in the input. With
+// -pointer the input pane shows only the value at the pointer, whose paths
+// are not the paths of the input, and with -json5 the offsets would be those
+// of the converted input rather than of the file. With -sort-by the input
+// pane is sorted.
+func offsetsAvailable(o Options) bool {
+	return !o.rawInput && !o.nullInput && !o.json5 && o.prefix == "" && o.sortBy == ""
+}
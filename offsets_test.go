@@ -0,0 +1,69 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOffsets(t *testing.T) {
+	input := `{"a": [1, "x"], "b": {}} 2` + "\n" + `[true]`
+	offsets := parseOffsets(input, maxOffsetValues)
+	assert.True(t, offsets.complete)
+	assert.Equal(t, 8, offsets.values)
+
+	span := func(k valueKey) string {
+		s := offsets.spans[k]
+		return input[s.start:s.end]
+	}
+
+	assert.Equal(t, `{"a": [1, "x"], "b": {}}`, span(valueKey{0, "."}))
+	assert.Equal(t, `[1, "x"]`, span(valueKey{0, ".a"}))
+	assert.Equal(t, `"x"`, span(valueKey{0, ".a[1]"}))
+	assert.Equal(t, `{}`, span(valueKey{0, ".b"}))
+	assert.Equal(t, `2`, span(valueKey{1, "."}))
+	assert.Equal(t, `true`, span(valueKey{2, "[0]"}))
+	assert.Equal(t, "byte 6, length 8", offsets.describe(valueKey{0, ".a"}))
+	assert.Equal(t, "", offsets.describe(valueKey{0, ".c"}))
+
+	// Parsing stops after the maximum number of values
+	offsets = parseOffsets(input, 3)
+	assert.False(t, offsets.complete)
+	assert.Equal(t, `1`, input[offsets.spans[valueKey{0, ".a[0]"}].start:offsets.spans[valueKey{0, ".a[0]"}].end])
+	assert.Equal(t, "offset not known after the first 3 values", offsets.describe(valueKey{0, ".b"}))
+
+	// And at the first error
+	offsets = parseOffsets(`1 {"a": nan}`, maxOffsetValues)
+	assert.False(t, offsets.complete)
+	assert.Equal(t, valueSpan{0, 1}, offsets.spans[valueKey{0, "."}])
+}
+
+func TestLineRoots(t *testing.T) {
+	text := "{\n  \"a\": [\n    1\n  ]\n}\n2\n[]\n"
+	assert.Equal(t, []int{0, 0, 0, 0, 0, 1, 2}, lineRoots(text))
+}
+
+func TestLineValueKey(t *testing.T) {
+	assert.Equal(t, valueKey{1, ".a"}, lineValueKey(1, ".a", false))
+	assert.Equal(t, valueKey{3, ".a"}, lineValueKey(0, "[3].a", true))
+	assert.Equal(t, valueKey{3, "."}, lineValueKey(0, "[3]", true))
+	assert.Equal(t, valueKey{12, "[0]"}, lineValueKey(0, "[12][0]", true))
+	assert.Equal(t, valueKey{-1, "."}, lineValueKey(0, ".", true))
+}
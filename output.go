@@ -0,0 +1,315 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Functions converting the filter results to each supported output format
+var outputFormats = map[string]func(values []*jsonValue) []byte{
+	"json": func(values []*jsonValue) []byte {
+		return formatJSONStream(values, "  ")
+	},
+	"compact": func(values []*jsonValue) []byte {
+		return formatJSONStream(values, "")
+	},
+	"raw":  formatRaw,
+	"yaml": formatYAML,
+}
+
+// File extensions from which the output format can be inferred
+var outputExtensions = map[string]string{
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".txt":  "raw",
+}
+
+type outputSpec struct {
+	file   string
+	format string
+}
+
+// A list of output files given with repeated -o flags
+type outputSpecs []outputSpec
+
+func (s *outputSpecs) String() string {
+	return strings.Join(s.items(), ",")
+}
+
+func (s *outputSpecs) items() []string {
+	var items []string
+	for _, spec := range *s {
+		items = append(items, spec.file+":"+spec.format)
+	}
+
+	return items
+}
+
+// Parse an output spec of the form file:format. If the format is omitted it
+// is inferred from the file extension.
+func (s *outputSpecs) Set(value string) error {
+	file, format := value, ""
+	if i := strings.LastIndexByte(value, ':'); i >= 0 {
+		file, format = value[:i], value[i+1:]
+	}
+
+	if format == "" {
+		format = outputExtensions[strings.ToLower(filepath.Ext(file))]
+		if format == "" {
+			return fmt.Errorf("cannot infer output format of %q, use file:format", file)
+		}
+	}
+
+	if _, ok := outputFormats[format]; !ok {
+		var names []string
+		for name := range outputFormats {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		return fmt.Errorf("unknown output format %q: must be one of %s", format, strings.Join(names, ", "))
+	}
+
+	if file == "" {
+		return fmt.Errorf("missing file name in output %q", value)
+	}
+
+	*s = append(*s, outputSpec{file, format})
+	return nil
+}
+
+// Whether the final output ends with a newline. When the flag is not given
+// the output is written exactly as jq produced it.
+type trailingNewline struct {
+	set   bool
+	value bool
+}
+
+func (t *trailingNewline) String() string {
+	if !t.set {
+		return ""
+	}
+
+	return strconv.FormatBool(t.value)
+}
+
+func (t *trailingNewline) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value %q: must be true or false", value)
+	}
+
+	t.set, t.value = true, v
+	return nil
+}
+
+func (t *trailingNewline) IsBoolFlag() bool {
+	return true
+}
+
+// Add or remove the newline at the end of the output. Only a single newline
+// is removed, so a raw string that itself ends with a newline keeps it. Empty
+// output is left empty.
+func (t trailingNewline) apply(out []byte) []byte {
+	if !t.set || len(out) == 0 {
+		return out
+	}
+
+	hasNewline := out[len(out)-1] == '\n'
+	if t.value && !hasNewline {
+		return append(out, '\n')
+	}
+
+	if !t.value && hasNewline {
+		return out[:len(out)-1]
+	}
+
+	return out
+}
+
+// Run the document filter and parse the results
+func (d *Document) Values() ([]*jsonValue, error) {
+	c := Document{input: d.input, filter: d.filter, options: d.options, inputFile: d.inputFile}
+	c.options.compact = true
+	c.options.rawOutput = false
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.seq = false
+	c.options.trailingNewline = trailingNewline{}
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return parseJSONStream(buf.Bytes())
+}
+
+// Write the filter results to each output file in its format. The filter is
+// run once and each format is converted once. An error is returned for each
+// file that could not be written.
+func (d *Document) WriteOutputs(specs outputSpecs) []error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	values, err := d.Values()
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	converted := map[string][]byte{}
+	for _, spec := range specs {
+		data, ok := converted[spec.format]
+		if !ok {
+			data = outputFormats[spec.format](values)
+			if spec.format == "json" && d.options.indentString != "" {
+				data = reindent(data, d.options.indentString)
+			}
+
+			data, err = encodeOutput(d.options.trailingNewline.apply(data), d.options.encoding)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", spec.file, err))
+				continue
+			}
+
+			converted[spec.format] = data
+		}
+
+		if err := os.WriteFile(spec.file, data, 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// Format values like jq -r: strings are written as is and other values as
+// compact JSON
+func formatRaw(values []*jsonValue) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if v.kind == jsonString {
+			buf.WriteString(v.str)
+		} else {
+			v.writeJSON(&buf, "", 0)
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// Format values as a stream of YAML documents
+func formatYAML(values []*jsonValue) []byte {
+	var buf bytes.Buffer
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+
+		writeYAML(&buf, v, 0)
+	}
+
+	return buf.Bytes()
+}
+
+// Strings that can be written in YAML without quotes
+var plainYAMLPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ ./-]*$`)
+
+// Words that YAML parsers interpret as something other than a string
+var reservedYAMLWords = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true,
+	"off": true, "y": true, "n": true, "null": true, "~": true,
+}
+
+// Quote a string for YAML if it would otherwise be read as a different
+// value. JSON string literals are valid double quoted YAML strings.
+func quoteYAML(s string) string {
+	if plainYAMLPattern.MatchString(s) && !strings.HasSuffix(s, " ") && !reservedYAMLWords[strings.ToLower(s)] {
+		return s
+	}
+
+	return quoteJSON(s)
+}
+
+// Write a value that is not a non-empty array or object
+func writeYAMLScalar(w *bytes.Buffer, v *jsonValue) {
+	switch v.kind {
+	case jsonString:
+		w.WriteString(quoteYAML(v.str))
+	case jsonArray:
+		w.WriteString("[]")
+	case jsonObject:
+		w.WriteString("{}")
+	default:
+		w.WriteString(v.scalar)
+	}
+}
+
+func isYAMLCollection(v *jsonValue) bool {
+	return (v.kind == jsonArray && len(v.items) > 0) || (v.kind == jsonObject && len(v.members) > 0)
+}
+
+// Write a value in YAML block style at the given indentation level
+func writeYAML(w *bytes.Buffer, v *jsonValue, level int) {
+	indent := strings.Repeat("  ", level)
+	switch {
+	case v.kind == jsonArray && len(v.items) > 0:
+		for _, item := range v.items {
+			w.WriteString(indent + "- ")
+			if isYAMLCollection(item) {
+				// Write the first line of the nested collection
+				// after the dash and the rest indented below it
+				var nested bytes.Buffer
+				writeYAML(&nested, item, level+1)
+				w.WriteString(strings.TrimPrefix(nested.String(), indent+"  "))
+			} else {
+				writeYAMLScalar(w, item)
+				w.WriteByte('\n')
+			}
+		}
+	case v.kind == jsonObject && len(v.members) > 0:
+		for _, m := range v.members {
+			w.WriteString(indent + quoteYAML(m.key) + ":")
+			if isYAMLCollection(m.value) {
+				w.WriteByte('\n')
+				writeYAML(w, m.value, level+1)
+			} else {
+				w.WriteByte(' ')
+				writeYAMLScalar(w, m.value)
+				w.WriteByte('\n')
+			}
+		}
+	default:
+		w.WriteString(indent)
+		writeYAMLScalar(w, v)
+		w.WriteByte('\n')
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputSpecsSet(t *testing.T) {
+	var specs outputSpecs
+	assert.NoError(t, specs.Set("out.json:json"))
+	assert.NoError(t, specs.Set("out.yml"))
+	assert.NoError(t, specs.Set("C:/out:compact"))
+	assert.Equal(t, outputSpecs{{"out.json", "json"}, {"out.yml", "yaml"}, {"C:/out", "compact"}}, specs)
+
+	assert.Error(t, specs.Set("out.json:xml"))
+	assert.Error(t, specs.Set("out"))
+	assert.Error(t, specs.Set(":json"))
+	assert.Len(t, specs, 3)
+}
+
+func TestFormatYAML(t *testing.T) {
+	values, err := parseJSONStream([]byte(`{"name":"a b","n":1,"tags":["x","true"],"items":[{"k":null,"v":[]},[1,2]],"e":{}} "1"`))
+	assert.NoError(t, err)
+
+	expected := `name: a b
+"n": 1
+tags:
+  - x
+  - "true"
+items:
+  - k: null
+    v: []
+  - - 1
+    - 2
+e: {}
+---
+"1"
+`
+	assert.Equal(t, expected, string(formatYAML(values)))
+}
+
+func TestFormatRaw(t *testing.T) {
+	values, err := parseJSONStream([]byte(`"a\tb" {"c":"d"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\tb\n{\"c\":\"d\"}\n", string(formatRaw(values)))
+}
+
+func TestDocumentWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	doc := Document{
+		input:   `{"a":[1,2]}`,
+		options: Options{command: "./testdata/cat"},
+	}
+
+	specs := outputSpecs{
+		{filepath.Join(dir, "out.json"), "json"},
+		{filepath.Join(dir, "out.yaml"), "yaml"},
+		{filepath.Join(dir, "missing", "out.json"), "compact"},
+	}
+
+	errs := doc.WriteOutputs(specs)
+	assert.Len(t, errs, 1)
+
+	contents, err := os.ReadFile(specs[0].file)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n", string(contents))
+
+	contents, err = os.ReadFile(specs[1].file)
+	assert.NoError(t, err)
+	assert.Equal(t, "a:\n  - 1\n  - 2\n", string(contents))
+}
+
+func TestTrailingNewline(t *testing.T) {
+	var unset trailingNewline
+	assert.Equal(t, "a\n\n", string(unset.apply([]byte("a\n\n"))))
+	assert.Equal(t, "a", string(unset.apply([]byte("a"))))
+
+	var on, off trailingNewline
+	assert.NoError(t, on.Set("true"))
+	assert.NoError(t, off.Set("false"))
+	assert.Error(t, on.Set("maybe"))
+	assert.Equal(t, "true", on.String())
+	assert.Empty(t, unset.String())
+
+	assert.Equal(t, "a\n", string(on.apply([]byte("a"))))
+	assert.Equal(t, "a\n", string(on.apply([]byte("a\n"))))
+	assert.Equal(t, "a", string(off.apply([]byte("a\n"))))
+	assert.Equal(t, "a\n", string(off.apply([]byte("a\n\n"))))
+	assert.Empty(t, on.apply(nil))
+	assert.Empty(t, off.apply(nil))
+}
+
+func TestDocumentWriteToTrailingNewline(t *testing.T) {
+	doc := Document{input: "\"a\"\n", options: Options{command: "./testdata/cat"}}
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\"a\"\n"), buf.Bytes())
+
+	doc.options.trailingNewline = trailingNewline{set: true, value: false}
+	buf.Reset()
+	_, err = doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\"a\""), buf.Bytes())
+
+	doc.input = "\"a\""
+	doc.options.trailingNewline = trailingNewline{set: true, value: true}
+	buf.Reset()
+	_, err = doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\"a\"\n"), buf.Bytes())
+
+	dir := t.TempDir()
+	specs := outputSpecs{
+		{filepath.Join(dir, "out.json"), "compact"},
+		{filepath.Join(dir, "out.txt"), "raw"},
+	}
+
+	doc.input = "\"a\"\n"
+	doc.options.trailingNewline = trailingNewline{set: true, value: false}
+	assert.Empty(t, doc.WriteOutputs(specs))
+
+	contents, err := os.ReadFile(specs[0].file)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("\"a\""), contents)
+
+	contents, err = os.ReadFile(specs[1].file)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), contents)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+)
+
+// An action that can be run from the command palette
+type paletteAction struct {
+	name string
+
+	// The key bound to the action, if any
+	key string
+
+	run func()
+}
+
+// Return the actions whose names contain all of the words in the query,
+// ignoring case
+func filterActions(actions []paletteAction, query string) []paletteAction {
+	words := strings.Fields(strings.ToLower(query))
+
+	var matches []paletteAction
+outer:
+	for _, a := range actions {
+		name := strings.ToLower(a.name)
+		for _, w := range words {
+			if !strings.Contains(name, w) {
+				continue outer
+			}
+		}
+
+		matches = append(matches, a)
+	}
+
+	return matches
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterActions(t *testing.T) {
+	actions := []paletteAction{
+		{name: "Toggle diff mode", key: "Alt-D"},
+		{name: "Export output to HTML", key: "Alt-H"},
+		{name: "Save output to a file"},
+	}
+
+	names := func(actions []paletteAction) []string {
+		var names []string
+		for _, a := range actions {
+			names = append(names, a.name)
+		}
+		return names
+	}
+
+	assert.Len(t, filterActions(actions, ""), 3)
+	assert.Equal(t, []string{"Export output to HTML", "Save output to a file"}, names(filterActions(actions, "OUTPUT")))
+	assert.Equal(t, []string{"Save output to a file"}, names(filterActions(actions, "file out")))
+	assert.Empty(t, filterActions(actions, "compact"))
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Parse a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or begin with '/'", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: '~' must be followed by '0' or '1'", pointer)
+			}
+		}
+
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tok = strings.ReplaceAll(tok, "~0", "~")
+		tokens[i] = tok
+	}
+
+	return tokens, nil
+}
+
+// Report whether the reference token is a valid array index
+func isArrayIndex(tok string) bool {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return false
+	}
+
+	for _, c := range tok {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Convert a JSON Pointer into an equivalent jq path expression. Tokens that
+// look like array indices are used as an index when the value is an array and
+// as a key otherwise.
+func pointerToFilter(pointer string) (string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tokens) == 0 {
+		return ".", nil
+	}
+
+	// Steps are joined with pipes so that the type check in each step
+	// applies to the value at that point of the path
+	steps := make([]string, len(tokens))
+	for i, tok := range tokens {
+		key, _ := json.Marshal(tok)
+		if isArrayIndex(tok) {
+			steps[i] = fmt.Sprintf(`.[if type == "array" then %s else %s end]`, tok, key)
+		} else {
+			steps[i] = fmt.Sprintf(".[%s]", key)
+		}
+	}
+
+	return strings.Join(steps, " | "), nil
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePointer(t *testing.T) {
+	tokens, err := parsePointer("")
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	tokens, err = parsePointer("/foo/a~1b/m~0n/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "a/b", "m~n", ""}, tokens)
+
+	_, err = parsePointer("foo")
+	assert.Error(t, err)
+
+	_, err = parsePointer("/foo~2")
+	assert.Error(t, err)
+
+	_, err = parsePointer("/foo~")
+	assert.Error(t, err)
+}
+
+func TestPointerToFilter(t *testing.T) {
+	filter, err := pointerToFilter("")
+	assert.NoError(t, err)
+	assert.Equal(t, ".", filter)
+
+	filter, err = pointerToFilter(`/foo/"bar"`)
+	assert.NoError(t, err)
+	assert.Equal(t, `.["foo"] | .["\"bar\""]`, filter)
+
+	filter, err = pointerToFilter("/items/0/01")
+	assert.NoError(t, err)
+	assert.Equal(t, `.["items"] | .[if type == "array" then 0 else "0" end] | .["01"]`, filter)
+}
@@ -0,0 +1,214 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// What -preserve-order does when the filter reorders the keys of the input
+const (
+	PreserveOrderWarn    = "warn"
+	PreserveOrderRestore = "restore"
+)
+
+var preserveOrderModes = map[string]bool{
+	PreserveOrderWarn:    true,
+	PreserveOrderRestore: true,
+}
+
+// Message shown in batch mode when the output has keys in another order than
+// the input
+const reorderedNotice string = "The filter reorders the keys of the input, use -preserve-order restore to keep their order"
+
+// The order of the keys of the objects in the input. The keys of an object in
+// the results are in input order when they are in the order of an input
+// object with the same keys, or, if there is none, in the order in which the
+// keys first appear in the input.
+type keyOrder struct {
+	// The keys of the first input object with each set of keys, by the
+	// sorted keys
+	sets map[string][]string
+
+	// The position of each key where it first appears in the input
+	rank map[string]int
+}
+
+func newKeyOrder(values []*jsonValue) *keyOrder {
+	o := &keyOrder{sets: make(map[string][]string), rank: make(map[string]int)}
+	for _, v := range values {
+		o.add(v)
+	}
+
+	return o
+}
+
+func (o *keyOrder) add(v *jsonValue) {
+	if v.kind == jsonObject {
+		keys := make([]string, len(v.members))
+		for i, m := range v.members {
+			keys[i] = m.key
+			if _, ok := o.rank[m.key]; !ok {
+				o.rank[m.key] = len(o.rank)
+			}
+		}
+
+		if set := keySet(keys); o.sets[set] == nil {
+			o.sets[set] = keys
+		}
+	}
+
+	for _, item := range v.items {
+		o.add(item)
+	}
+
+	for _, m := range v.members {
+		o.add(m.value)
+	}
+}
+
+// Identify a set of keys whatever their order
+func keySet(keys []string) string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\x00")
+}
+
+// Put the keys of the value's objects, including the objects nested in it,
+// in input order. Keys that are not in the input keep their place. Reports
+// whether any key was moved.
+func (o *keyOrder) restore(v *jsonValue) bool {
+	moved := false
+	for _, item := range v.items {
+		moved = o.restore(item) || moved
+	}
+
+	for _, m := range v.members {
+		moved = o.restore(m.value) || moved
+	}
+
+	if v.kind != jsonObject {
+		return moved
+	}
+
+	keys := make([]string, len(v.members))
+	for i, m := range v.members {
+		keys[i] = m.key
+	}
+
+	if order := o.sets[keySet(keys)]; order != nil {
+		keys = order
+	} else {
+		// Only the keys known from the input are sorted, among the
+		// places they take
+		var places []int
+		var known []string
+		for i, k := range keys {
+			if _, ok := o.rank[k]; ok {
+				places = append(places, i)
+				known = append(known, k)
+			}
+		}
+
+		sort.SliceStable(known, func(i, j int) bool {
+			return o.rank[known[i]] < o.rank[known[j]]
+		})
+
+		keys = append([]string(nil), keys...)
+		for i, place := range places {
+			keys[place] = known[i]
+		}
+	}
+
+	members := make(map[string]*jsonValue, len(v.members))
+	for _, m := range v.members {
+		members[m.key] = m.value
+	}
+
+	for i, k := range keys {
+		if v.members[i].key != k {
+			moved = true
+		}
+
+		v.members[i] = jsonMember{key: k, value: members[k]}
+	}
+
+	return moved
+}
+
+// The key order of the input of the document
+func (d *Document) keyOrder() (*keyOrder, error) {
+	return d.keyOrders.get(d.input)
+}
+
+// Keeps the key order of the last input it was asked for, so that the input
+// is parsed once instead of on every run of the filter. A nil cache parses
+// the input every time.
+type keyOrderCache struct {
+	mu    sync.Mutex
+	input string
+	order *keyOrder
+	err   error
+	done  bool
+}
+
+func (c *keyOrderCache) get(input string) (*keyOrder, error) {
+	if c != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.done && c.input == input {
+			return c.order, c.err
+		}
+	}
+
+	var order *keyOrder
+	values, err := parseJSONStream([]byte(input))
+	if err == nil {
+		order = newKeyOrder(values)
+	}
+
+	if c != nil {
+		c.input, c.order, c.err, c.done = input, order, err, true
+	}
+
+	return order, err
+}
+
+// Report whether the results of the filter have keys in another order than
+// the input. Returns false if the filter fails.
+func (d *Document) reordersKeys(filter string) bool {
+	order, err := d.keyOrder()
+	if err != nil {
+		return false
+	}
+
+	c := Document{input: d.input, filter: filter, options: d.options}
+	values, err := d.results(c.EffectiveFilter(false), d.options)
+	if err != nil {
+		return false
+	}
+
+	moved := false
+	for _, v := range values {
+		moved = order.restore(v) || moved
+	}
+
+	return moved
+}
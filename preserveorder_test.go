@@ -0,0 +1,114 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyOrderRestore(t *testing.T) {
+	input, err := parseJSONStream([]byte(`[{"b": 1, "a": 2}, {"a": 3, "b": 4}] {"z": {"y": 1, "x": 2}, "c": 5}`))
+	assert.Nil(t, err)
+	order := newKeyOrder(input)
+
+	restore := func(results string) (string, bool) {
+		values, err := parseJSONStream([]byte(results))
+		assert.Nil(t, err)
+
+		moved := false
+		for _, v := range values {
+			moved = order.restore(v) || moved
+		}
+
+		return string(formatJSONStream(values, "")), moved
+	}
+
+	// Objects with the keys of an input object take its order
+	out, moved := restore(`{"a": 1, "b": 2} {"b": 2, "a": 1}`)
+	assert.Equal(t, `{"b":2,"a":1}`+"\n"+`{"b":2,"a":1}`+"\n", out)
+	assert.True(t, moved)
+
+	// Nested objects are restored too
+	out, moved = restore(`[{"c": 5, "z": {"x": 2, "y": 1}}]`)
+	assert.Equal(t, `[{"z":{"y":1,"x":2},"c":5}]`+"\n", out)
+	assert.True(t, moved)
+
+	// Other objects follow the order in which keys first appear, and
+	// keys that are not in the input keep their place
+	out, moved = restore(`{"c": 1, "new": 2, "b": 3, "y": 4}`)
+	assert.Equal(t, `{"b":3,"new":2,"c":1,"y":4}`+"\n", out)
+	assert.True(t, moved)
+
+	out, moved = restore(`{"new": 1, "z": 2, "c": 3} [1, "a"]`)
+	assert.Equal(t, `{"new":1,"z":2,"c":3}`+"\n"+`[1,"a"]`+"\n", out)
+	assert.False(t, moved)
+}
+
+func TestWriteToPreserveOrder(t *testing.T) {
+	doc := Document{
+		input:   `{"b": 1, "a": {"y": 1, "x": 2}}`,
+		filter:  ".",
+		options: Options{command: "./testdata/cat", preserveOrder: PreserveOrderRestore},
+	}
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"b":1,"a":{"y":1,"x":2}}`+"\n", buf.String())
+	assert.False(t, doc.reordersKeys(doc.filter))
+
+	doc.options.command = "./testdata/caterror"
+	_, err = doc.WriteTo(&buf)
+	assert.NotNil(t, err)
+	assert.False(t, doc.reordersKeys(doc.filter))
+
+	// The keys cannot be restored without JSON input
+	doc.options.command = "./testdata/cat"
+	doc.input = "not json"
+	_, err = doc.WriteTo(&buf)
+	assert.NotNil(t, err)
+}
+
+func TestKeyOrderCache(t *testing.T) {
+	cache := &keyOrderCache{}
+	order, err := cache.get(`{"b": 1, "a": 2}`)
+	assert.NoError(t, err)
+
+	// The same input is not parsed again
+	again, err := cache.get(`{"b": 1, "a": 2}`)
+	assert.NoError(t, err)
+	assert.Same(t, order, again)
+
+	other, err := cache.get(`{"a": 1}`)
+	assert.NoError(t, err)
+	assert.NotSame(t, order, other)
+
+	_, err = cache.get("not json")
+	assert.Error(t, err)
+
+	// Without a cache the input is parsed every time
+	var none *keyOrderCache
+	order, err = none.get(`{"b": 1}`)
+	assert.NoError(t, err)
+	again, err = none.get(`{"b": 1}`)
+	assert.NoError(t, err)
+	assert.NotSame(t, order, again)
+}
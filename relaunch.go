@@ -0,0 +1,94 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+)
+
+// Flags that describe how the original input is read and so are not passed
+// on when ijq is relaunched on its output
+var inputFlags = map[string]bool{
+	"f": true, "n": true, "s": true, "R": true,
+	"pointer": true, "join-mode": true, "batch": true, "pre": true,
+	"merge": true, "json5": true, "sort-by": true,
+}
+
+// A flag that can be given multiple times
+type repeatedValue interface {
+	items() []string
+}
+
+// The flags set on the command line, except for those describing the input
+func relaunchArgs(flags *flag.FlagSet) []string {
+	var args []string
+	flags.Visit(func(f *flag.Flag) {
+		if inputFlags[f.Name] {
+			return
+		}
+
+		if r, ok := f.Value.(repeatedValue); ok {
+			for _, item := range r.items() {
+				args = append(args, "-"+f.Name+"="+item)
+			}
+
+			return
+		}
+
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+
+	return args
+}
+
+// Run a new instance of ijq with the output of the document filter as its
+// input and the given arguments, and return its exit code
+func (d *Document) Relaunch(args []string) (int, error) {
+	c := Document{input: d.input, filter: d.filter, options: d.options}
+	c.options.rawOutput = false
+	c.options.forceColor = false
+	c.options.monochrome = true
+	c.options.seq = false
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return 0, err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(executable, append(args, ".")...)
+	cmd.Stdin = &buf
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+
+		return 0, err
+	}
+
+	return 0, nil
+}
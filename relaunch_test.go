@@ -0,0 +1,40 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelaunchArgs(t *testing.T) {
+	var options Options
+	flags := flag.NewFlagSet("ijq", flag.ContinueOnError)
+	flags.BoolVar(&options.compact, "c", false, "")
+	flags.BoolVar(&options.slurp, "s", false, "")
+	flags.StringVar(&options.historyFile, "H", "", "")
+	flags.String("f", "", "")
+	flags.Var(&options.outputs, "o", "")
+
+	err := flags.Parse([]string{"-c", "-s", "-H", "hist", "-f", "filter.jq", "-o", "a.json", "-o", "b:yaml", "file.json"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"-H=hist", "-c=true", "-o=a.json:json", "-o=b:yaml"}, relaunchArgs(flags))
+}
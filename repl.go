@@ -0,0 +1,127 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// The prompt shown before each filter in the REPL
+const replPrompt = "ijq> "
+
+// Lines in the REPL that quit it instead of being run as filters
+var replQuitCommands = map[string]bool{":q": true, ":quit": true}
+
+// Whether the input was read from standard input, which then cannot be used
+// to read filters
+func (d *Document) readsStdin() bool {
+	return !d.options.nullInput && (len(d.files) == 0 || contains(d.files, stdinName))
+}
+
+// Run the document filter, then read filters one per line from in and run
+// them, writing the output to out and the errors and the prompt to errOut.
+// An empty prompt shows no prompt. Returns the exit code of the last filter
+// once in is read or a quit command is read.
+func (d *Document) REPL(in io.Reader, out, errOut io.Writer, prompt string) int {
+	code := d.replRun(out, errOut)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+	for {
+		fmt.Fprint(errOut, prompt)
+		if !scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if replQuitCommands[line] {
+			return code
+		}
+
+		if line == "" {
+			continue
+		}
+
+		d.filter = line
+		code = d.replRun(out, errOut)
+	}
+
+	// End the line of the last prompt
+	if prompt != "" {
+		fmt.Fprintln(errOut)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	return code
+}
+
+func (d *Document) replRun(out, errOut io.Writer) int {
+	if _, err := d.WriteTo(out); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			errOut.Write(exitErr.Stderr)
+			return exitErr.ExitCode()
+		}
+
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	return 0
+}
+
+// Run the REPL on the terminal, used with -repl or when the terminal cannot
+// run the interface. Filters are read from standard input, or from the
+// terminal if the input was read from standard input.
+func runREPL(doc Document) int {
+	if doc.loadErr != nil {
+		log.Println(doc.loadErr)
+		return 1
+	}
+
+	in := os.Stdin
+	if doc.readsStdin() {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			log.Printf("cannot read filters: the input was read from standard input and %v\n", err)
+			return 1
+		}
+
+		defer tty.Close()
+		in = tty
+	}
+
+	prompt := ""
+	if term.IsTerminal(int(in.Fd())) {
+		prompt = replPrompt
+	}
+
+	doc.options.setColor(term.IsTerminal(int(os.Stdout.Fd())))
+	return doc.REPL(in, newEncodingWriter(os.Stdout, doc.options.encoding), os.Stderr, prompt)
+}
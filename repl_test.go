@@ -0,0 +1,52 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentREPL(t *testing.T) {
+	doc := &Document{input: `{"a": [1, 2]}`, filter: ".a", options: Options{command: "jq", compact: true, monochrome: true}}
+	var out, errOut bytes.Buffer
+	code := doc.REPL(strings.NewReader(".a[]\n\n  .a | length  \n.a.b\n"), &out, &errOut, "> ")
+	assert.Equal(t, 5, code)
+	assert.Equal(t, "[1,2]\n1\n2\n2\n", out.String())
+	assert.True(t, strings.HasPrefix(errOut.String(), "> > > > jq: error"))
+	assert.True(t, strings.HasSuffix(errOut.String(), "\n> \n"))
+
+	// A quit command stops reading filters
+	out.Reset()
+	errOut.Reset()
+	doc.filter = "."
+	code = doc.REPL(strings.NewReader(":q\n.a\n"), &out, &errOut, "")
+	assert.Zero(t, code)
+	assert.Equal(t, "{\"a\":[1,2]}\n", out.String())
+	assert.Empty(t, errOut.String())
+}
+
+func TestDocumentReadsStdin(t *testing.T) {
+	assert.True(t, (&Document{}).readsStdin())
+	assert.True(t, (&Document{files: []string{"a.json", stdinName}}).readsStdin())
+	assert.False(t, (&Document{files: []string{"a.json"}}).readsStdin())
+	assert.False(t, (&Document{options: Options{nullInput: true}}).readsStdin())
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Read the filters of a filter queue file, one per line. Blank lines and
+// lines holding only a comment are skipped.
+func readFilterQueue(data string) []string {
+	var filters []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		filters = append(filters, line)
+	}
+
+	return filters
+}
+
+func stringValue(s string) *jsonValue {
+	return &jsonValue{kind: jsonString, str: s}
+}
+
+// Run each filter on the document input and return a report holding for
+// each filter either its output values or its error message. The report is
+// an array, so that the filters keep their order. Also returns whether all
+// of the filters succeeded.
+func (d *Document) Report(filters []string) (*jsonValue, bool) {
+	report := &jsonValue{kind: jsonArray}
+	ok := true
+	for _, filter := range filters {
+		c := Document{input: d.input, filter: filter, options: d.options}
+		record := &jsonValue{kind: jsonObject}
+		record.members = append(record.members, jsonMember{"filter", stringValue(filter)})
+
+		values, err := c.Values()
+		if err != nil {
+			msg := err.Error()
+			if exitErr, isExit := err.(*exec.ExitError); isExit {
+				msg = strings.TrimSpace(string(exitErr.Stderr))
+			}
+
+			record.members = append(record.members, jsonMember{"error", stringValue(msg)})
+			ok = false
+		} else {
+			output := &jsonValue{kind: jsonArray, items: values}
+			record.members = append(record.members, jsonMember{"output", output})
+		}
+
+		report.items = append(report.items, record)
+	}
+
+	return report, ok
+}
+
+// Run the filters of the filter queue file on the document input and write
+// the report to standard output. Returns the exit status, which is 1 if any
+// of the filters failed.
+func runReport(doc Document) int {
+	if doc.loadErr != nil {
+		log.Println(doc.loadErr)
+		return 1
+	}
+
+	data, err := os.ReadFile(doc.options.filterQueue)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	report, ok := doc.Report(readFilterQueue(string(data)))
+
+	var buf bytes.Buffer
+	report.writeJSON(&buf, "  ", 0)
+	buf.WriteByte('\n')
+	if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	if !ok {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFilterQueue(t *testing.T) {
+	filters := readFilterQueue(".a\n\n  # a comment\n.b | length  \n")
+	assert.Equal(t, []string{".a", ".b | length"}, filters)
+	assert.Empty(t, readFilterQueue(""))
+}
+
+func TestDocumentReport(t *testing.T) {
+	doc := Document{input: "1 \"a\"", options: Options{command: "./testdata/cat"}}
+	report, ok := doc.Report([]string{"."})
+	assert.True(t, ok)
+
+	var buf bytes.Buffer
+	report.writeJSON(&buf, "", 0)
+	assert.Equal(t, `[{"filter":".","output":[1,"a"]}]`, buf.String())
+
+	doc.options.command = "./testdata/caterror"
+	doc.input = "jq: error\n"
+	report, ok = doc.Report([]string{".x"})
+	assert.False(t, ok)
+
+	buf.Reset()
+	report.writeJSON(&buf, "", 0)
+	assert.Equal(t, `[{"filter":".x","error":"jq: error"}]`, buf.String())
+}
@@ -0,0 +1,185 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The format strings of jq, which are always arguments rather than response
+// files
+var jqFormats = map[string]bool{
+	"@text": true, "@json": true, "@html": true, "@uri": true, "@csv": true, "@tsv": true,
+	"@sh": true, "@base64": true, "@base64d": true, "@base32": true, "@base32d": true,
+}
+
+// Replace each argument of the form @file among the options with the
+// arguments read from the file, see readResponseFile. Response files can
+// refer to other response files. The values of the flags, the arguments
+// after the first argument that is not an option, and the arguments after --
+// are kept as they are, so that @file can still be given as the value of a
+// flag or as the filter. An argument naming a file that does not exist is
+// kept as is too, as are jq's format strings such as @csv.
+func expandResponseFiles(args []string, flags *flag.FlagSet) ([]string, error) {
+	e := responseExpander{flags: flags, open: map[string]bool{}}
+	return e.expand(args)
+}
+
+type responseExpander struct {
+	flags *flag.FlagSet
+
+	// The response files being read, to detect a file including itself
+	open map[string]bool
+
+	// The next argument is the value of a flag
+	value bool
+
+	// The options ended, so no more arguments are expanded
+	done bool
+}
+
+func (e *responseExpander) expand(args []string) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		if e.done || e.value {
+			e.value = false
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		if !strings.HasPrefix(arg, "@") || len(arg) == 1 || jqFormats[arg] {
+			e.option(arg)
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		path := arg[1:]
+		if e.open[path] {
+			return nil, fmt.Errorf("%s: response file includes itself", path)
+		}
+
+		lines, err := readResponseFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			e.option(arg)
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		e.open[path] = true
+		nested, err := e.expand(lines)
+		delete(e.open, path)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, nested...)
+	}
+
+	return expanded, nil
+}
+
+// Note an argument that is not a response file: the flags that are not
+// boolean take the next argument as their value, and the options end at --
+// and at the first argument that is not a flag, like they do for the flag
+// package
+func (e *responseExpander) option(arg string) {
+	if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+		e.done = true
+		return
+	}
+
+	name := strings.TrimPrefix(arg[1:], "-")
+	if strings.Contains(name, "=") {
+		return
+	}
+
+	f := e.flags.Lookup(name)
+	if f == nil {
+		return
+	}
+
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+		e.value = true
+	}
+}
+
+// Read the arguments in a response file, one per line. Leading and trailing
+// white space is removed, and blank lines and lines beginning with # are
+// skipped. A line can be quoted to keep its white space or to begin it with
+// #: single quotes are taken literally and double quotes allow Go escape
+// sequences such as \n and \".
+func readResponseFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		arg, err := unquoteResponseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+
+		args = append(args, arg)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+func unquoteResponseLine(line string) (string, error) {
+	switch line[0] {
+	case '\'':
+		if len(line) < 2 || line[len(line)-1] != '\'' {
+			return "", errors.New("unterminated single quote")
+		}
+
+		return line[1 : len(line)-1], nil
+	case '"':
+		arg, err := strconv.Unquote(line)
+		if err != nil {
+			return "", errors.New("invalid double quoted argument")
+		}
+
+		return arg, nil
+	}
+
+	return line, nil
+}
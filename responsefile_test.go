@@ -0,0 +1,93 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The flags used to tell the options from their values
+func responseFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("ijq", flag.ContinueOnError)
+	flags.Bool("S", false, "")
+	flags.Bool("c", false, "")
+	flags.String("o", "", "")
+	flags.String("var", "", "")
+	return flags
+}
+
+func TestExpandResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	nested := filepath.Join(dir, "nested")
+	contents := "# Variables\n-var\n  name=file  \n-o\n'  padded value  '\n-o\n\"tab\\there\"\n-o\n'# not a comment'\n\n@" + nested + "\n"
+	assert.NoError(t, os.WriteFile(args, []byte(contents), 0644))
+	assert.NoError(t, os.WriteFile(nested, []byte("-c\n"), 0644))
+
+	expanded, err := expandResponseFiles([]string{"-S", "@" + args, "@csv", "@", "file.json"}, responseFlags())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-S", "-var", "name=file", "-o", "  padded value  ", "-o", "tab\there", "-o", "# not a comment", "-c",
+		"@csv", "@", "file.json",
+	}, expanded)
+
+	loop := filepath.Join(dir, "loop")
+	assert.NoError(t, os.WriteFile(loop, []byte("@"+loop+"\n"), 0644))
+	_, err = expandResponseFiles([]string{"@" + loop}, responseFlags())
+	assert.EqualError(t, err, loop+": response file includes itself")
+
+	bad := filepath.Join(dir, "bad")
+	assert.NoError(t, os.WriteFile(bad, []byte("-c\n'unterminated\n"), 0644))
+	_, err = expandResponseFiles([]string{"@" + bad}, responseFlags())
+	assert.EqualError(t, err, bad+":2: unterminated single quote")
+}
+
+func TestExpandResponseFilesOptionsOnly(t *testing.T) {
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	assert.NoError(t, os.WriteFile(args, []byte("-c\n"), 0644))
+
+	// The values of flags are kept
+	expanded, err := expandResponseFiles([]string{"-o", "@" + args, "--o=@" + args, "@" + args}, responseFlags())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-o", "@" + args, "--o=@" + args, "-c"}, expanded)
+
+	// As are the filter and the arguments after it, and those after --
+	expanded, err = expandResponseFiles([]string{"-S", ".a", "@" + args}, responseFlags())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-S", ".a", "@" + args}, expanded)
+
+	expanded, err = expandResponseFiles([]string{"@" + args, "--", "@" + args}, responseFlags())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-c", "--", "@" + args}, expanded)
+
+	// A format string is the filter even if a file has its name
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	assert.NoError(t, os.WriteFile("csv", []byte("-c\n"), 0644))
+	expanded, err = expandResponseFiles([]string{"@csv", "data.json"}, responseFlags())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"@csv", "data.json"}, expanded)
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Builtins, keywords, and variables that read data other than the input,
+// write to the terminal, or load modules, and so are rejected with
+// -no-side-effects
+var sideEffectNames = map[string]bool{
+	"input":           true,
+	"inputs":          true,
+	"debug":           true,
+	"stderr":          true,
+	"halt_error":      true,
+	"input_filename":  true,
+	"env":             true,
+	"$ENV":            true,
+	"import":          true,
+	"include":         true,
+	"modulemeta":      true,
+	"get_search_list": true,
+}
+
+// The first version of jq that accepts -- before the filter
+var endOfOptionsVersion = jqVersion{1, 7, 1}
+
+// Return the code of each interpolation in a string literal token
+func interpolations(literal string) []string {
+	var parts []string
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' || i+1 >= len(literal) {
+			continue
+		}
+
+		if literal[i+1] != '(' {
+			i++
+			continue
+		}
+
+		start := i + 2
+		depth := 1
+		for i = start; i < len(literal) && depth > 0; i++ {
+			switch literal[i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+		}
+
+		parts = append(parts, literal[start:i-1])
+		i--
+	}
+
+	return parts
+}
+
+// Return the names in a filter that are rejected with -no-side-effects
+func sideEffects(filter string) []string {
+	found := map[string]bool{}
+	tokens := tokenizeFilter(filter)
+	for i, tok := range tokens {
+		switch tok.kind {
+		case tokenString:
+			for _, code := range interpolations(tok.text) {
+				for _, name := range sideEffects(code) {
+					found[name] = true
+				}
+			}
+		case tokenIdent, tokenVariable:
+			// Identifiers followed by a colon are object keys
+			if tok.kind == tokenIdent && i+1 < len(tokens) && tokens[i+1].text == ":" {
+				continue
+			}
+
+			if sideEffectNames[tok.text] {
+				found[tok.text] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range found {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Check that a filter may be run with -no-side-effects. If jq does not accept
+// -- before the filter, a filter starting with - is rejected since jq would
+// read it as an option.
+func checkSandbox(filter string, endOfOptions bool) error {
+	if names := sideEffects(filter); len(names) > 0 {
+		return fmt.Errorf("the filter uses %s, which is not allowed with -no-side-effects", strings.Join(names, ", "))
+	}
+
+	if !endOfOptions && strings.HasPrefix(filter, "-") {
+		return fmt.Errorf("a filter starting with - is not allowed with -no-side-effects")
+	}
+
+	return nil
+}
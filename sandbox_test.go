@@ -0,0 +1,49 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolations(t *testing.T) {
+	assert.Equal(t, []string{"input", "(1 + 2)"}, interpolations(`"a\(input) b \((1 + 2))"`))
+	assert.Empty(t, interpolations(`"a\\(input)"`))
+	assert.Empty(t, interpolations(`"plain"`))
+}
+
+func TestSideEffects(t *testing.T) {
+	assert.Empty(t, sideEffects(".input | .debug"))
+	assert.Empty(t, sideEffects(`{input: 1, "env": 2} | $input`))
+	assert.Empty(t, sideEffects(`"input" # debug`))
+	assert.Equal(t, []string{"input"}, sideEffects("[., input]"))
+	assert.Equal(t, []string{"$ENV", "env"}, sideEffects("env.HOME, $ENV.HOME"))
+	assert.Equal(t, []string{"debug"}, sideEffects(`"\(debug)"`))
+	assert.Equal(t, []string{"halt_error"}, sideEffects(`"oops" | halt_error(5)`))
+	assert.Equal(t, []string{"halt_error"}, sideEffects(`if . then halt_error else . end`))
+	assert.Equal(t, []string{"import"}, sideEffects(`import "a" as a; .`))
+}
+
+func TestCheckSandbox(t *testing.T) {
+	assert.NoError(t, checkSandbox(".a", false))
+	assert.EqualError(t, checkSandbox("inputs, debug", true), "the filter uses debug, inputs, which is not allowed with -no-side-effects")
+	assert.Error(t, checkSandbox("-1", false))
+	assert.NoError(t, checkSandbox("-1", true))
+}
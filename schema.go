@@ -0,0 +1,156 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Only the first few errors of the output are reported, which is usually
+// enough to see what is wrong while the filter is edited
+const maxSchemaErrors = 5
+
+// Convert a parsed value to the form the schema validator expects, which
+// is that of encoding/json with numbers kept as json.Number
+func schemaInstance(v *jsonValue) interface{} {
+	switch v.kind {
+	case jsonNull:
+		return nil
+	case jsonBool:
+		return v.scalar == "true"
+	case jsonNumber:
+		return json.Number(v.scalar)
+	case jsonString:
+		return v.str
+	case jsonArray:
+		items := make([]interface{}, len(v.items))
+		for i, item := range v.items {
+			items[i] = schemaInstance(item)
+		}
+
+		return items
+	}
+
+	members := make(map[string]interface{}, len(v.members))
+	for _, m := range v.members {
+		members[m.key] = schemaInstance(m.value)
+	}
+
+	return members
+}
+
+// Convert the location of an error in a value, a JSON pointer, to a jq path.
+// Tokens are array indices where the value is an array.
+func instancePath(v *jsonValue, pointer string) string {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return pointer
+	}
+
+	var frames []pathFrame
+	for _, tok := range tokens {
+		if v != nil && v.kind == jsonArray {
+			i, _ := strconv.Atoi(tok)
+			frames = append(frames, pathFrame{array: true, index: i})
+			if i < len(v.items) {
+				v = v.items[i]
+			} else {
+				v = nil
+			}
+
+			continue
+		}
+
+		frames = append(frames, pathFrame{key: tok})
+		var member *jsonValue
+		if v != nil {
+			for _, m := range v.members {
+				if m.key == tok {
+					member = m.value
+					break
+				}
+			}
+		}
+
+		v = member
+	}
+
+	return formatPath(frames)
+}
+
+// Append the errors without causes, which are the ones that say what is
+// wrong rather than which part of the schema failed
+func leafErrors(errs []*jsonschema.ValidationError, e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(e.Causes) == 0 {
+		return append(errs, e)
+	}
+
+	for _, cause := range e.Causes {
+		errs = leafErrors(errs, cause)
+	}
+
+	return errs
+}
+
+// Validate each value against the schema and return a message with the jq
+// path for each error, at most max in total. When there are several values
+// each message also says which value it is about.
+func schemaErrors(schema *jsonschema.Schema, values []*jsonValue, max int) []string {
+	var messages []string
+	for i, v := range values {
+		err := schema.Validate(schemaInstance(v))
+		var verr *jsonschema.ValidationError
+		if err == nil || !errors.As(err, &verr) {
+			if err != nil {
+				messages = append(messages, err.Error())
+			}
+
+			continue
+		}
+
+		for _, e := range leafErrors(nil, verr) {
+			if len(messages) == max {
+				return append(messages, "...")
+			}
+
+			message := instancePath(v, e.InstanceLocation) + ": " + e.Message
+			if len(values) > 1 {
+				message = fmt.Sprintf("value %d: %s", i+1, message)
+			}
+
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// Validate the output of the filter against the schema
+func (d *Document) ValidateSchema(schema *jsonschema.Schema) ([]string, error) {
+	values, err := d.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaErrors(schema, values, maxSchemaErrors), nil
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstancePath(t *testing.T) {
+	values, err := parseJSONStream([]byte(`{"a": [{"0": 1}], "b c": {}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, ".", instancePath(values[0], ""))
+	assert.Equal(t, ".a[0].\"0\"", instancePath(values[0], "/a/0/0"))
+	assert.Equal(t, ".\"b c\".d", instancePath(values[0], "/b c/d"))
+}
+
+func TestSchemaErrors(t *testing.T) {
+	schema, err := jsonschema.CompileString("schema.json", `{
+		"type": "object",
+		"required": ["id"],
+		"properties": {"tags": {"type": "array", "items": {"type": "string"}}}
+	}`)
+	assert.NoError(t, err)
+
+	values, err := parseJSONStream([]byte(`{"id": 1, "tags": ["a"]}`))
+	assert.NoError(t, err)
+	assert.Empty(t, schemaErrors(schema, values, maxSchemaErrors))
+
+	values, err = parseJSONStream([]byte(`{"tags": ["a", 2]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		".: missing properties: 'id'",
+		".tags[1]: expected string, but got number",
+	}, schemaErrors(schema, values, maxSchemaErrors))
+
+	// Messages say which value they are about when there are several, and
+	// only the first errors are reported
+	values, err = parseJSONStream([]byte(`{"id": 1} [] {"id": 2, "tags": [1, 2]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"value 2: .: expected object, but got array",
+		"value 3: .tags[0]: expected string, but got number",
+		"...",
+	}, schemaErrors(schema, values, 2))
+}
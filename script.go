@@ -0,0 +1,141 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Words that need no quoting in a shell command
+var plainShellWordPattern = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// Quote a word for the shell. Single quotes keep everything, including
+// newlines, except single quotes themselves.
+func shellQuote(word string) string {
+	if plainShellWordPattern.MatchString(word) {
+		return word
+	}
+
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+func shellJoin(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = shellQuote(w)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// Return a shell script that runs jq with the options and the effective
+// filter of the document on the original input: the input files, or the
+// standard input of the script if the input was read from standard input.
+// Colors are left for jq to decide. The input is passed through the same
+// steps as in ijq: the document header is skipped, files are joined into an
+// array with -join-mode array, the input is run through the -pre command,
+// and the input values are merged with -merge.
+func (d *Document) Script() string {
+	opts := d.options
+	opts.forceColor = false
+	opts.monochrome = false
+	opts.vars = nil
+
+	command := shellJoin(append([]string{opts.command}, opts.ToSlice()...))
+	for _, fv := range d.options.vars {
+		// A file holding a single value is read when the script runs,
+		// like the files of the other variables, unless the value was
+		// changed in ijq
+		if fv.edited {
+			command += " --argjson " + shellQuote(fv.name) + " " + shellQuote(fv.value)
+		} else if fv.value != "" {
+			command += " --argjson " + shellQuote(fv.name) + ` "$(cat ` + shellQuote(fv.file) + `)"`
+		} else {
+			command += " " + shellJoin([]string{"--slurpfile", fv.name, fv.file})
+		}
+	}
+
+	if opts.endOfOptions {
+		command += " --"
+	}
+
+	command += " " + shellQuote(d.EffectiveFilter(false))
+
+	var pipeline []string
+	piped := d.headerFilter != "" || opts.joinMode == JoinModeArray || opts.preCommand != "" || opts.mergeMode != "" || contains(d.files, stdinName)
+	switch {
+	case opts.nullInput:
+	case len(d.files) > 0 && !piped:
+		command += " " + shellJoin(d.files)
+	case len(d.files) > 0 && d.headerFilter != "":
+		// The header is the first line of the first file
+		read := "tail -n +2 " + shellQuote(d.files[0])
+		if len(d.files) > 1 {
+			read = "{ " + read + "; cat " + shellJoin(d.files[1:]) + "; }"
+		}
+
+		pipeline = append(pipeline, read)
+	case len(d.files) > 0:
+		pipeline = append(pipeline, "cat "+shellJoin(d.files))
+	}
+
+	if !opts.nullInput && opts.joinMode == JoinModeArray && len(d.files) > 0 {
+		pipeline = append(pipeline, shellJoin([]string{opts.command, "-s", "."}))
+	}
+
+	if !opts.nullInput && opts.preCommand != "" {
+		pipeline = append(pipeline, shellJoin([]string{"sh", "-c", opts.preCommand}))
+	}
+
+	if !opts.nullInput && opts.mergeMode != "" {
+		pipeline = append(pipeline, shellJoin([]string{opts.command, "-s", "-c", mergeFilters[opts.mergeMode]}))
+	}
+
+	pipeline = append(pipeline, command)
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("# Generated by ijq\n")
+	if (len(d.files) == 0 || contains(d.files, stdinName)) && !opts.nullInput {
+		sb.WriteString("# The input is read from standard input\n")
+	}
+
+	if d.edited && !opts.nullInput {
+		sb.WriteString("# The input was edited in ijq, but the script reads the original input\n")
+	}
+
+	if opts.json5 && !opts.nullInput {
+		sb.WriteString("# The input is JSON5, which jq cannot read: convert it to JSON first\n")
+	}
+
+	sb.WriteString(strings.Join(pipeline, " |\n\t") + "\n")
+	return sb.String()
+}
+
+// Write the script reproducing the output of the document to a file and make
+// it executable
+func (d *Document) WriteScript(path string) error {
+	if err := os.WriteFile(path, []byte(d.Script()), 0755); err != nil {
+		return err
+	}
+
+	// The mode given to WriteFile does not apply to existing files
+	return os.Chmod(path, 0755)
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "data.json", shellQuote("data.json"))
+	assert.Equal(t, "'my file.json'", shellQuote("my file.json"))
+	assert.Equal(t, `'.a | "it'\''s"'`, shellQuote(`.a | "it's"`))
+	assert.Equal(t, "''", shellQuote(""))
+}
+
+func TestDocumentScript(t *testing.T) {
+	doc := &Document{
+		filter:  ".[] | .name",
+		files:   []string{"a.json", "my b.json"},
+		options: Options{command: "jq", compact: true, forceColor: true, joinMode: JoinModeStream},
+	}
+	assert.Equal(t, "#!/bin/sh\n# Generated by ijq\njq -c '.[] | .name' a.json 'my b.json'\n", doc.Script())
+
+	doc.files = nil
+	doc.options.prefix = ".items"
+	assert.Equal(t, "#!/bin/sh\n# Generated by ijq\n# The input is read from standard input\njq -c '.items | (.[] | .name\n)'\n", doc.Script())
+
+	doc = &Document{
+		filter:       ".",
+		files:        []string{"a.json", "b.json"},
+		headerFilter: ".x",
+		options: Options{
+			command:    "jq",
+			joinMode:   JoinModeArray,
+			preCommand: "grep -v '^#'",
+			vars:       fileVars{{name: "v", file: "v.json", value: "1"}, {name: "w", file: "w.json"}, {name: "x", value: "\"it's\"", edited: true}},
+		},
+	}
+	assert.Equal(t, `#!/bin/sh
+# Generated by ijq
+{ tail -n +2 a.json; cat b.json; } |
+	jq -s . |
+	sh -c 'grep -v '\''^#'\''' |
+	jq --argjson v "$(cat v.json)" --slurpfile w w.json --argjson x '"it'\''s"' .
+`, doc.Script())
+
+	doc = &Document{
+		filter:  ".a",
+		files:   []string{"a.json", "b.json"},
+		options: Options{command: "jq", mergeMode: MergeDeep},
+	}
+	assert.Equal(t, `#!/bin/sh
+# Generated by ijq
+cat a.json b.json |
+	jq -s -c 'reduce .[] as $x ({}; . * $x)' |
+	jq .a
+`, doc.Script())
+
+	doc = &Document{
+		filter:  ".a",
+		files:   []string{"a.json5"},
+		options: Options{command: "jq", json5: true},
+	}
+	assert.Equal(t, "#!/bin/sh\n# Generated by ijq\n# The input is JSON5, which jq cannot read: convert it to JSON first\njq .a a.json5\n", doc.Script())
+
+	doc = &Document{filter: ".a", files: []string{"a.json"}, edited: true, options: Options{command: "jq"}}
+	assert.Equal(t, "#!/bin/sh\n# Generated by ijq\n# The input was edited in ijq, but the script reads the original input\njq .a a.json\n", doc.Script())
+}
+
+func TestDocumentWriteScript(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in put.json")
+	doc := &Document{filter: ".a b", files: []string{input}, options: Options{command: "echo"}}
+	path := filepath.Join(dir, "out.sh")
+	assert.NoError(t, doc.WriteScript(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// echo prints its arguments as they were passed to jq
+	out, err := exec.Command(path).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, ".a b "+input+"\n", string(out))
+}
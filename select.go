@@ -0,0 +1,80 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Operators offered by the select() builder
+var selectOperators = []string{"==", "!=", "<", "<=", ">", ">=", "contains"}
+
+// Return the jq expression for a value entered in the select() builder.
+// Valid JSON, such as numbers, booleans, null, and quoted strings, is used
+// as is and anything else is quoted as a string.
+func selectValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value != "" && json.Valid([]byte(value)) {
+		return value
+	}
+
+	return quoteJSON(value)
+}
+
+// Build a select() expression comparing field with value
+func buildSelect(field, op, value string) string {
+	if op == "contains" {
+		return "select(" + field + " | contains(" + selectValue(value) + "))"
+	}
+
+	return "select(" + field + " " + op + " " + selectValue(value) + ")"
+}
+
+// Return the filter whose output the select() expression is applied to when
+// it is inserted after before. The result has the form of the prefixes used
+// for key completion.
+func selectContext(before string) string {
+	context := strings.TrimSpace(before)
+	context = strings.TrimSpace(strings.TrimSuffix(context, "|"))
+	if context == "." {
+		return ""
+	}
+
+	return context
+}
+
+// Return the text to insert after before so that expr is piped the output
+// of the filter before it
+func selectInsertion(before, expr string) string {
+	trimmed := strings.TrimSpace(before)
+	switch {
+	case trimmed == "":
+		return expr
+	case strings.HasSuffix(trimmed, "|"):
+		if strings.HasSuffix(before, " ") {
+			return expr
+		}
+
+		return " " + expr
+	case strings.HasSuffix(before, " "):
+		return "| " + expr
+	default:
+		return " | " + expr
+	}
+}
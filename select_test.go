@@ -0,0 +1,56 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectValue(t *testing.T) {
+	assert.Equal(t, "42", selectValue("42"))
+	assert.Equal(t, "true", selectValue(" true "))
+	assert.Equal(t, "null", selectValue("null"))
+	assert.Equal(t, `"a b"`, selectValue(`"a b"`))
+	assert.Equal(t, `"alice"`, selectValue("alice"))
+	assert.Equal(t, `""`, selectValue(""))
+	assert.Equal(t, `"say \"hi\""`, selectValue(`say "hi"`))
+}
+
+func TestBuildSelect(t *testing.T) {
+	assert.Equal(t, "select(.age > 30)", buildSelect(".age", ">", "30"))
+	assert.Equal(t, `select(.name == "bob")`, buildSelect(".name", "==", "bob"))
+	assert.Equal(t, `select(.tags | contains(["x"]))`, buildSelect(".tags", "contains", `["x"]`))
+}
+
+func TestSelectContext(t *testing.T) {
+	assert.Equal(t, "", selectContext(""))
+	assert.Equal(t, "", selectContext(". | "))
+	assert.Equal(t, ".items[]", selectContext(".items[] | "))
+	assert.Equal(t, ".items[]", selectContext(".items[]"))
+}
+
+func TestSelectInsertion(t *testing.T) {
+	expr := "select(.a == 1)"
+	assert.Equal(t, expr, selectInsertion("", expr))
+	assert.Equal(t, " | "+expr, selectInsertion(".[]", expr))
+	assert.Equal(t, "| "+expr, selectInsertion(".[] ", expr))
+	assert.Equal(t, " "+expr, selectInsertion(".[] |", expr))
+	assert.Equal(t, expr, selectInsertion(".[] | ", expr))
+}
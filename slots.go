@@ -0,0 +1,51 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+// Number of filter slots that can be switched between
+const filterSlots int = 9
+
+// Filter slots hold alternative filters over the same input, and remember
+// the output each filter last showed. A slot that has not been used yet
+// starts with the filter and the output of the slot that was active when it
+// was first selected.
+type slotSet struct {
+	filters [filterSlots]string
+	outputs [filterSlots]string
+	used    [filterSlots]bool
+	current int
+}
+
+func newSlotSet() *slotSet {
+	s := &slotSet{}
+	s.used[0] = true
+	return s
+}
+
+// Store the filter and the output of the current slot and switch to the
+// given slot. Returns the filter and the remembered output of that slot.
+func (s *slotSet) Switch(slot int, filter, output string) (string, string) {
+	s.filters[s.current], s.outputs[s.current] = filter, output
+	if !s.used[slot] {
+		s.filters[slot], s.outputs[slot] = filter, output
+		s.used[slot] = true
+	}
+
+	s.current = slot
+	return s.filters[slot], s.outputs[slot]
+}
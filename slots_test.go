@@ -0,0 +1,47 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotSetSwitch(t *testing.T) {
+	s := newSlotSet()
+
+	// A new slot starts with the filter and output of the current slot
+	filter, output := s.Switch(1, ".a", "1")
+	assert.Equal(t, ".a", filter)
+	assert.Equal(t, "1", output)
+	assert.Equal(t, 1, s.current)
+
+	// Each slot remembers its own filter and output
+	filter, output = s.Switch(0, ".b", "2")
+	assert.Equal(t, ".a", filter)
+	assert.Equal(t, "1", output)
+
+	filter, output = s.Switch(1, ".a | tostring", "\"1\"")
+	assert.Equal(t, ".b", filter)
+	assert.Equal(t, "2", output)
+
+	filter, output = s.Switch(0, ".b", "2")
+	assert.Equal(t, ".a | tostring", filter)
+	assert.Equal(t, "\"1\"", output)
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Message shown when -sort-by cannot sort the input
+const unsortedNotice string = "The input is not an array, -sort-by leaves it unsorted"
+
+// The filter sorting the input by the -sort-by path. Values that are not
+// arrays are left as they are.
+func sortByFilter(path string) string {
+	return fmt.Sprintf(`if type == "array" then sort_by%s else . end`, parenthesize(path))
+}
+
+// Check that the -sort-by path compiles, which jq reports with exit status 3
+// otherwise. The path is compiled without being run.
+func checkSortPath(command, path string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(command, "-n", "if false then "+sortByFilter(path)+" else empty end")
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 3 {
+		return fmt.Errorf("invalid -sort-by path %q: %s", path, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// Report whether each input value is an array that -sort-by sorts. The input
+// is checked at the pointer, if any.
+func (d *Document) sortable() bool {
+	count, ok := d.count(`select(type != "array")`)
+	return ok && count == 0
+}
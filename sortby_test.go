@@ -0,0 +1,56 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortByFilter(t *testing.T) {
+	assert.Equal(t, "if type == \"array\" then sort_by(.date\n) else . end", sortByFilter(".date"))
+
+	doc := &Document{filter: ".[0]", options: Options{sortBy: ".date", prefix: ".items", selection: ".[1:]"}}
+	assert.Equal(t, ".items | (if type == \"array\" then sort_by(.date\n) else . end | (.[1:] | (.[0]\n)\n)\n)", doc.EffectiveFilter(false))
+
+	// The input pane is sorted too, so that its paths are those the
+	// filter sees
+	opts := Options{command: "jq", sortBy: ".date", inputView: InputViewPlain}
+	assert.Equal(t, ".date", opts.inputViewOptions().sortBy)
+}
+
+func TestCheckSortPath(t *testing.T) {
+	assert.NoError(t, checkSortPath("./testdata/cat", ".date"))
+	assert.NoError(t, checkSortPath("./testdata/caterror", ".date"))
+
+	err := checkSortPath("./testdata/compileerror", ".date)")
+	assert.EqualError(t, err, `invalid -sort-by path ".date)": jq: 1 compile error`)
+}
+
+func TestDocumentSortable(t *testing.T) {
+	// The count of values that are not arrays is the output of cat
+	doc := &Document{input: "0", options: Options{command: "./testdata/cat", sortBy: ".date"}}
+	assert.True(t, doc.sortable())
+
+	doc.input = "2"
+	assert.False(t, doc.sortable())
+
+	doc.options.command = "./testdata/caterror"
+	assert.False(t, doc.sortable())
+}
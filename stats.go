@@ -0,0 +1,130 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Inputs larger than this many bytes are not parsed for statistics
+const statsMaxSize int = 16 << 20
+
+func kindName(k jsonKind) string {
+	return [...]string{"null", "boolean", "number", "string", "array", "object"}[k]
+}
+
+// Format a count of things with the singular or plural form of the noun
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
+
+// Format a size in bytes for display
+func formatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Count the values nested in v, including v itself, and the maximum nesting
+// depth
+func countValues(v *jsonValue) (count, depth int) {
+	count = 1
+	var children []*jsonValue
+	switch v.kind {
+	case jsonArray:
+		children = v.items
+	case jsonObject:
+		for _, m := range v.members {
+			children = append(children, m.value)
+		}
+	}
+
+	for _, c := range children {
+		n, d := countValues(c)
+		count += n
+		if d > depth {
+			depth = d
+		}
+	}
+
+	if len(children) > 0 {
+		depth++
+	}
+
+	return count, depth
+}
+
+// Summarize the input: the type of the top-level value (or the number of
+// values in a stream), the number of keys or items, the total number of
+// values, the nesting depth, and the size
+func inputStats(input string) string {
+	if len(input) > statsMaxSize {
+		return fmt.Sprintf("%s (too large for statistics)", formatSize(len(input)))
+	}
+
+	values, err := parseJSONStream([]byte(input))
+	if err != nil {
+		return fmt.Sprintf("Input is not valid JSON: %v", err)
+	}
+
+	var parts []string
+	if len(values) == 1 {
+		v := values[0]
+		switch v.kind {
+		case jsonObject:
+			parts = append(parts, "object ("+plural(len(v.members), "key", "keys")+")")
+		case jsonArray:
+			parts = append(parts, "array ("+plural(len(v.items), "item", "items")+")")
+		default:
+			parts = append(parts, kindName(v.kind))
+		}
+	} else {
+		parts = append(parts, "stream ("+plural(len(values), "value", "values")+")")
+	}
+
+	total, depth := 0, 0
+	for _, v := range values {
+		n, d := countValues(v)
+		total += n
+		if d > depth {
+			depth = d
+		}
+	}
+
+	parts = append(parts,
+		plural(total, "value", "values"),
+		fmt.Sprintf("depth %d", depth),
+		formatSize(len(input)),
+	)
+
+	return strings.Join(parts, ", ")
+}
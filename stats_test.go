@@ -0,0 +1,40 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "2.0 MiB", formatSize(2<<20))
+}
+
+func TestInputStats(t *testing.T) {
+	assert.Equal(t, "object (2 keys), 4 values, depth 2, 19 B", inputStats(`{"a":1,"b":{"c":2}}`))
+	assert.Equal(t, "array (3 items), 4 values, depth 1, 7 B", inputStats(`[1,2,3]`))
+	assert.Equal(t, "stream (2 values), 2 values, depth 0, 4 B", inputStats("1 \"\""))
+	assert.Equal(t, "string, 1 value, depth 0, 3 B", inputStats(`"a"`))
+	assert.True(t, strings.HasPrefix(inputStats(`{`), "Input is not valid JSON"))
+	assert.Contains(t, inputStats(strings.Repeat(" ", statsMaxSize+1)), "too large")
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The output of the filter is written to the -tee file at most once per
+// interval while the filter is edited
+const teeInterval = time.Second
+
+// Writes the output of each filter that runs successfully to a file, so that
+// another process can follow the results while the filter is edited. Each
+// output is preceded by a line with the filter that produced it.
+type teeWriter struct {
+	w        io.Writer
+	interval time.Duration
+
+	// The latest document to write and whether a write is scheduled
+	mu        sync.Mutex
+	pending   *Document
+	scheduled bool
+
+	// Writes are serialized, and an output that is the same as the last
+	// one written is skipped
+	writeMu sync.Mutex
+	last    []byte
+}
+
+func newTeeWriter(w io.Writer, interval time.Duration) *teeWriter {
+	return &teeWriter{w: w, interval: interval}
+}
+
+// Schedule the output of the document to be written. Of the documents
+// scheduled within an interval, only the latest is written.
+func (t *teeWriter) Update(d Document) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending = &d
+	if !t.scheduled {
+		t.scheduled = true
+		time.AfterFunc(t.interval, t.flush)
+	}
+}
+
+func (t *teeWriter) flush() {
+	t.mu.Lock()
+	d := t.pending
+	t.pending, t.scheduled = nil, false
+	t.mu.Unlock()
+
+	if d != nil {
+		_ = t.write(d)
+	}
+}
+
+// Run the filter of the document and write its output preceded by the
+// filter. Nothing is written if the filter fails.
+func (t *teeWriter) write(d *Document) error {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	out, err := encodeOutput(buf.Bytes(), d.options.encoding)
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if t.last != nil && bytes.Equal(out, t.last) {
+		return nil
+	}
+
+	t.last = out
+
+	// The filter is written on one line so that the delimiter is a
+	// single line
+	header := "# ijq: " + strings.Join(strings.Fields(d.filter), " ") + "\n"
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+
+	_, err = t.w.Write(append([]byte(header), out...))
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeeWriterWrite(t *testing.T) {
+	var buf bytes.Buffer
+	tee := newTeeWriter(&buf, time.Millisecond)
+	doc := &Document{input: "1", filter: ".a\n| .b", options: Options{command: "./testdata/cat"}}
+
+	assert.NoError(t, tee.write(doc))
+	assert.Equal(t, "# ijq: .a | .b\n1\n", buf.String())
+
+	// The same output is not written again
+	doc.filter = ".c"
+	assert.NoError(t, tee.write(doc))
+	assert.Equal(t, "# ijq: .a | .b\n1\n", buf.String())
+
+	doc.input = "2\n"
+	assert.NoError(t, tee.write(doc))
+	assert.Equal(t, "# ijq: .a | .b\n1\n# ijq: .c\n2\n", buf.String())
+
+	// Nothing is written for a failing filter
+	doc.options.command = "./testdata/caterror"
+	doc.input = "3\n"
+	assert.Error(t, tee.write(doc))
+	assert.Equal(t, "# ijq: .a | .b\n1\n# ijq: .c\n2\n", buf.String())
+}
+
+func TestTeeWriterUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	tee := newTeeWriter(&buf, 20*time.Millisecond)
+	doc := Document{input: "1\n", filter: ".a", options: Options{command: "./testdata/cat"}}
+	tee.Update(doc)
+	doc.input, doc.filter = "2\n", ".b"
+	tee.Update(doc)
+
+	// Only the latest document is written
+	assert.Eventually(t, func() bool {
+		tee.writeMu.Lock()
+		defer tee.writeMu.Unlock()
+		return buf.Len() > 0
+	}, time.Second, 5*time.Millisecond)
+
+	tee.writeMu.Lock()
+	defer tee.writeMu.Unlock()
+	assert.Equal(t, "# ijq: .b\n2\n", buf.String())
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Placeholders in filter templates have the form ${N} or ${N:default}, where
+// N is the order in which placeholders are visited. This is never valid jq
+// syntax, so placeholders cannot be confused with a real filter.
+var placeholderPattern = regexp.MustCompile(`\$\{(\d+)(?::([^}]*))?\}`)
+
+// Find the placeholder with the lowest number in the filter. The start and end
+// offsets of the placeholder are returned along with its default text.
+func nextPlaceholder(filter string) (start, end int, def string, ok bool) {
+	lowest := -1
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(filter, -1) {
+		n, err := strconv.Atoi(filter[m[2]:m[3]])
+		if err != nil {
+			continue
+		}
+
+		if lowest == -1 || n < lowest {
+			lowest = n
+			start, end = m[0], m[1]
+			def = ""
+			if m[4] != -1 {
+				def = filter[m[4]:m[5]]
+			}
+		}
+	}
+
+	return start, end, def, lowest != -1
+}
+
+// A template that wraps a filter in an aggregation of its results. The
+// filter replaces %s in the template.
+type aggregation struct {
+	name        string
+	description string
+	template    string
+}
+
+// The aggregations offered by Alt-G, each chosen by the first letter of its
+// name
+var aggregations = []aggregation{
+	{"length", "count the results", "[%s] | length"},
+	{"add", "add up the results", "[%s] | add"},
+	{"unique", "the distinct results, sorted", "[%s] | unique"},
+	{"group_by", "group the results by a key", "[%s] | group_by(${1:.})"},
+	{"map", "map a filter over the elements of the result", "%s | map(${1:.})"},
+}
+
+// Wrap the filter in the aggregation. An empty filter is the identity, and a
+// newline ends a filter that may end with a comment, which would otherwise
+// hide the rest of the template.
+func (a aggregation) wrap(filter string) string {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		filter = "."
+	}
+
+	if strings.Contains(filter, "#") {
+		filter += "\n"
+	}
+
+	return strings.Replace(a.template, "%s", filter, 1)
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextPlaceholder(t *testing.T) {
+	filter := `.items[] | select(.${2:name} == "${1}")`
+
+	start, end, def, ok := nextPlaceholder(filter)
+	assert.True(t, ok)
+	assert.Equal(t, "${1}", filter[start:end])
+	assert.Equal(t, "", def)
+
+	filter = filter[:start] + filter[end:]
+	start, end, def, ok = nextPlaceholder(filter)
+	assert.True(t, ok)
+	assert.Equal(t, "${2:name}", filter[start:end])
+	assert.Equal(t, "name", def)
+
+	_, _, _, ok = nextPlaceholder(".foo | $bar")
+	assert.False(t, ok)
+}
+
+func TestAggregationWrap(t *testing.T) {
+	add := aggregations[1]
+	assert.Equal(t, "[.items[].price] | add", add.wrap(".items[].price"))
+	assert.Equal(t, "[.] | add", add.wrap(" "))
+	assert.Equal(t, "[.a # comment\n] | add", add.wrap(".a # comment\n"))
+
+	groupBy := aggregations[3]
+	filter := groupBy.wrap(".[]")
+	assert.Equal(t, "[.[]] | group_by(${1:.})", filter)
+	start, end, def, ok := nextPlaceholder(filter)
+	assert.True(t, ok)
+	assert.Equal(t, "${1:.}", filter[start:end])
+	assert.Equal(t, ".", def)
+
+	// Each aggregation is chosen by a different letter
+	letters := map[byte]bool{}
+	for _, a := range aggregations {
+		assert.False(t, letters[a.name[0]], a.name)
+		letters[a.name[0]] = true
+	}
+}
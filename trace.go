@@ -0,0 +1,149 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// The first version of jq with the --debug-trace option
+var debugTraceVersion = jqVersion{1, 5, 0}
+
+// jq traces every instruction it executes, along with the values on its
+// stack, so the trace is cut off after this many lines and each line after
+// this many bytes
+const (
+	maxTraceLines = 5000
+	maxTraceWidth = 300
+)
+
+// Run the filter of the output pane with jq's --debug-trace option and
+// return the trace of the instructions jq executes, interleaved with the
+// results. The trace of a filter that fails ends with the error of jq.
+func (d *Document) Trace() (string, error) {
+	opts := d.options.preview()
+	opts.forceColor = false
+	opts.monochrome = true
+	opts.compact = true
+	opts.unbuffered = false
+
+	filter := d.EffectiveFilter(true)
+	if opts.noSideEffects {
+		if err := checkSandbox(filter, opts.endOfOptions); err != nil {
+			return "", err
+		}
+	}
+
+	args := append(opts.ToSlice(), "--debug-trace")
+	if opts.endOfOptions {
+		args = append(args, "--")
+	}
+
+	args = append(args, filter)
+	cmd := exec.Command(d.options.command, args...)
+	if opts.noSideEffects {
+		cmd.Env = []string{}
+	}
+
+	cmd.Stdin = strings.NewReader(d.input)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	trace, complete := readTrace(stdout)
+	if !complete {
+		_ = cmd.Process.Kill()
+	}
+
+	// A failing filter has already written its error to the trace
+	_ = cmd.Wait()
+
+	if !complete {
+		trace += fmt.Sprintf("(the trace is cut off after %d lines)\n", maxTraceLines)
+	}
+
+	return trace, nil
+}
+
+// Read at most maxTraceLines lines of a trace, shortening long lines.
+// Returns false if there were more lines.
+func readTrace(r io.Reader) (string, bool) {
+	var b strings.Builder
+	br := bufio.NewReader(r)
+	for lines := 0; ; lines++ {
+		line, err := readTraceLine(br)
+		if line == "" && err != nil {
+			return b.String(), true
+		}
+
+		if lines == maxTraceLines {
+			return b.String(), false
+		}
+
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+}
+
+// Read a line without its newline, keeping at most maxTraceWidth bytes
+func readTraceLine(br *bufio.Reader) (string, error) {
+	var line []byte
+	long := false
+	for {
+		fragment, isPrefix, err := br.ReadLine()
+		if err != nil {
+			return string(line), err
+		}
+
+		// The rest of a long line is skipped
+		if !long {
+			if room := maxTraceWidth - len(line); len(fragment) > room {
+				// Do not split a character
+				for room > 0 && !utf8.RuneStart(fragment[room]) {
+					room--
+				}
+
+				fragment = fragment[:room]
+				long = true
+			}
+
+			line = append(line, fragment...)
+		}
+
+		if !isPrefix {
+			break
+		}
+	}
+
+	if long {
+		return string(line) + "...", nil
+	}
+
+	return string(line), nil
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentTrace(t *testing.T) {
+	doc := &Document{input: "0000 TOP\n1\n", filter: ".", options: Options{command: "./testdata/cat"}}
+	trace, err := doc.Trace()
+	assert.NoError(t, err)
+	assert.Equal(t, "0000 TOP\n1\n", trace)
+
+	doc.input = strings.Repeat("0001 DUP\n", maxTraceLines+1)
+	trace, err = doc.Trace()
+	assert.NoError(t, err)
+	assert.Equal(t, maxTraceLines+1, strings.Count(trace, "\n"))
+	assert.True(t, strings.HasSuffix(trace, "0001 DUP\n(the trace is cut off after 5000 lines)\n"))
+}
+
+func TestReadTraceLongLines(t *testing.T) {
+	long := strings.Repeat("x", maxTraceWidth-1) + "é" + strings.Repeat("y", 5000)
+	trace, complete := readTrace(strings.NewReader(long + "\nshort"))
+	assert.True(t, complete)
+	assert.Equal(t, strings.Repeat("x", maxTraceWidth-1)+"...\nshort\n", trace)
+}
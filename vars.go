@@ -0,0 +1,161 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Valid jq variable names, not including the leading $
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// A jq variable bound to the contents of a file
+type fileVar struct {
+	name string
+	file string
+
+	// The JSON text of the file if it contains a single value
+	value string
+
+	// Whether the value was set in the interface rather than read from
+	// the file, which is then only kept for reference. Variables added in
+	// the interface have no file.
+	edited bool
+}
+
+// Variables given with repeated -var flags
+type fileVars []fileVar
+
+func (v *fileVars) String() string {
+	return strings.Join(v.items(), ",")
+}
+
+func (v *fileVars) items() []string {
+	var items []string
+	for _, fv := range *v {
+		if fv.file != "" {
+			items = append(items, fv.name+"="+fv.file)
+		}
+	}
+
+	return items
+}
+
+// Parse a variable binding of the form name=file. A file that contains a
+// single JSON value is bound to the variable as is, otherwise the variable
+// holds an array of all of the values in the file.
+func (v *fileVars) Set(value string) error {
+	name, file, ok := strings.Cut(value, "=")
+	if !ok || file == "" {
+		return fmt.Errorf("invalid variable %q, use name=file", value)
+	}
+
+	name = strings.TrimPrefix(name, "$")
+	if !variableNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+
+	for _, fv := range *v {
+		if fv.name == name {
+			return fmt.Errorf("variable %q is given more than once", name)
+		}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	values, err := parseJSONStream(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	fv := fileVar{name: name, file: file}
+	if len(values) == 1 {
+		fv.value = strings.TrimSpace(string(data))
+	}
+
+	*v = append(*v, fv)
+	return nil
+}
+
+// Return the JSON text of the value the variable is bound to. A variable
+// holding all of the values of its file is an array of the values.
+func (fv fileVar) valueText() (string, error) {
+	if fv.value != "" {
+		return fv.value, nil
+	}
+
+	data, err := os.ReadFile(fv.file)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := parseJSONStream(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", fv.file, err)
+	}
+
+	array := &jsonValue{kind: jsonArray, items: values}
+	return strings.TrimSpace(string(formatJSONStream([]*jsonValue{array}, ""))), nil
+}
+
+// Return the variables with the variable bound to a JSON value given in the
+// interface, which is added if there is no such variable. The value must be
+// a single JSON value. The variables are copied, so that other documents
+// sharing them are not changed.
+func (v fileVars) withValue(name, value string) (fileVars, error) {
+	name = strings.TrimPrefix(name, "$")
+	if !variableNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+
+	values, err := parseJSONStream([]byte(value))
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("the value of $%s must be a single JSON value", name)
+	}
+
+	vars := append(fileVars(nil), v...)
+	for i, fv := range vars {
+		if fv.name == name {
+			vars[i].value = strings.TrimSpace(value)
+			vars[i].edited = true
+			return vars, nil
+		}
+	}
+
+	return append(vars, fileVar{name: name, value: strings.TrimSpace(value), edited: true}), nil
+}
+
+// The jq arguments that bind the variables
+func (v fileVars) args() []string {
+	var args []string
+	for _, fv := range v {
+		if fv.value != "" {
+			args = append(args, "--argjson", fv.name, fv.value)
+		} else {
+			args = append(args, "--slurpfile", fv.name, fv.file)
+		}
+	}
+
+	return args
+}
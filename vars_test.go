@@ -0,0 +1,85 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileVarsSet(t *testing.T) {
+	dir := t.TempDir()
+	single := filepath.Join(dir, "single.json")
+	stream := filepath.Join(dir, "stream.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, os.WriteFile(single, []byte("{\"a\": 1}\n"), 0644))
+	assert.NoError(t, os.WriteFile(stream, []byte("1 2"), 0644))
+	assert.NoError(t, os.WriteFile(invalid, []byte("{"), 0644))
+
+	var vars fileVars
+	assert.NoError(t, vars.Set("config="+single))
+	assert.NoError(t, vars.Set("$data="+stream))
+	assert.Equal(t, []string{
+		"--argjson", "config", `{"a": 1}`,
+		"--slurpfile", "data", stream,
+	}, vars.args())
+
+	assert.Error(t, vars.Set("config="+single))
+	assert.Error(t, vars.Set("1x="+single))
+	assert.Error(t, vars.Set("a-b="+single))
+	assert.Error(t, vars.Set("x"))
+	assert.Error(t, vars.Set("x="))
+	assert.Error(t, vars.Set("x="+invalid))
+	assert.Error(t, vars.Set("x="+filepath.Join(dir, "missing.json")))
+	assert.Len(t, vars, 2)
+
+	opt := Options{vars: vars}
+	assert.Contains(t, opt.ToSlice(), "--slurpfile")
+}
+
+func TestFileVarsWithValue(t *testing.T) {
+	stream := filepath.Join(t.TempDir(), "stream.json")
+	assert.NoError(t, os.WriteFile(stream, []byte("1 {\"a\": 2}"), 0644))
+
+	var vars fileVars
+	assert.NoError(t, vars.Set("data="+stream))
+	value, err := vars[0].valueText()
+	assert.NoError(t, err)
+	assert.Equal(t, `[1,{"a":2}]`, value)
+
+	edited, err := vars.withValue("data", " [3] ")
+	assert.NoError(t, err)
+	edited, err = edited.withValue("$n", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--argjson", "data", "[3]", "--argjson", "n", "5"}, edited.args())
+
+	// The original variables are unchanged, and variables without a file
+	// are not given on the command line again
+	assert.Equal(t, []string{"--slurpfile", "data", stream}, vars.args())
+	assert.Equal(t, []string{"data=" + stream}, edited.items())
+
+	_, err = vars.withValue("n", "1 2")
+	assert.Error(t, err)
+	_, err = vars.withValue("n", "{")
+	assert.Error(t, err)
+	_, err = vars.withValue("1x", "1")
+	assert.Error(t, err)
+}
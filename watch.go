@@ -0,0 +1,225 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long the filter file and the modules it includes must stay unchanged
+// before the filter is reloaded
+const watchDelay = 100 * time.Millisecond
+
+// Return the names of the modules imported or included by a filter, e.g.
+// "lib/util" for include "lib/util";
+func moduleReferences(filter string) []string {
+	var names []string
+	tokens := tokenizeFilter(filter)
+	for i := 0; i+1 < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind != tokenIdent || (t.text != "import" && t.text != "include") {
+			continue
+		}
+
+		next := tokens[i+1]
+		var name string
+		if next.kind == tokenString && json.Unmarshal([]byte(next.text), &name) == nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// The directories that jq searches for modules by default, in addition to
+// the directory of the program
+func moduleSearchPath(command string) []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".jq"))
+	}
+
+	if path, err := exec.LookPath(command); err == nil {
+		origin := filepath.Dir(path)
+		dirs = append(dirs, filepath.Join(origin, "..", "lib", "jq"), filepath.Join(origin, "..", "lib"))
+	}
+
+	return dirs
+}
+
+// Return the files that jq may load for a module name referenced from a
+// program in dir. Names beginning with ./ or ../ are only looked up relative
+// to dir. The files do not need to exist.
+func moduleCandidates(name, dir string, searchPath []string) []string {
+	dirs := append([]string{dir}, searchPath...)
+	if filepath.IsAbs(name) {
+		dirs = []string{""}
+	} else if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		dirs = []string{dir}
+	}
+
+	var files []string
+	for _, d := range dirs {
+		path := filepath.Join(d, name)
+		files = append(files,
+			path+".jq",
+			filepath.Join(path, filepath.Base(name)+".jq"),
+			path+".json",
+		)
+	}
+
+	return files
+}
+
+// Return the filter file followed by the module files it references,
+// directly or through other modules. The filter itself is run from the
+// current directory, while modules reference other modules relative to their
+// own directory.
+func watchedFiles(file string, searchPath []string) []string {
+	files := []string{file}
+	seen := map[string]bool{file: true}
+
+	var visit func(path, dir string)
+	visit = func(path, dir string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+
+		for _, name := range moduleReferences(string(data)) {
+			for _, candidate := range moduleCandidates(name, dir, searchPath) {
+				if seen[candidate] {
+					continue
+				}
+
+				seen[candidate] = true
+				files = append(files, candidate)
+				visit(candidate, filepath.Dir(candidate))
+			}
+		}
+	}
+
+	visit(file, ".")
+	return files
+}
+
+// The state of a watched file. Files that do not exist have the zero state.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watches a filter file and the modules it includes for changes. The
+// directories of the files are watched rather than the files themselves,
+// since editors often save a file by replacing it, and a file that is being
+// saved may briefly be missing or incomplete.
+type filterWatcher struct {
+	file       string
+	searchPath []string
+	states     map[string]fileState
+
+	notify *fsnotify.Watcher
+	dirs   map[string]bool
+}
+
+func newFilterWatcher(file string, searchPath []string) (*filterWatcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &filterWatcher{file: file, searchPath: searchPath, notify: notify, dirs: map[string]bool{}}
+	w.changed()
+	return w, nil
+}
+
+// Stop watching the files
+func (w *filterWatcher) Close() error {
+	return w.notify.Close()
+}
+
+// Report whether any of the watched files changed, appeared, or disappeared
+// since the last call. The directories of files that were added are watched
+// from then on.
+func (w *filterWatcher) changed() bool {
+	states := map[string]fileState{}
+	for _, path := range watchedFiles(w.file, w.searchPath) {
+		var state fileState
+		if info, err := os.Stat(path); err == nil {
+			state = fileState{info.ModTime(), info.Size()}
+		}
+
+		states[path] = state
+
+		// Directories that do not exist cannot be watched
+		if dir := filepath.Dir(path); !w.dirs[dir] && w.notify.Add(dir) == nil {
+			w.dirs[dir] = true
+		}
+	}
+
+	changed := len(states) != len(w.states)
+	for path, state := range states {
+		if previous, ok := w.states[path]; !ok || !previous.modTime.Equal(state.modTime) || previous.size != state.size {
+			changed = true
+		}
+	}
+
+	w.states = states
+	return changed
+}
+
+// Wait for changes in the watched directories and call reload with the
+// contents of the filter file once the files have not changed for the delay.
+// While the filter file is missing the reload is postponed until it appears
+// again. This function returns once the watcher is closed.
+func (w *filterWatcher) run(delay time.Duration, reload func(filter string)) {
+	settled := time.NewTimer(delay)
+	settled.Stop()
+	for {
+		select {
+		case _, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+
+			settled.Reset(delay)
+		case _, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+		case <-settled.C:
+			if !w.changed() {
+				continue
+			}
+
+			data, err := os.ReadFile(w.file)
+			if err != nil {
+				continue
+			}
+
+			reload(string(data))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2021 Gregory Anders <greg@gpanders.com>
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleReferences(t *testing.T) {
+	filter := `import "lib/a" as a; include "b"; # include "c";
+import "data" as $data; .x | "include \"d\""`
+	assert.Equal(t, []string{"lib/a", "b", "data"}, moduleReferences(filter))
+	assert.Empty(t, moduleReferences(".include"))
+}
+
+func TestModuleCandidates(t *testing.T) {
+	assert.Equal(t, []string{
+		"dir/lib/a.jq", "dir/lib/a/a.jq", "dir/lib/a.json",
+		"home/lib/a.jq", "home/lib/a/a.jq", "home/lib/a.json",
+	}, moduleCandidates("lib/a", "dir", []string{"home"}))
+	assert.Equal(t, []string{"a.jq", "a/a.jq", "a.json"}, moduleCandidates("./a", ".", []string{"home"}))
+	assert.Equal(t, []string{"/lib/a.jq", "/lib/a/a.jq", "/lib/a.json"}, moduleCandidates("/lib/a", "dir", nil))
+}
+
+func TestWatchedFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.jq")
+	module := filepath.Join(dir, "lib.jq")
+	assert.NoError(t, os.WriteFile(main, []byte(`include "lib"; f`), 0644))
+	assert.NoError(t, os.WriteFile(module, []byte(`include "./more"; def f: g;`), 0644))
+
+	files := watchedFiles(main, []string{dir})
+	assert.Equal(t, main, files[0])
+	assert.Contains(t, files, module)
+	assert.Contains(t, files, filepath.Join(dir, "more.jq"))
+}
+
+func TestFilterWatcherChanged(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.jq")
+	module := filepath.Join(dir, "lib.jq")
+	assert.NoError(t, os.WriteFile(main, []byte(`include "lib"; f`), 0644))
+
+	w, err := newFilterWatcher(main, []string{dir})
+	assert.NoError(t, err)
+	defer w.Close()
+	assert.False(t, w.changed())
+
+	// A module that appears is a change
+	assert.NoError(t, os.WriteFile(module, []byte(`def f: 1;`), 0644))
+	assert.True(t, w.changed())
+	assert.False(t, w.changed())
+
+	assert.NoError(t, os.WriteFile(module, []byte(`def f: 12;`), 0644))
+	assert.True(t, w.changed())
+
+	// As is a filter file that disappears while it is saved
+	assert.NoError(t, os.Remove(main))
+	assert.True(t, w.changed())
+}
+
+func TestFilterWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.jq")
+	assert.NoError(t, os.WriteFile(main, []byte(`.a`), 0644))
+
+	reloaded := make(chan string, 1)
+	w, err := newFilterWatcher(main, nil)
+	assert.NoError(t, err)
+	defer w.Close()
+	go w.run(10*time.Millisecond, func(filter string) {
+		reloaded <- filter
+	})
+
+	assert.NoError(t, os.WriteFile(main, []byte(`.abc`), 0644))
+	select {
+	case filter := <-reloaded:
+		assert.Equal(t, ".abc", filter)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the filter was not reloaded")
+	}
+
+	// Editors often save a file by writing another file and renaming it
+	tmp := filepath.Join(dir, "main.jq.tmp")
+	assert.NoError(t, os.WriteFile(tmp, []byte(`.b`), 0644))
+	assert.NoError(t, os.Rename(tmp, main))
+	select {
+	case filter := <-reloaded:
+		assert.Equal(t, ".b", filter)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the filter was not reloaded after it was replaced")
+	}
+}